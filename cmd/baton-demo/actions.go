@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/conductorone/baton-demo/pkg/connector"
+)
+
+// newActionCmd returns the action command, which lists and invokes the connector's custom actions against the demo
+// data so that they can be tested without a sync.
+func newActionCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
+	actionCmd := &cobra.Command{
+		Use:   "action",
+		Short: "List and invoke custom actions",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the available actions and their arguments",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			demo, err := newActionConnector(ctx, cmd, v)
+			if err != nil {
+				return err
+			}
+			defer demo.Close()
+
+			return printJSON(demo.ListActions())
+		},
+	}
+	addBackendFlags(listCmd)
+
+	var rawArgs []string
+	invokeCmd := &cobra.Command{
+		Use:   "invoke <action>",
+		Short: "Invoke an action",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			actionArgs := make(map[string]string)
+			for _, arg := range rawArgs {
+				name, value, ok := strings.Cut(arg, "=")
+				if !ok {
+					return fmt.Errorf("baton-demo: argument %q must have the form name=value", arg)
+				}
+				actionArgs[name] = value
+			}
+
+			demo, err := newActionConnector(ctx, cmd, v)
+			if err != nil {
+				return err
+			}
+			defer demo.Close()
+
+			result, err := demo.InvokeAction(ctx, args[0], actionArgs)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(result)
+		},
+	}
+	addBackendFlags(invokeCmd)
+	invokeCmd.Flags().StringArrayVar(&rawArgs, "arg", nil, "An argument of the action, as name=value. May be repeated")
+
+	actionCmd.AddCommand(listCmd, invokeCmd)
+
+	return actionCmd
+}
+
+func newActionConnector(ctx context.Context, cmd *cobra.Command, v *viper.Viper) (*connector.Demo, error) {
+	b, err := openBackend(cmd, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.New(ctx, b)
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
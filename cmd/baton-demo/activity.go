@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// newSimulateActivityCmd returns the simulate-activity command, which fills the demo data with a history of logins so
+// that last logins, dormant accounts and lockouts can be synced.
+func newSimulateActivityCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
+	cfg := client.ActivityConfig{}
+
+	cmd := &cobra.Command{
+		Use:   "simulate-activity",
+		Short: "Simulate user logins over a window of time",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := openBackend(cmd, v)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			report, err := client.SimulateActivity(ctx, b, cfg)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(report)
+		},
+	}
+	addBackendFlags(cmd)
+	cmd.Flags().DurationVar(&cfg.Window, "window", 30*24*time.Hour, "How far back from now the simulated activity starts")
+	cmd.Flags().Float64Var(&cfg.LoginsPerDay, "logins-per-day", 2, "Average number of logins of an active user on a working day")
+	cmd.Flags().Float64Var(&cfg.FailureRate, "failure-rate", 0.05, "Probability of a login attempt using the wrong password")
+	cmd.Flags().Float64Var(&cfg.DormantRate, "dormant-rate", 0.2, "Probability of a user not logging in at all")
+	cmd.Flags().StringVar(&cfg.Password, "password", "password", "The password users log in with")
+	cmd.Flags().Int64Var(&cfg.Seed, "seed", 0, "Seed for the simulation, the same seed reproduces the same activity")
+
+	return cmd
+}
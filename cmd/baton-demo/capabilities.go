@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/conductorone/baton-sdk/pkg/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+
+	"github.com/conductorone/baton-demo/pkg/conformance"
+	"github.com/conductorone/baton-demo/pkg/connector"
+)
+
+// addCapabilitiesVerify adds a --verify flag to the capabilities command of the SDK. Rather than printing the
+// capabilities, it checks them: the capabilities the connector computes are compared to the checked-in file, and
+// every capability the file advertises is exercised against a throwaway database. The command fails if the
+// capabilities drifted or any check failed.
+func addCapabilitiesVerify(ctx context.Context, root *cobra.Command, v *viper.Viper) error {
+	var capabilitiesCmd *cobra.Command
+	for _, c := range root.Commands() {
+		if c.Name() == "capabilities" {
+			capabilitiesCmd = c
+		}
+	}
+	if capabilitiesCmd == nil {
+		return errors.New("baton-demo: the SDK defined no capabilities command")
+	}
+
+	verify := false
+	path := ""
+	capabilitiesCmd.Flags().BoolVar(&verify, "verify", false, "Check the capabilities in --capabilities-file against the connector instead of printing them")
+	capabilitiesCmd.Flags().StringVar(&path, "capabilities-file", "baton_capabilities.json", "The capabilities checked with --verify")
+
+	printCapabilities := capabilitiesCmd.RunE
+	capabilitiesCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if !verify {
+			return printCapabilities(cmd, args)
+		}
+
+		ctx, err := logging.Init(ctx, logging.WithLogFormat(v.GetString("log-format")), logging.WithLogLevel(v.GetString("log-level")))
+		if err != nil {
+			return err
+		}
+
+		return verifyCapabilities(ctx, path)
+	}
+
+	return nil
+}
+
+func verifyCapabilities(ctx context.Context, path string) error {
+	advertised, err := conformance.LoadCapabilities(path)
+	if err != nil {
+		return err
+	}
+
+	actual, err := computeCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+
+	drift := conformance.Diff(advertised, actual)
+	for _, d := range drift {
+		fmt.Printf("DRIFT %s\n", d)
+	}
+
+	results, err := conformance.Run(ctx, connector.Conformance(ctx, advertised))
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Skipped != "":
+			fmt.Printf("SKIP  %s: %s\n", r.Name, r.Skipped)
+		case r.Err != nil:
+			failed++
+			fmt.Printf("FAIL  %s: %v\n", r.Name, r.Err)
+		default:
+			fmt.Printf("PASS  %s\n", r.Name)
+		}
+	}
+
+	if len(drift) > 0 || failed > 0 {
+		return fmt.Errorf("baton-demo: %s has %d differences from the connector and %d of %d checks failed",
+			path, len(drift), failed, len(results))
+	}
+
+	return nil
+}
+
+// computeCapabilities returns the capabilities of the connector as the SDK computes them from its builders.
+func computeCapabilities(ctx context.Context) (*v2.ConnectorCapabilities, error) {
+	inst, err := connector.NewConformanceInstance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer inst.Close()
+
+	md, err := inst.Server.GetMetadata(ctx, &v2.ConnectorServiceGetMetadataRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	if md.GetMetadata().GetCapabilities() == nil {
+		return nil, errors.New("baton-demo: connector does not support capabilities")
+	}
+
+	return md.GetMetadata().GetCapabilities(), nil
+}
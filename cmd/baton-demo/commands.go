@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// addBackendFlags adds the flags that select the storage backend to a subcommand that works on the demo data
+// directly, outside of a sync.
+func addBackendFlags(cmd *cobra.Command) {
+	cmd.Flags().String(dbFile.FieldName, "", dbFile.Description)
+	cmd.Flags().Bool(initDB.FieldName, false, initDB.Description)
+	cmd.Flags().String(backend.FieldName, backendSQLite, backend.Description)
+	cmd.Flags().String(dbDriver.FieldName, client.DriverSQLite, dbDriver.Description)
+	cmd.Flags().String(dbDSN.FieldName, "", dbDSN.Description)
+	cmd.Flags().String(tenant.FieldName, "", tenant.Description)
+}
+
+// openBackend returns the storage backend selected by the flags of a subcommand.
+func openBackend(cmd *cobra.Command, v *viper.Viper) (client.Backend, error) {
+	err := v.BindPFlags(cmd.Flags())
+	if err != nil {
+		return nil, err
+	}
+
+	return newBackend(v)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-demo/pkg/console"
+)
+
+// newConsoleCmd returns the console command, which serves a web console over the demo data until it is interrupted.
+func newConsoleCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
+	listen := ""
+
+	cmd := &cobra.Command{
+		Use:   "console",
+		Short: "Serve a web console to browse and edit the demo data",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := openBackend(cmd, v)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			ctx, err = logging.Init(ctx, logging.WithLogFormat(v.GetString("log-format")), logging.WithLogLevel(v.GetString("log-level")))
+			if err != nil {
+				return err
+			}
+			l := ctxzap.Extract(ctx)
+
+			handler, err := console.New(b)
+			if err != nil {
+				return err
+			}
+
+			srv := &http.Server{
+				Addr:              listen,
+				Handler:           handler,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			go func() {
+				<-ctx.Done()
+				_ = srv.Close()
+			}()
+
+			l.Info("serving web console", zap.String("url", "http://"+listen))
+			err = srv.ListenAndServe()
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+	}
+	addBackendFlags(cmd)
+	cmd.Flags().StringVar(&listen, "listen", "localhost:8080", "The address the web console listens on")
+
+	return cmd
+}
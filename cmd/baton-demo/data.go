@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+// dataCmd holds the state shared by the subcommands of the data command.
+type dataCmd struct {
+	ctx    context.Context
+	v      *viper.Viper
+	output string
+}
+
+// newDataCmd returns the data command, whose subcommands read and change the demo data directly so that scenarios
+// can be scripted without going through a sync or provisioning.
+func newDataCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
+	d := &dataCmd{ctx: ctx, v: v}
+
+	cmd := &cobra.Command{
+		Use:   "data",
+		Short: "Read and change the demo data",
+	}
+	cmd.PersistentFlags().StringVarP(&d.output, "output", "o", outputJSON, `How results are printed, "json" or "table"`)
+
+	usersCmd := &cobra.Command{
+		Use:   "users",
+		Short: "List, add and remove users",
+	}
+	usersCmd.AddCommand(d.usersListCmd(), d.usersAddCmd(), d.usersRmCmd())
+
+	groupsCmd := &cobra.Command{
+		Use:   "groups",
+		Short: "Change group memberships",
+	}
+	groupsCmd.AddCommand(d.groupsAddMemberCmd())
+
+	rolesCmd := &cobra.Command{
+		Use:   "roles",
+		Short: "Change role assignments",
+	}
+	rolesCmd.AddCommand(d.rolesAssignCmd())
+
+	projectsCmd := &cobra.Command{
+		Use:   "projects",
+		Short: "Change project ownership",
+	}
+	projectsCmd.AddCommand(d.projectsTransferOwnerCmd())
+
+	passwordsCmd := &cobra.Command{
+		Use:   "passwords",
+		Short: "Change user passwords",
+	}
+	passwordsCmd.AddCommand(d.passwordsSetCmd())
+
+	cmd.AddCommand(usersCmd, groupsCmd, rolesCmd, projectsCmd, passwordsCmd)
+
+	return cmd
+}
+
+// command returns a subcommand that runs fn against the backend selected by its flags and prints what fn returns.
+func (d *dataCmd) command(
+	cmd *cobra.Command,
+	fn func(ctx context.Context, b client.Backend, args []string) (interface{}, [][]string, error),
+) *cobra.Command {
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if d.output != outputJSON && d.output != outputTable {
+			return fmt.Errorf("baton-demo: unknown output format %q", d.output)
+		}
+
+		b, err := openBackend(cmd, d.v)
+		if err != nil {
+			return err
+		}
+		defer b.Close()
+
+		result, table, err := fn(d.ctx, b, args)
+		if err != nil {
+			return err
+		}
+
+		if d.output == outputTable {
+			return printTable(table)
+		}
+		return printJSON(result)
+	}
+	addBackendFlags(cmd)
+
+	return cmd
+}
+
+func (d *dataCmd) usersListCmd() *cobra.Command {
+	return d.command(&cobra.Command{
+		Use:   "list",
+		Short: "List users",
+		Args:  cobra.NoArgs,
+	}, func(ctx context.Context, b client.Backend, args []string) (interface{}, [][]string, error) {
+		users, _, err := b.ListUsers(ctx, client.PageOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return users, userRows(users...), nil
+	})
+}
+
+func (d *dataCmd) usersAddCmd() *cobra.Command {
+	email, password := "", ""
+
+	cmd := d.command(&cobra.Command{
+		Use:   "add <name>",
+		Short: "Add a user",
+		Args:  cobra.ExactArgs(1),
+	}, func(ctx context.Context, b client.Backend, args []string) (interface{}, [][]string, error) {
+		user, err := b.CreateUser(ctx, args[0], email, password)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return user, userRows(user), nil
+	})
+	cmd.Flags().StringVar(&email, "email", "", "Email address of the user")
+	cmd.Flags().StringVar(&password, "password", "", "Initial password of the user")
+
+	return cmd
+}
+
+func (d *dataCmd) usersRmCmd() *cobra.Command {
+	return d.command(&cobra.Command{
+		Use:   "rm <user-id>",
+		Short: "Remove a user",
+		Args:  cobra.ExactArgs(1),
+	}, func(ctx context.Context, b client.Backend, args []string) (interface{}, [][]string, error) {
+		user, err := b.GetUser(ctx, args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = b.DeleteUser(ctx, user.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return user, userRows(user), nil
+	})
+}
+
+func (d *dataCmd) groupsAddMemberCmd() *cobra.Command {
+	memberGroup := false
+	grant := &grantFlags{}
+
+	cmd := d.command(&cobra.Command{
+		Use:   "add-member <group-id> <member-id>",
+		Short: "Add a user, or with --group a group, to a group",
+		Args:  cobra.ExactArgs(2),
+	}, func(ctx context.Context, b client.Backend, args []string) (interface{}, [][]string, error) {
+		var err error
+		if memberGroup {
+			err = b.GrantGroupMemberGroup(ctx, args[0], args[1], grant.options()...)
+		} else {
+			err = b.GrantGroupMember(ctx, args[0], args[1], grant.options()...)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		group, err := b.GetGroup(ctx, args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return group, [][]string{
+			{"ID", "NAME", "MEMBERS", "MEMBER GROUPS", "ADMINS"},
+			{group.Id, group.Name, strings.Join(group.Members, ","), strings.Join(group.MemberGroups, ","), strings.Join(group.Admins, ",")},
+		}, nil
+	})
+	cmd.Flags().BoolVar(&memberGroup, "group", false, "The member is a group rather than a user")
+	grant.add(cmd)
+
+	return cmd
+}
+
+func (d *dataCmd) rolesAssignCmd() *cobra.Command {
+	principalGroup := false
+	grant := &grantFlags{}
+
+	cmd := d.command(&cobra.Command{
+		Use:   "assign <role-id> <principal-id>",
+		Short: "Assign a role to a user, or with --group to a group",
+		Args:  cobra.ExactArgs(2),
+	}, func(ctx context.Context, b client.Backend, args []string) (interface{}, [][]string, error) {
+		var err error
+		if principalGroup {
+			err = b.GrantRoleGroup(ctx, args[1], args[0], grant.options()...)
+		} else {
+			err = b.GrantRole(ctx, args[1], args[0], grant.options()...)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		role, err := b.GetRole(ctx, args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return role, [][]string{
+			{"ID", "NAME", "USERS", "GROUPS"},
+			{role.Id, role.Name, strings.Join(role.DirectAssignments, ","), strings.Join(role.GroupAssignments, ",")},
+		}, nil
+	})
+	cmd.Flags().BoolVar(&principalGroup, "group", false, "The principal is a group rather than a user")
+	grant.add(cmd)
+
+	return cmd
+}
+
+func (d *dataCmd) projectsTransferOwnerCmd() *cobra.Command {
+	return d.command(&cobra.Command{
+		Use:   "transfer-owner <project-id> <user-id>",
+		Short: "Make a user the owner of a project",
+		Args:  cobra.ExactArgs(2),
+	}, func(ctx context.Context, b client.Backend, args []string) (interface{}, [][]string, error) {
+		err := b.TransferProjectOwnership(ctx, args[0], args[1])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		project, err := b.GetProject(ctx, args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return project, [][]string{
+			{"ID", "NAME", "OWNER", "GROUPS"},
+			{project.Id, project.Name, project.Owner, strings.Join(project.GroupAssignments, ",")},
+		}, nil
+	})
+}
+
+func (d *dataCmd) passwordsSetCmd() *cobra.Command {
+	return d.command(&cobra.Command{
+		Use:   "set <user-id> [password]",
+		Short: "Set the password of a user, read from stdin when it is not given",
+		Args:  cobra.RangeArgs(1, 2),
+	}, func(ctx context.Context, b client.Backend, args []string) (interface{}, [][]string, error) {
+		password := ""
+		if len(args) == 2 {
+			password = args[1]
+		} else {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if line == "" && err != nil {
+				return nil, nil, fmt.Errorf("baton-demo: reading password from stdin: %w", err)
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+
+		err := b.ChangePassword(ctx, args[0], password)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		user, err := b.GetUser(ctx, args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return user, userRows(user), nil
+	})
+}
+
+// grantFlags are the flags of subcommands that grant something, which are recorded on the assignment.
+type grantFlags struct {
+	expiresIn time.Duration
+	reason    string
+}
+
+func (g *grantFlags) add(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&g.expiresIn, "expires-in", 0, "How long until the assignment expires. It never expires when unset")
+	cmd.Flags().StringVar(&g.reason, "reason", "", "Why the assignment is made")
+}
+
+func (g *grantFlags) options() []client.GrantOption {
+	var opts []client.GrantOption
+	if g.expiresIn > 0 {
+		opts = append(opts, client.WithExpiry(time.Now().Add(g.expiresIn)))
+	}
+	if g.reason != "" {
+		opts = append(opts, client.WithReason(g.reason))
+	}
+	return opts
+}
+
+func userRows(users ...*client.User) [][]string {
+	rows := [][]string{{"ID", "NAME", "EMAIL"}}
+	for _, u := range users {
+		rows = append(rows, []string{u.Id, u.Name, u.Email})
+	}
+	return rows
+}
+
+// printTable writes rows to stdout as aligned columns, the first row being the header.
+func printTable(rows [][]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		_, err := fmt.Fprintln(w, strings.Join(row, "\t"))
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
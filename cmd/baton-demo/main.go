@@ -25,13 +25,27 @@ var version = "dev"
 func main() {
 	ctx := context.Background()
 
-	_, cmd, err := configschema.DefineConfiguration(ctx, "baton-demo", getConnector, configuration)
+	v, cmd, err := configschema.DefineConfiguration(ctx, "baton-demo", getConnector, configuration)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
 
 	cmd.Version = version
+	cmd.AddCommand(
+		newActionCmd(ctx, v),
+		newSimulateActivityCmd(ctx, v),
+		newReapExpiredCmd(ctx, v),
+		newConsoleCmd(ctx, v),
+		newDataCmd(ctx, v),
+		newScenarioCmd(ctx, v),
+	)
+
+	err = addCapabilitiesVerify(ctx, cmd, v)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
 
 	err = cmd.Execute()
 	if err != nil {
@@ -43,7 +57,18 @@ func main() {
 func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
-	cb, err := connector.New(ctx, v.GetString("db-file"), v.GetBool("init-db"))
+	backend, err := newBackend(v)
+	if err != nil {
+		l.Error("error creating backend", zap.Error(err))
+		return nil, err
+	}
+
+	cb, err := connector.New(ctx, backend,
+		connector.WithPageSize(v.GetInt("page-size")),
+		connector.WithSyncToken(v.GetString("sync-token")),
+		connector.WithConsoleURL(v.GetString("console-url")),
+		connector.WithTenantResource(v.GetBool("tenant-resource")),
+	)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
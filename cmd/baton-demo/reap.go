@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-sdk/pkg/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// newReapExpiredCmd returns the reap-expired command, which revokes every time-bound assignment that has expired.
+// Each revoke is logged, and the revoked assignments are printed once all of them are gone.
+func newReapExpiredCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reap-expired",
+		Short: "Revoke expired group memberships, role assignments and project access",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := openBackend(cmd, v)
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			ctx, err := logging.Init(ctx, logging.WithLogFormat(v.GetString("log-format")), logging.WithLogLevel(v.GetString("log-level")))
+			if err != nil {
+				return err
+			}
+			l := ctxzap.Extract(ctx)
+
+			reaped, err := client.ReapExpired(ctx, b)
+			for _, a := range reaped {
+				l.Info("revoked expired assignment",
+					zap.String("resource_type", a.ResourceType),
+					zap.String("resource_id", a.ResourceID),
+					zap.String("principal_type", a.PrincipalType),
+					zap.String("principal_id", a.PrincipalID),
+					zap.Timep("expires_at", a.ExpiresAt),
+				)
+			}
+			if err != nil {
+				return err
+			}
+
+			return printJSON(reaped)
+		},
+	}
+	addBackendFlags(cmd)
+
+	return cmd
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/conductorone/baton-sdk/pkg/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/conductorone/baton-demo/pkg/scenario"
+)
+
+// newScenarioCmd returns the scenario command, which runs scenario files against a fresh copy of the demo data and
+// prints the report of the steps that ran. It fails if any step does.
+func newScenarioCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
+	scenarioCmd := &cobra.Command{
+		Use:   "scenario",
+		Short: "Run scripted scenarios of changes, syncs and assertions",
+	}
+
+	outDir := ""
+	runCmd := &cobra.Command{
+		Use:   "run <file>",
+		Short: "Run a scenario against a fresh temporary database",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := logging.Init(ctx, logging.WithLogFormat(v.GetString("log-format")), logging.WithLogLevel(v.GetString("log-level")))
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			s, err := scenario.Load(f)
+			if err != nil {
+				return err
+			}
+
+			report, runErr := scenario.Run(ctx, s, outDir)
+			if report != nil {
+				err = printJSON(report)
+				if err != nil {
+					return err
+				}
+			}
+
+			return runErr
+		},
+	}
+	runCmd.Flags().StringVar(&outDir, "out-dir", "", "Directory the c1z of every sync is kept in. They are discarded when unset")
+
+	scenarioCmd.AddCommand(runCmd)
+
+	return scenarioCmd
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// AccountState is the security state of a user's account. Users without any recorded state have the zero value.
+type AccountState struct {
+	UserID string
+	// FailedLogins counts the failed logins since the last successful login or unlock.
+	FailedLogins int
+	// LockedAt is set while the account is locked out.
+	LockedAt              *time.Time
+	PasswordResetRequired bool
+	MFAEnrolled           bool
+	// LastLoginAt is the time of the last successful login, if the user ever logged in.
+	LastLoginAt *time.Time
+	// ActiveSessions is the number of sessions that are neither revoked nor expired.
+	ActiveSessions int
+}
+
+// Locked reports whether the account is locked out.
+func (a *AccountState) Locked() bool {
+	return a.LockedAt != nil
+}
+
+// Session is a login session of a user. Revoked sessions are kept with RevokedAt set.
+type Session struct {
+	Id        string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// active reports whether the session can still be used at the given time.
+func (s *Session) active(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(now)
+}
+
+// GetAccountState returns the security state of the user's account.
+func (c *Client) GetAccountState(ctx context.Context, userID string) (*AccountState, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &AccountState{
+		UserID: userID,
+	}
+
+	q := c.db.From(accountStates.Name()).Prepared(true)
+	q = q.Select("failed_logins", "locked_at", "password_reset_required", "mfa_enrolled", "last_login_at")
+	q = q.Where(goqu.C("user_id").Eq(userID))
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	var lockedAt, lastLoginAt sql.NullTime
+	row := c.db.QueryRowContext(ctx, query, args...)
+	err = row.Scan(&state.FailedLogins, &lockedAt, &state.PasswordResetRequired, &state.MFAEnrolled, &lastLoginAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if lockedAt.Valid {
+		state.LockedAt = &lockedAt.Time
+	}
+	if lastLoginAt.Valid {
+		state.LastLoginAt = &lastLoginAt.Time
+	}
+
+	active, err := c.activeSessionIDs(ctx, userID, now(ctx))
+	if err != nil {
+		return nil, err
+	}
+	state.ActiveSessions = len(active)
+
+	return state, nil
+}
+
+// UnlockAccount lifts a lockout and resets the failed login counter of the user's account.
+func (c *Client) UnlockAccount(ctx context.Context, userID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		return c.updateAccountState(ctx, userID, goqu.Record{
+			"failed_logins": 0,
+			"locked_at":     nil,
+		})
+	})
+}
+
+// RequirePasswordReset forces the user to choose a new password the next time they log in.
+func (c *Client) RequirePasswordReset(ctx context.Context, userID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		return c.updateAccountState(ctx, userID, goqu.Record{
+			"password_reset_required": true,
+		})
+	})
+}
+
+// ResetMFA removes the user's MFA enrollment, they will have to enroll again the next time they log in.
+func (c *Client) ResetMFA(ctx context.Context, userID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		return c.updateAccountState(ctx, userID, goqu.Record{
+			"mfa_enrolled": false,
+		})
+	})
+}
+
+// RevokeSessions revokes every active session of the user and returns how many were revoked.
+func (c *Client) RevokeSessions(ctx context.Context, userID string) (int, error) {
+	return inTx(ctx, c, func(c *Client) (int, error) {
+		err := c.validateDB()
+		if err != nil {
+			return 0, err
+		}
+
+		// Check if user exists
+		_, err = c.GetUser(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = c.bumpRevision(ctx, sessions.Name())
+		if err != nil {
+			return 0, err
+		}
+
+		now := time.Now().UTC()
+		active, err := c.activeSessionIDs(ctx, userID, now)
+		if err != nil {
+			return 0, err
+		}
+
+		if len(active) > 0 {
+			q := c.db.Update(sessions.Name()).Prepared(true)
+			q = q.Set(goqu.Record{
+				"revoked_at": now,
+			})
+			q = q.Where(goqu.C("id").In(active))
+
+			query, args, err := q.ToSQL()
+			if err != nil {
+				return 0, err
+			}
+
+			_, err = c.db.ExecContext(ctx, query, args...)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		err = c.recordChange(ctx, c.db, ResourceTypeUser, userID, ChangeUpdate)
+		if err != nil {
+			return 0, err
+		}
+
+		return len(active), nil
+	})
+}
+
+// activeSessionIDs returns the IDs of the user's sessions that are neither revoked nor expired at the given time.
+// Timestamps are compared here rather than in SQL, the driver stores them as text.
+func (c *Client) activeSessionIDs(ctx context.Context, userID string, at time.Time) ([]string, error) {
+	q := c.db.From(sessions.Name()).Prepared(true)
+	q = q.Select("id", "expires_at")
+	q = q.Where(
+		goqu.C("user_id").Eq(userID),
+		goqu.C("revoked_at").IsNull(),
+	)
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		session := &Session{}
+		err = rows.Scan(&session.Id, &session.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if session.active(at) {
+			ids = append(ids, session.Id)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// updateAccountState sets the given columns of the user's account state, creating the state if the user has none yet.
+func (c *Client) updateAccountState(ctx context.Context, userID string, record goqu.Record) error {
+	err := c.validateDB()
+	if err != nil {
+		return err
+	}
+
+	// Check if user exists
+	_, err = c.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.bumpRevision(ctx, accountStates.Name())
+	if err != nil {
+		return err
+	}
+
+	row := goqu.Record{
+		"user_id": userID,
+	}
+	for k, v := range record {
+		row[k] = v
+	}
+
+	q := c.db.Insert(accountStates.Name()).Prepared(true)
+	q = q.Rows(row)
+	q = q.OnConflict(goqu.DoUpdate("user_id", record))
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	err = c.recordChange(ctx, c.db, ResourceTypeUser, userID, ChangeUpdate)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
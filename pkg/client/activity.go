@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// ActivityConfig configures SimulateActivity.
+type ActivityConfig struct {
+	// Window is how far back from now the simulated activity starts.
+	Window time.Duration
+	// LoginsPerDay is the average number of logins of an active user on a working day.
+	LoginsPerDay float64
+	// FailureRate is the probability of a login attempt using the wrong password. A failed attempt is retried with
+	// the right password a minute later.
+	FailureRate float64
+	// DormantRate is the probability of a user not logging in at all during the window.
+	DormantRate float64
+	// Password is the password users log in with.
+	Password string
+	// Seed seeds the random source, the same seed and data produce the same activity. A seed of zero uses the
+	// current time.
+	Seed int64
+}
+
+// ActivityReport summarizes the outcome of SimulateActivity.
+type ActivityReport struct {
+	Attempts     int      `json:"attempts"`
+	Succeeded    int      `json:"succeeded"`
+	Failed       int      `json:"failed"`
+	Locked       int      `json:"locked"`
+	DormantUsers []string `json:"dormant_users"`
+}
+
+type loginEvent struct {
+	at       time.Time
+	login    string
+	password string
+}
+
+// SimulateActivity replays a plausible history of logins against the backend: active users log in on working days
+// during working hours, occasionally mistyping their password, while dormant users never log in. Logins are made in
+// chronological order, so lockouts and last logins come out the way they would have in real life.
+func SimulateActivity(ctx context.Context, b Backend, cfg ActivityConfig) (*ActivityReport, error) {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	//nolint:gosec // Activity only needs to be reproducible, not unpredictable.
+	rng := rand.New(rand.NewSource(seed))
+
+	users, _, err := b.ListUsers(ctx, PageOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	end := now(ctx)
+	start := end.Add(-cfg.Window)
+
+	report := &ActivityReport{
+		DormantUsers: []string{},
+	}
+
+	var events []loginEvent
+	for _, user := range users {
+		if rng.Float64() < cfg.DormantRate {
+			report.DormantUsers = append(report.DormantUsers, user.Id)
+			continue
+		}
+
+		login := user.Email
+		if login == "" {
+			login = user.Name
+		}
+
+		for day := start.Truncate(24 * time.Hour); day.Before(end); day = day.Add(24 * time.Hour) {
+			if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+				continue
+			}
+
+			// Round the average up or down at random so that fractional rates come out right over the window
+			count := int(cfg.LoginsPerDay)
+			if rng.Float64() < cfg.LoginsPerDay-float64(count) {
+				count++
+			}
+
+			for i := 0; i < count; i++ {
+				// Working hours, 08:00 to 18:00 UTC
+				at := day.Add(8*time.Hour + time.Duration(rng.Int63n(int64(10*time.Hour))))
+				if at.Before(start) || at.After(end) {
+					continue
+				}
+
+				if rng.Float64() < cfg.FailureRate {
+					events = append(events, loginEvent{
+						at:       at,
+						login:    login,
+						password: cfg.Password + "-typo",
+					})
+					at = at.Add(time.Minute)
+				}
+
+				events = append(events, loginEvent{
+					at:       at,
+					login:    login,
+					password: cfg.Password,
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b loginEvent) int {
+		return a.at.Compare(b.at)
+	})
+
+	for _, event := range events {
+		report.Attempts++
+
+		_, err := b.Authenticate(WithTime(ctx, event.at), event.login, event.password)
+		switch {
+		case err == nil:
+			report.Succeeded++
+		case errors.Is(err, ErrAccountLocked):
+			report.Locked++
+		case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrPasswordResetRequired):
+			report.Failed++
+		default:
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,424 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// AssignmentSource records how an assignment came to be.
+type AssignmentSource string
+
+const (
+	// SourceSeed assignments are part of the demo data.
+	SourceSeed AssignmentSource = "seed"
+	// SourceProvisioning assignments were granted by the connector.
+	SourceProvisioning AssignmentSource = "provisioning"
+	// SourceAPI assignments were made by calling a Backend directly. This is the default source.
+	SourceAPI AssignmentSource = "api"
+	// SourceSimulation assignments were made by simulated activity.
+	SourceSimulation AssignmentSource = "simulation"
+	// SourceMigration assignments were carried over from a database of the first release, which didn't record how
+	// assignments came to be.
+	SourceMigration AssignmentSource = "migration"
+)
+
+// Assignment is a single principal being assigned a resource: a member of a group, a user or group assigned a role,
+// or a group with access to a project. Besides who is assigned what, it records the provenance of the assignment.
+type Assignment struct {
+	ResourceType  string `json:"resource_type"`
+	ResourceID    string `json:"resource_id"`
+	PrincipalType string `json:"principal_type"`
+	PrincipalID   string `json:"principal_id"`
+	// ExpiresAt is unset for assignments that do not expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// GrantedBy is whoever made the assignment, it is empty when they are unknown.
+	GrantedBy string           `json:"granted_by,omitempty"`
+	GrantedAt time.Time        `json:"granted_at"`
+	Reason    string           `json:"reason,omitempty"`
+	Source    AssignmentSource `json:"source"`
+}
+
+// Expired reports whether the assignment expired at or before t.
+func (a *Assignment) Expired(t time.Time) bool {
+	return a.ExpiresAt != nil && !a.ExpiresAt.After(t)
+}
+
+// GrantOption configures an assignment made by one of the grant methods of a Backend.
+type GrantOption func(*grantOptions)
+
+type grantOptions struct {
+	expiresAt *time.Time
+	grantedBy string
+	grantedAt time.Time
+	reason    string
+	source    AssignmentSource
+}
+
+// WithExpiry makes an assignment expire at t. Expired assignments are still returned until they are reaped, see
+// ReapExpired. Granting an existing assignment again with a different expiry replaces the assignment, without
+// WithExpiry it no longer expires. Granting it again with the same expiry keeps the original provenance.
+func WithExpiry(t time.Time) GrantOption {
+	return func(o *grantOptions) {
+		expiresAt := t.UTC()
+		o.expiresAt = &expiresAt
+	}
+}
+
+// WithGrantedBy records who made an assignment.
+func WithGrantedBy(grantedBy string) GrantOption {
+	return func(o *grantOptions) {
+		o.grantedBy = grantedBy
+	}
+}
+
+// WithReason records why an assignment was made.
+func WithReason(reason string) GrantOption {
+	return func(o *grantOptions) {
+		o.reason = reason
+	}
+}
+
+// WithSource records how an assignment came to be, assignments default to SourceAPI.
+func WithSource(source AssignmentSource) GrantOption {
+	return func(o *grantOptions) {
+		o.source = source
+	}
+}
+
+// newGrantOptions returns the options of an assignment made now.
+func newGrantOptions(ctx context.Context, opts []GrantOption) *grantOptions {
+	ret := &grantOptions{
+		grantedAt: now(ctx),
+		source:    SourceAPI,
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+// row returns the row of an assignment table for an assignment made with the options.
+func (o *grantOptions) row(t assignmentTable, resourceID, principalType, principalID string) goqu.Record {
+	ret := o.record()
+	ret[t.resourceColumn()] = resourceID
+	ret["principal_type"] = principalType
+	ret["principal_id"] = principalID
+	return ret
+}
+
+// record returns the columns of an assignment that are set by the options.
+func (o *grantOptions) record() goqu.Record {
+	return goqu.Record{
+		"expires_at": o.expiresAt,
+		"granted_by": o.grantedBy,
+		"granted_at": o.grantedAt,
+		"reason":     o.reason,
+		"source":     string(o.source),
+	}
+}
+
+// ReapExpired revokes every assignment that expired by now, and returns the revoked assignments. If revoking an
+// assignment fails, the assignments revoked before it are returned along with the error.
+func ReapExpired(ctx context.Context, b Backend) ([]*Assignment, error) {
+	expired, err := b.ListExpiredAssignments(ctx, now(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, a := range expired {
+		err = revokeAssignment(ctx, b, a)
+		if err != nil {
+			return expired[:i], fmt.Errorf("revoking %s %s from %s %s: %w", a.ResourceType, a.ResourceID, a.PrincipalType, a.PrincipalID, err)
+		}
+	}
+
+	return expired, nil
+}
+
+// revokeAssignment removes an assignment through the revoke method of the backend that matches it.
+func revokeAssignment(ctx context.Context, b Backend, a *Assignment) error {
+	switch {
+	case a.ResourceType == ResourceTypeGroup && a.PrincipalType == ResourceTypeUser:
+		return b.RevokeGroupMember(ctx, a.ResourceID, a.PrincipalID)
+	case a.ResourceType == ResourceTypeGroup && a.PrincipalType == ResourceTypeGroup:
+		return b.RevokeGroupMemberGroup(ctx, a.ResourceID, a.PrincipalID)
+	case a.ResourceType == ResourceTypeRole && a.PrincipalType == ResourceTypeUser:
+		return b.RevokeRole(ctx, a.PrincipalID, a.ResourceID)
+	case a.ResourceType == ResourceTypeRole && a.PrincipalType == ResourceTypeGroup:
+		return b.RevokeRoleGroup(ctx, a.PrincipalID, a.ResourceID)
+	case a.ResourceType == ResourceTypeProject && a.PrincipalType == ResourceTypeGroup:
+		return b.RevokeProjectAccess(ctx, a.ResourceID, a.PrincipalID)
+	default:
+		return fmt.Errorf("cannot revoke %s from %s", a.ResourceType, a.PrincipalType)
+	}
+}
+
+// sortAssignments orders assignments by resource, then by principal.
+func sortAssignments(assignments []*Assignment) {
+	slices.SortFunc(assignments, func(a, b *Assignment) int {
+		for _, c := range []int{
+			strings.Compare(a.ResourceType, b.ResourceType),
+			strings.Compare(a.ResourceID, b.ResourceID),
+			strings.Compare(a.PrincipalType, b.PrincipalType),
+			strings.Compare(a.PrincipalID, b.PrincipalID),
+		} {
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// assignmentTable is a table holding the assignments of one resource type, one row per principal.
+type assignmentTable interface {
+	tableDescriptor
+	// resourceType is the type of the resources assigned by the table.
+	resourceType() string
+	// resourceColumn is the column holding the ID of the assigned resource.
+	resourceColumn() string
+	// resourceTable is the table of the assigned resources, which is stamped with a new revision when their
+	// assignments change.
+	resourceTable() tableDescriptor
+}
+
+var allAssignmentTables = []assignmentTable{
+	groupMembers,
+	roleAssignments,
+	projectAssignments,
+}
+
+func assignmentTableFor(resourceType string) (assignmentTable, error) {
+	for _, t := range allAssignmentTables {
+		if t.resourceType() == resourceType {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s cannot be assigned", resourceType)
+}
+
+// ListAssignments returns every assignment of a resource, ordered by principal.
+func (c *Client) ListAssignments(ctx context.Context, resourceType, resourceID string) ([]*Assignment, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := assignmentTableFor(resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.queryAssignments(ctx, t, goqu.C(t.resourceColumn()).Eq(resourceID))
+}
+
+// ListExpiredAssignments returns every assignment that expired at or before at, ordered by resource and principal.
+func (c *Client) ListExpiredAssignments(ctx context.Context, at time.Time) ([]*Assignment, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, err
+	}
+
+	expired := []*Assignment{}
+	for _, t := range allAssignmentTables {
+		// Timestamps are compared here rather than in SQL, the driver stores them as text
+		assignments, err := c.queryAssignments(ctx, t, goqu.And(
+			goqu.C("expires_at").IsNotNull(),
+			goqu.C(t.resourceColumn()).In(c.tenantResourceIDs(t.resourceTable())),
+		))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range assignments {
+			if a.Expired(at) {
+				expired = append(expired, a)
+			}
+		}
+	}
+
+	sortAssignments(expired)
+	return expired, nil
+}
+
+func (c *Client) queryAssignments(ctx context.Context, t assignmentTable, where goqu.Expression) ([]*Assignment, error) {
+	q := c.db.From(t.Name()).Prepared(true)
+	q = q.Select(t.resourceColumn(), "principal_type", "principal_id", "expires_at", "granted_by", "granted_at", "reason", "source")
+	q = q.Where(where)
+	q = q.Order(goqu.C(t.resourceColumn()).Asc(), goqu.C("principal_type").Asc(), goqu.C("principal_id").Asc())
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []*Assignment{}
+	for rows.Next() {
+		a := &Assignment{
+			ResourceType: t.resourceType(),
+		}
+		var expiresAt sql.NullTime
+		source := ""
+		err = rows.Scan(&a.ResourceID, &a.PrincipalType, &a.PrincipalID, &expiresAt, &a.GrantedBy, &a.GrantedAt, &a.Reason, &source)
+		if err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			a.ExpiresAt = &expiresAt.Time
+		}
+		a.Source = AssignmentSource(source)
+		assignments = append(assignments, a)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+// loadAssignments calls fn with the principal of every assignment of the given resources, ordered by principal ID.
+func (c *Client) loadAssignments(ctx context.Context, t assignmentTable, resourceIDs []interface{}, fn func(resourceID, principalType, principalID string) error) error {
+	if len(resourceIDs) == 0 {
+		return nil
+	}
+
+	q := c.db.From(t.Name()).Prepared(true)
+	q = q.Select(t.resourceColumn(), "principal_type", "principal_id")
+	q = q.Where(goqu.C(t.resourceColumn()).In(resourceIDs...))
+	q = q.Order(goqu.C(t.resourceColumn()).Asc(), goqu.C("principal_id").Asc())
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		resourceID, principalType, principalID := "", "", ""
+		err = rows.Scan(&resourceID, &principalType, &principalID)
+		if err != nil {
+			return err
+		}
+
+		err = fn(resourceID, principalType, principalID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// addAssignment assigns a resource to a principal. The resource is stamped with a new revision if the principal was
+// not assigned yet, or if the options of the assignment changed.
+func (c *Client) addAssignment(ctx context.Context, t assignmentTable, resourceID, principalType, principalID string, opts []GrantOption) error {
+	o := newGrantOptions(ctx, opts)
+	key := goqu.Ex{
+		t.resourceColumn(): resourceID,
+		"principal_type":   principalType,
+		"principal_id":     principalID,
+	}
+
+	existing, err := c.queryAssignments(ctx, t, key)
+	if err != nil {
+		return err
+	}
+
+	var query string
+	var args []interface{}
+	switch {
+	case len(existing) == 0:
+		query, args, err = c.db.Insert(t.Name()).Prepared(true).Rows(o.row(t, resourceID, principalType, principalID)).ToSQL()
+	case !equalTimes(existing[0].ExpiresAt, o.expiresAt):
+		query, args, err = c.db.Update(t.Name()).Prepared(true).Set(o.record()).Where(key).ToSQL()
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	res, err := c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return c.assignmentsChanged(ctx, t, resourceID, res)
+}
+
+// removeAssignment removes the assignment of a resource to a principal, stamping the resource with a new revision if
+// the principal was assigned.
+func (c *Client) removeAssignment(ctx context.Context, t assignmentTable, resourceID, principalType, principalID string) error {
+	q := c.db.Delete(t.Name()).Prepared(true)
+	q = q.Where(
+		goqu.C(t.resourceColumn()).Eq(resourceID),
+		goqu.C("principal_type").Eq(principalType),
+		goqu.C("principal_id").Eq(principalID),
+	)
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	res, err := c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return c.assignmentsChanged(ctx, t, resourceID, res)
+}
+
+// assignmentsChanged stamps the resource with a new revision and records the change if the assignment write res
+// affected any rows.
+func (c *Client) assignmentsChanged(ctx context.Context, t assignmentTable, resourceID string, res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return nil
+	}
+
+	rev, err := c.bumpRevision(ctx, t.resourceTable().Name())
+	if err != nil {
+		return err
+	}
+
+	q := c.db.Update(t.resourceTable().Name()).Prepared(true)
+	q = q.Set(goqu.Record{
+		"revision": rev,
+	})
+	q = q.Where(goqu.C("id").Eq(resourceID))
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return c.recordChange(ctx, c.db, t.resourceType(), resourceID, ChangeUpdate)
+}
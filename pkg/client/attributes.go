@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// Attribute is a custom key/value attribute of a resource. Attributes make up the profile of the resource when it is
+// synced.
+type Attribute struct {
+	ResourceType string
+	ResourceID   string
+	Key          string
+	Value        string
+}
+
+// resourceTables maps the resource types that can have attributes to the table holding the resources.
+var resourceTables = map[string]tableDescriptor{
+	ResourceTypeUser:           users,
+	ResourceTypeGroup:          groups,
+	ResourceTypeRole:           roles,
+	ResourceTypeProject:        projects,
+	ResourceTypeServiceAccount: serviceAccounts,
+	ResourceTypeAPIKey:         apiKeys,
+}
+
+func resourceTableFor(resourceType string) (tableDescriptor, error) {
+	t, ok := resourceTables[resourceType]
+	if !ok {
+		return nil, fmt.Errorf("%s cannot have attributes", resourceType)
+	}
+	return t, nil
+}
+
+// GetAttributes returns the attributes of a resource by key. A resource without attributes has an empty map.
+func (c *Client) GetAttributes(ctx context.Context, resourceType, resourceID string) (map[string]string, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = resourceTableFor(resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	q := c.db.From(resourceAttributes.Name()).Prepared(true)
+	q = q.Select("key", "value")
+	q = q.Where(
+		goqu.C("resource_type").Eq(resourceType),
+		goqu.C("resource_id").Eq(resourceID),
+	)
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attrs := map[string]string{}
+	for rows.Next() {
+		key, value := "", ""
+		err = rows.Scan(&key, &value)
+		if err != nil {
+			return nil, err
+		}
+		attrs[key] = value
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return attrs, nil
+}
+
+// SetAttribute sets an attribute of a resource, replacing its previous value.
+func (c *Client) SetAttribute(ctx context.Context, resourceType, resourceID, key, value string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		err = c.touchResource(ctx, resourceType, resourceID)
+		if err != nil {
+			return err
+		}
+
+		q := c.db.Insert(resourceAttributes.Name()).Prepared(true)
+		q = q.Rows(goqu.Record{
+			"resource_type": resourceType,
+			"resource_id":   resourceID,
+			"key":           key,
+			"value":         value,
+		})
+		q = q.OnConflict(goqu.DoUpdate("resource_type, resource_id, key", goqu.Record{
+			"value": value,
+		}))
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// DeleteAttribute removes an attribute from a resource. Removing an attribute the resource does not have is not an
+// error.
+func (c *Client) DeleteAttribute(ctx context.Context, resourceType, resourceID, key string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		err = c.touchResource(ctx, resourceType, resourceID)
+		if err != nil {
+			return err
+		}
+
+		return c.deleteAttributes(ctx, resourceType, resourceID, goqu.C("key").Eq(key))
+	})
+}
+
+// deleteAttributes removes the attributes of a resource matching the filters, all of them if there are none.
+func (c *Client) deleteAttributes(ctx context.Context, resourceType, resourceID string, filters ...goqu.Expression) error {
+	q := c.db.Delete(resourceAttributes.Name()).Prepared(true)
+	q = q.Where(
+		goqu.C("resource_type").Eq(resourceType),
+		goqu.C("resource_id").Eq(resourceID),
+	)
+	q = q.Where(filters...)
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// touchResource stamps a resource with a new revision and records the change, because one of its attributes is about
+// to change. It fails with ErrNotFound if the resource does not exist.
+func (c *Client) touchResource(ctx context.Context, resourceType, resourceID string) error {
+	t, err := resourceTableFor(resourceType)
+	if err != nil {
+		return err
+	}
+
+	rev, err := c.bumpRevision(ctx, t.Name())
+	if err != nil {
+		return err
+	}
+
+	q := c.db.Update(t.Name()).Prepared(true)
+	q = q.Set(goqu.Record{
+		"revision": rev,
+	})
+	q = q.Where(goqu.C("id").Eq(resourceID))
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	res, err := c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s %s: %w", resourceType, resourceID, ErrNotFound)
+	}
+
+	return c.recordChange(ctx, c.db, resourceType, resourceID, ChangeUpdate)
+}
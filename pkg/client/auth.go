@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/segmentio/ksuid"
+)
+
+var (
+	// ErrInvalidCredentials is returned by Authenticate when the login is unknown, the password does not match or the
+	// user has no password.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrAccountLocked is returned by Authenticate when the account is locked out.
+	ErrAccountLocked = errors.New("account locked")
+	// ErrPasswordResetRequired is returned by Authenticate when the password matches but the user must choose a new
+	// one before they can log in again.
+	ErrPasswordResetRequired = errors.New("password reset required")
+)
+
+const (
+	// maxFailedLogins is the number of failed logins in a row after which an account is locked out.
+	maxFailedLogins = 5
+	// sessionLifetime is how long a session created by a successful login lasts.
+	sessionLifetime = 24 * time.Hour
+)
+
+// Reasons a login attempt failed, as recorded in the logins table.
+const (
+	loginFailureInvalidCredentials = "invalid_credentials"
+	loginFailureLocked             = "locked"
+	loginFailurePasswordReset      = "password_reset_required"
+)
+
+type nowContextKey struct{}
+
+// WithTime returns a context in which backends treat t as the current time when recording activity. It lets
+// simulations record activity in the past.
+func WithTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, nowContextKey{}, t.UTC())
+}
+
+// now returns the current time, as overridden by WithTime.
+func now(ctx context.Context) time.Time {
+	if t, ok := ctx.Value(nowContextKey{}).(time.Time); ok {
+		return t
+	}
+	return time.Now().UTC()
+}
+
+// Authenticate checks the password of the user whose name or email is login and starts a new session for them.
+// Every attempt is recorded. Failed attempts count towards a lockout, successful ones reset the count and update the
+// user's last login. Users required to reset their password get no session until they have changed it.
+func (c *Client) Authenticate(ctx context.Context, login, password string) (*Session, error) {
+	return inTx(ctx, c, func(c *Client) (*Session, error) {
+		err := c.validateDB()
+		if err != nil {
+			return nil, err
+		}
+
+		user, storedPassword, err := c.lookupLogin(ctx, login)
+		if errors.Is(err, ErrNotFound) {
+			err = c.recordLogin(ctx, "", login, loginFailureInvalidCredentials)
+			if err != nil {
+				return nil, err
+			}
+			return nil, commitErr{ErrInvalidCredentials}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		state, err := c.GetAccountState(ctx, user.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		at := now(ctx)
+
+		if state.Locked() {
+			err = c.recordLogin(ctx, user.Id, login, loginFailureLocked)
+			if err != nil {
+				return nil, err
+			}
+			return nil, commitErr{fmt.Errorf("user %s: %w", user.Id, ErrAccountLocked)}
+		}
+
+		// Users created without a password cannot log in at all, not even with an empty password
+		if storedPassword == "" || subtle.ConstantTimeCompare([]byte(storedPassword), []byte(password)) != 1 {
+			record := goqu.Record{
+				"failed_logins": state.FailedLogins + 1,
+			}
+			if state.FailedLogins+1 >= maxFailedLogins {
+				record["locked_at"] = at
+			}
+
+			err = c.updateAccountState(ctx, user.Id, record)
+			if err != nil {
+				return nil, err
+			}
+
+			err = c.recordLogin(ctx, user.Id, login, loginFailureInvalidCredentials)
+			if err != nil {
+				return nil, err
+			}
+			return nil, commitErr{ErrInvalidCredentials}
+		}
+
+		if state.PasswordResetRequired {
+			err = c.recordLogin(ctx, user.Id, login, loginFailurePasswordReset)
+			if err != nil {
+				return nil, err
+			}
+			return nil, commitErr{fmt.Errorf("user %s: %w", user.Id, ErrPasswordResetRequired)}
+		}
+
+		err = c.updateAccountState(ctx, user.Id, goqu.Record{
+			"failed_logins": 0,
+			"last_login_at": at,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		session := &Session{
+			Id:        ksuid.New().String(),
+			UserID:    user.Id,
+			CreatedAt: at,
+			ExpiresAt: at.Add(sessionLifetime),
+		}
+
+		_, err = c.bumpRevision(ctx, sessions.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		q := c.db.Insert(sessions.Name()).Prepared(true)
+		q = q.Rows(goqu.Record{
+			"id":         session.Id,
+			"user_id":    session.UserID,
+			"created_at": session.CreatedAt,
+			"expires_at": session.ExpiresAt,
+		})
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.recordLogin(ctx, user.Id, login, "")
+		if err != nil {
+			return nil, err
+		}
+
+		return session, nil
+	})
+}
+
+// lookupLogin returns the user whose name or email is login, along with their password.
+func (c *Client) lookupLogin(ctx context.Context, login string) (*User, string, error) {
+	q := c.db.From(users.Name()).Prepared(true)
+	q = q.Select(goqu.I("users.id"), goqu.I("users.name"), goqu.I("users.email"), goqu.I("users.revision"), goqu.I("passwords.password"))
+	q = q.Where(c.inTenant(users))
+	q = q.Join(goqu.T(passwords.Name()), goqu.On(goqu.I("passwords.user_id").Eq(goqu.I("users.id"))))
+	q = q.Where(goqu.Or(
+		goqu.I("users.name").Eq(login),
+		goqu.I("users.email").Eq(login),
+	))
+	q = q.Limit(1)
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, "", err
+	}
+
+	user := &User{}
+	password := ""
+	err = c.db.QueryRowContext(ctx, query, args...).Scan(&user.Id, &user.Name, &user.Email, &user.Revision, &password)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", fmt.Errorf("login %s: %w", login, ErrNotFound)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, password, nil
+}
+
+// recordLogin appends a login attempt to the logins table. A failure reason of "" records a successful login.
+func (c *Client) recordLogin(ctx context.Context, userID, login, failureReason string) error {
+	record := goqu.Record{
+		"id":             ksuid.New().String(),
+		"user_id":        nil,
+		"login":          login,
+		"succeeded":      failureReason == "",
+		"failure_reason": failureReason,
+		"created_at":     now(ctx),
+	}
+	if userID != "" {
+		record["user_id"] = userID
+	}
+
+	q := c.db.Insert(logins.Name()).Prepared(true)
+	q = q.Rows(record)
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/segmentio/ksuid"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// postgresDSNEnv names the environment variable holding the data source name of a Postgres database to run the
+// backend tests against as well. They are skipped for Postgres if it isn't set.
+const postgresDSNEnv = "BATON_DEMO_POSTGRES_DSN"
+
+// eachBackend runs fn against a freshly seeded instance of every backend.
+func eachBackend(t *testing.T, fn func(t *testing.T, b client.Backend)) {
+	t.Run("sqlite", func(t *testing.T) {
+		c, err := client.NewClient(filepath.Join(t.TempDir(), "baton-demo.db"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Close() })
+		fn(t, c)
+	})
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv(postgresDSNEnv)
+		if dsn == "" {
+			t.Skipf("%s is not set", postgresDSNEnv)
+		}
+
+		// Every test seeds a tenant of its own, so tests sharing the database don't see each other's writes
+		c, err := client.NewClient("", true, client.WithDriver(client.DriverPostgres, dsn), client.WithTenant("test-"+ksuid.New().String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Close() })
+		fn(t, c)
+	})
+	t.Run("memory", func(t *testing.T) {
+		fn(t, client.NewMemoryBackend(true))
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+
+	eachBackend(t, func(t *testing.T, b client.Backend) {
+		user, err := b.CreateUser(ctx, "mallory", "mallory@example.org", "hunter2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		session, err := b.Authenticate(ctx, "mallory@example.org", "hunter2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if session.UserID != user.Id {
+			t.Errorf("session of user %s, want %s", session.UserID, user.Id)
+		}
+
+		_, err = b.Authenticate(ctx, "mallory", "wrong")
+		if !errors.Is(err, client.ErrInvalidCredentials) {
+			t.Errorf("wrong password: got %v, want %v", err, client.ErrInvalidCredentials)
+		}
+
+		_, err = b.Authenticate(ctx, "nobody", "hunter2")
+		if !errors.Is(err, client.ErrInvalidCredentials) {
+			t.Errorf("unknown login: got %v, want %v", err, client.ErrInvalidCredentials)
+		}
+	})
+}
+
+func TestAuthenticateWithoutPassword(t *testing.T) {
+	ctx := context.Background()
+
+	eachBackend(t, func(t *testing.T, b client.Backend) {
+		user, err := b.CreateUser(ctx, "trent", "trent@example.org", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, password := range []string{"", "anything"} {
+			_, err = b.Authenticate(ctx, "trent", password)
+			if !errors.Is(err, client.ErrInvalidCredentials) {
+				t.Errorf("password %q: got %v, want %v", password, err, client.ErrInvalidCredentials)
+			}
+		}
+
+		state, err := b.GetAccountState(ctx, user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.ActiveSessions != 0 {
+			t.Errorf("%d active sessions, want none", state.ActiveSessions)
+		}
+
+		// Once a password is set, the user can log in with it
+		err = b.ChangePassword(ctx, user.Id, "s3cret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = b.Authenticate(ctx, "trent", "s3cret")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestAuthenticateWithPasswordResetRequired(t *testing.T) {
+	ctx := context.Background()
+
+	eachBackend(t, func(t *testing.T, b client.Backend) {
+		user, err := b.CreateUser(ctx, "peggy", "peggy@example.org", "hunter2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = b.RequirePasswordReset(ctx, user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = b.Authenticate(ctx, "peggy", "hunter2")
+		if !errors.Is(err, client.ErrPasswordResetRequired) {
+			t.Errorf("right password: got %v, want %v", err, client.ErrPasswordResetRequired)
+		}
+		_, err = b.Authenticate(ctx, "peggy", "wrong")
+		if !errors.Is(err, client.ErrInvalidCredentials) {
+			t.Errorf("wrong password: got %v, want %v", err, client.ErrInvalidCredentials)
+		}
+
+		state, err := b.GetAccountState(ctx, user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.ActiveSessions != 0 {
+			t.Errorf("%d active sessions, want none", state.ActiveSessions)
+		}
+
+		// Changing the password lifts the requirement
+		err = b.ChangePassword(ctx, user.Id, "s3cret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = b.Authenticate(ctx, "peggy", "s3cret")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
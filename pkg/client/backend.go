@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// ErrNotFound is returned by every Backend when the requested object does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Backend is the storage the demo connector syncs from and provisions against.
+// Client is the SQLite implementation, MemoryBackend keeps all data in process memory.
+type Backend interface {
+	ListUsers(ctx context.Context, page PageOptions) ([]*User, string, error)
+	GetUser(ctx context.Context, userID string) (*User, error)
+	CreateUser(ctx context.Context, name, email, password string) (*User, error)
+	DeleteUser(ctx context.Context, userID string) error
+	ChangePassword(ctx context.Context, userID, password string) error
+
+	Authenticate(ctx context.Context, login, password string) (*Session, error)
+	GetAccountState(ctx context.Context, userID string) (*AccountState, error)
+	UnlockAccount(ctx context.Context, userID string) error
+	RequirePasswordReset(ctx context.Context, userID string) error
+	ResetMFA(ctx context.Context, userID string) error
+	RevokeSessions(ctx context.Context, userID string) (int, error)
+
+	ListGroups(ctx context.Context, page PageOptions) ([]*Group, string, error)
+	GetGroup(ctx context.Context, groupID string) (*Group, error)
+	GrantGroupMember(ctx context.Context, groupID, userID string, opts ...GrantOption) error
+	RevokeGroupMember(ctx context.Context, groupID, userID string) error
+	GrantGroupMemberGroup(ctx context.Context, groupID, memberGroupID string, opts ...GrantOption) error
+	RevokeGroupMemberGroup(ctx context.Context, groupID, memberGroupID string) error
+	GrantGroupAdmin(ctx context.Context, groupID, userID string) error
+	RevokeGroupAdmin(ctx context.Context, groupID, userID string) error
+
+	ListRoles(ctx context.Context, page PageOptions) ([]*Role, string, error)
+	GetRole(ctx context.Context, roleID string) (*Role, error)
+	GrantRole(ctx context.Context, userID, roleID string, opts ...GrantOption) error
+	RevokeRole(ctx context.Context, userID, roleID string) error
+	GrantRoleGroup(ctx context.Context, groupID, roleID string, opts ...GrantOption) error
+	RevokeRoleGroup(ctx context.Context, groupID, roleID string) error
+	GrantRoleAdmin(ctx context.Context, roleID, userID string) error
+	RevokeRoleAdmin(ctx context.Context, roleID, userID string) error
+	GrantRoleDelegate(ctx context.Context, roleID, userID string) error
+	RevokeRoleDelegate(ctx context.Context, roleID, userID string) error
+
+	ListPermissions(ctx context.Context, page PageOptions) ([]*Permission, string, error)
+	GetPermission(ctx context.Context, permissionID string) (*Permission, error)
+	GrantRolePermission(ctx context.Context, roleID, permissionID string) error
+	RevokeRolePermission(ctx context.Context, roleID, permissionID string) error
+
+	ListProjects(ctx context.Context, page PageOptions) ([]*Project, string, error)
+	GetProject(ctx context.Context, projectID string) (*Project, error)
+	GrantProjectAccess(ctx context.Context, projectID, groupID string, opts ...GrantOption) error
+	RevokeProjectAccess(ctx context.Context, projectID, groupID string) error
+	TransferProjectOwnership(ctx context.Context, projectID, userID string) error
+
+	ListAssignments(ctx context.Context, resourceType, resourceID string) ([]*Assignment, error)
+	ListExpiredAssignments(ctx context.Context, at time.Time) ([]*Assignment, error)
+
+	ListServiceAccounts(ctx context.Context, page PageOptions) ([]*ServiceAccount, string, error)
+	GetServiceAccount(ctx context.Context, serviceAccountID string) (*ServiceAccount, error)
+	ListAPIKeys(ctx context.Context, serviceAccountID string, page PageOptions) ([]*APIKey, string, error)
+	GetAPIKey(ctx context.Context, keyID string) (*APIKey, error)
+	RotateAPIKey(ctx context.Context, keyID string) (*APIKey, string, error)
+
+	GetAttributes(ctx context.Context, resourceType, resourceID string) (map[string]string, error)
+	SetAttribute(ctx context.Context, resourceType, resourceID, key, value string) error
+	DeleteAttribute(ctx context.Context, resourceType, resourceID, key string) error
+
+	GetTenant(ctx context.Context) (*Tenant, error)
+
+	ListChanges(ctx context.Context, since int64) ([]*Change, int64, error)
+
+	Close() error
+}
+
+var (
+	_ Backend = (*Client)(nil)
+	_ Backend = (*MemoryBackend)(nil)
+	_ Backend = (*interceptedBackend)(nil)
+)
+
+// splitIDs parses a comma separated list of IDs as stored in the database.
+// An empty column yields an empty slice rather than a single empty ID.
+func splitIDs(ids string) []string {
+	if ids == "" {
+		return []string{}
+	}
+	return strings.Split(ids, ",")
+}
+
+// PageOptions selects a single page of a list call. An empty Token requests the first page.
+// A Size of zero or less disables pagination and every row is returned in one page.
+type PageOptions struct {
+	Token string
+	Size  int
+}
+
+func (p PageOptions) offset() (int, error) {
+	if p.Token == "" || p.Size <= 0 {
+		return 0, nil
+	}
+
+	offset, err := strconv.Atoi(p.Token)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid page token %q", p.Token)
+	}
+
+	return offset, nil
+}
+
+// nextToken returns the token for the page following a page of count rows that started at offset.
+// An empty token means that there are no more pages.
+func (p PageOptions) nextToken(offset int, count int) string {
+	if p.Size <= 0 || count < p.Size {
+		return ""
+	}
+	return strconv.Itoa(offset + count)
+}
+
+// paginate orders a list query by ID and restricts it to the requested page.
+func paginate(q *goqu.SelectDataset, page PageOptions) (*goqu.SelectDataset, int, error) {
+	offset, err := page.offset()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	q = q.Order(goqu.C("id").Asc())
+	if page.Size > 0 {
+		q = q.Limit(uint(page.Size)).Offset(uint(offset))
+	}
+
+	return q, offset, nil
+}
+
+// paginateSlice returns the requested page of items, which must already be ordered by ID.
+func paginateSlice[T any](items []T, page PageOptions) ([]T, string, error) {
+	offset, err := page.offset()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if offset > len(items) {
+		offset = len(items)
+	}
+	items = items[offset:]
+	if page.Size > 0 && len(items) > page.Size {
+		items = items[:page.Size]
+	}
+
+	return items, page.nextToken(offset, len(items)), nil
+}
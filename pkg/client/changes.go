@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// ChangeOperation is the kind of write recorded in the change log.
+type ChangeOperation string
+
+const (
+	ChangeInsert ChangeOperation = "insert"
+	ChangeUpdate ChangeOperation = "update"
+	ChangeDelete ChangeOperation = "delete"
+)
+
+// Resource types recorded in the change log.
+const (
+	ResourceTypeUser    = "user"
+	ResourceTypeGroup   = "group"
+	ResourceTypeRole    = "role"
+	ResourceTypeProject = "project"
+
+	ResourceTypeServiceAccount = "service_account"
+	ResourceTypeAPIKey         = "api_key"
+
+	ResourceTypePermission = "permission"
+)
+
+// Change is a single entry of the change log. Every write to a resource, including changes to who it is assigned to,
+// appends a change with a sequence number higher than any before it.
+type Change struct {
+	Seq          int64
+	ResourceType string
+	ResourceID   string
+	Operation    ChangeOperation
+	CreatedAt    time.Time
+}
+
+// execer is satisfied by both goqu.Database and goqu.TxDatabase.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// recordChange appends an entry to the change log of the client's tenant.
+func (c *Client) recordChange(ctx context.Context, db execer, resourceType, resourceID string, op ChangeOperation) error {
+	q := c.db.Insert(changes.Name()).Prepared(true)
+	q = q.Rows(goqu.Record{
+		"tenant_id":     c.tenant,
+		"resource_type": resourceType,
+		"resource_id":   resourceID,
+		"operation":     string(op),
+		"created_at":    time.Now().UTC(),
+	})
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListChanges returns every change to the resources of the client's tenant recorded after the cursor since, oldest
+// first, along with the cursor to use to list the changes that happen next. A cursor of zero lists the whole change
+// log.
+func (c *Client) ListChanges(ctx context.Context, since int64) ([]*Change, int64, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	q := c.db.From(changes.Name()).Prepared(true)
+	q = q.Select("seq", "resource_type", "resource_id", "operation", "created_at")
+	q = q.Where(
+		goqu.C("tenant_id").Eq(c.tenant),
+		goqu.C("seq").Gt(since),
+	)
+	q = q.Order(goqu.C("seq").Asc())
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	cursor := since
+	changesList := []*Change{}
+	for rows.Next() {
+		change := &Change{}
+		op := ""
+		err = rows.Scan(&change.Seq, &change.ResourceType, &change.ResourceID, &op, &change.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		change.Operation = ChangeOperation(op)
+		changesList = append(changesList, change)
+		cursor = change.Seq
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return changesList, cursor, nil
+}
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrInjectedFault is wrapped by every error that was produced by a FaultInjector rather than by the backend.
+var ErrInjectedFault = errors.New("injected fault")
+
+const defaultRateLimitReset = time.Second
+
+// FaultConfig describes the faults injected into a single operation.
+// Rates are probabilities between 0 and 1 that are rolled independently for every call.
+type FaultConfig struct {
+	// ErrorRate is the probability of the operation failing outright.
+	ErrorRate float64 `mapstructure:"error_rate"`
+	// Latency is added to every call before it is made.
+	Latency time.Duration `mapstructure:"latency"`
+	// RateLimitRate is the probability of the operation being rejected with a RateLimitError.
+	RateLimitRate float64 `mapstructure:"rate_limit_rate"`
+	// RateLimitReset is how far in the future simulated rate limits reset. Defaults to one second.
+	RateLimitReset time.Duration `mapstructure:"rate_limit_reset"`
+	// MidPageFailureRate is the probability of a list operation failing when it requests any page after the first.
+	MidPageFailureRate float64 `mapstructure:"mid_page_failure_rate"`
+}
+
+func (f FaultConfig) enabled() bool {
+	return f.ErrorRate > 0 || f.Latency > 0 || f.RateLimitRate > 0 || f.MidPageFailureRate > 0
+}
+
+func (f FaultConfig) validate() error {
+	for _, r := range []struct {
+		name string
+		rate float64
+	}{
+		{"error_rate", f.ErrorRate},
+		{"rate_limit_rate", f.RateLimitRate},
+		{"mid_page_failure_rate", f.MidPageFailureRate},
+	} {
+		if !(r.rate >= 0 && r.rate <= 1) {
+			return fmt.Errorf("invalid %s %v: must be between 0 and 1", r.name, r.rate)
+		}
+	}
+	if f.Latency < 0 {
+		return fmt.Errorf("invalid latency %s: must not be negative", f.Latency)
+	}
+	return nil
+}
+
+// ParseRate parses the probability of a fault, a number between 0 and 1 such as "0.05".
+func ParseRate(s string) (float64, error) {
+	r, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || !(r >= 0 && r <= 1) {
+		return 0, fmt.Errorf("invalid rate %q: must be a number between 0 and 1", s)
+	}
+	return r, nil
+}
+
+// ChaosConfig configures a FaultInjector.
+type ChaosConfig struct {
+	// Seed seeds the random source used to decide which calls fail. The same seed and the same sequence of calls
+	// produce the same faults. A seed of zero uses the current time.
+	Seed int64 `mapstructure:"seed"`
+	// Default applies to every operation that is not listed in Operations.
+	Default FaultConfig `mapstructure:"default"`
+	// Operations overrides Default for individual operations, keyed by Backend method name, e.g. "ListUsers".
+	Operations map[string]FaultConfig `mapstructure:"operations"`
+}
+
+// Enabled returns true if the configuration injects any faults at all.
+func (c ChaosConfig) Enabled() bool {
+	if c.Default.enabled() {
+		return true
+	}
+	for _, f := range c.Operations {
+		if f.enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if any rate is not between 0 and 1 or any latency is negative.
+func (c ChaosConfig) Validate() error {
+	err := c.Default.validate()
+	if err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for name, f := range c.Operations {
+		err = f.validate()
+		if err != nil {
+			return fmt.Errorf("operation %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (c ChaosConfig) forOperation(name string) FaultConfig {
+	for opName, f := range c.Operations {
+		// Operation names are matched case-insensitively because config file keys are lowercased when loaded.
+		if strings.EqualFold(opName, name) {
+			return f
+		}
+	}
+	return c.Default
+}
+
+// LoadChaosConfig reads a ChaosConfig from a JSON or YAML file.
+func LoadChaosConfig(path string) (ChaosConfig, error) {
+	cfg := ChaosConfig{}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	err := v.ReadInConfig()
+	if err != nil {
+		return cfg, fmt.Errorf("reading chaos config %s: %w", path, err)
+	}
+
+	err = v.Unmarshal(&cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("parsing chaos config %s: %w", path, err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return cfg, fmt.Errorf("chaos config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// FaultInjector simulates a flaky upstream by failing, slowing down and rate limiting backend operations.
+// Use its Intercept method with Intercept to apply it to a Backend.
+type FaultInjector struct {
+	cfg ChaosConfig
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func NewFaultInjector(cfg ChaosConfig) *FaultInjector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &FaultInjector{
+		cfg: cfg,
+		//nolint:gosec // Faults only need to be reproducible, not unpredictable.
+		rng: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// roll returns true with the given probability.
+func (f *FaultInjector) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.rng.Float64() < rate
+}
+
+// Intercept is an Interceptor that injects the faults configured for the operation.
+func (f *FaultInjector) Intercept(ctx context.Context, op Operation) error {
+	faults := f.cfg.forOperation(op.Name)
+
+	if faults.Latency > 0 {
+		select {
+		case <-time.After(faults.Latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if f.roll(faults.RateLimitRate) {
+		reset := faults.RateLimitReset
+		if reset <= 0 {
+			reset = defaultRateLimitReset
+		}
+
+		return &RateLimitError{
+			Operation: op.Name,
+			Limit:     1,
+			Remaining: 0,
+			ResetAt:   time.Now().Add(reset),
+		}
+	}
+
+	if f.roll(faults.ErrorRate) {
+		return fmt.Errorf("%s: %w", op.Name, ErrInjectedFault)
+	}
+
+	if op.Class == OperationClassList && op.PageToken != "" && f.roll(faults.MidPageFailureRate) {
+		return fmt.Errorf("%s: failed fetching page %s: %w", op.Name, op.PageToken, ErrInjectedFault)
+	}
+
+	return nil
+}
+
+// RateLimitError is returned when an operation is rejected because the upstream quota for it is exhausted.
+type RateLimitError struct {
+	Operation string
+	Limit     int64
+	Remaining int64
+	ResetAt   time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: rate limited until %s", e.Operation, e.ResetAt.Format(time.RFC3339))
+}
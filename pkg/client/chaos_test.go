@@ -0,0 +1,106 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// faults returns the outcome of n calls to a fault injector seeded with seed: "ok", "error" or "rate limit".
+func faults(t *testing.T, seed int64, n int) []string {
+	ctx := context.Background()
+
+	f := client.NewFaultInjector(client.ChaosConfig{
+		Seed: seed,
+		Default: client.FaultConfig{
+			ErrorRate:          0.3,
+			RateLimitRate:      0.2,
+			MidPageFailureRate: 0.5,
+		},
+	})
+
+	outcomes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		err := f.Intercept(ctx, client.Operation{Name: "ListUsers", Class: client.OperationClassList, PageToken: "2"})
+
+		var rateLimitErr *client.RateLimitError
+		switch {
+		case err == nil:
+			outcomes = append(outcomes, "ok")
+		case errors.As(err, &rateLimitErr):
+			outcomes = append(outcomes, "rate limit")
+		case errors.Is(err, client.ErrInjectedFault):
+			outcomes = append(outcomes, "error")
+		default:
+			t.Fatalf("call %d: unexpected error %v", i, err)
+		}
+	}
+	return outcomes
+}
+
+func TestFaultInjectorSeed(t *testing.T) {
+	first := faults(t, 42, 200)
+	second := faults(t, 42, 200)
+	if !slices.Equal(first, second) {
+		t.Errorf("the same seed injected different faults:\n%v\n%v", first, second)
+	}
+
+	for _, outcome := range []string{"ok", "error", "rate limit"} {
+		if !slices.Contains(first, outcome) {
+			t.Errorf("no call ended in %s out of %d", outcome, len(first))
+		}
+	}
+
+	other := faults(t, 43, 200)
+	if slices.Equal(first, other) {
+		t.Error("different seeds injected the same faults")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want float64
+	}{
+		{"0", 0},
+		{"0.05", 0.05},
+		{" 1 ", 1},
+	} {
+		got, err := client.ParseRate(tc.s)
+		if err != nil {
+			t.Errorf("%q: %v", tc.s, err)
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %v, want %v", tc.s, got, tc.want)
+		}
+	}
+
+	for _, s := range []string{"", "abc", "5%", "-0.1", "1.5", "NaN"} {
+		_, err := client.ParseRate(s)
+		if err == nil {
+			t.Errorf("%q: got no error", s)
+		}
+	}
+}
+
+func TestChaosConfigValidate(t *testing.T) {
+	for _, cfg := range []client.ChaosConfig{
+		{Default: client.FaultConfig{ErrorRate: 2}},
+		{Default: client.FaultConfig{Latency: -1}},
+		{Operations: map[string]client.FaultConfig{"ListUsers": {RateLimitRate: -0.5}}},
+	} {
+		err := cfg.Validate()
+		if err == nil {
+			t.Errorf("%+v: got no error", cfg)
+		}
+	}
+
+	cfg := client.ChaosConfig{Default: client.FaultConfig{ErrorRate: 0.5, MidPageFailureRate: 1}}
+	err := cfg.Validate()
+	if err != nil {
+		t.Errorf("%+v: %v", cfg, err)
+	}
+}
@@ -3,8 +3,8 @@ package client
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/doug-martin/goqu/v9"
@@ -20,69 +20,123 @@ import (
 
 // Resource model
 // Users are humans
-// Groups can be assigned Users as Admins or Members
-// Roles can be assigned directly to Users or to a Group
+// Groups can be assigned Users as Admins or Members, and other Groups as Members
+// Roles can be assigned directly to Users or to a Group, and have Users as Admins or Delegates who manage who holds them
 // Projects always have a single User as the owner, and can be assigned to Groups
+// Service accounts are non-humans owned by a single User, and authenticate with API keys
+//
+// Every table has a revision counter that is incremented by each write to the table. Rows record the revision of the
+// write that last changed them, which lets callers cheaply tell whether an object changed since they last read it.
 
 type User struct {
-	Id    string
-	Name  string
-	Email string
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Revision int64  `json:"-"`
 }
 
 type Group struct {
-	Id      string
-	Name    string
-	Admins  []string
-	Members []string
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Admins  []string `json:"admins"`
+	Members []string `json:"members"`
+	// MemberGroups are the groups that are members of this group. Their members are members of this group too.
+	MemberGroups []string `json:"member_groups"`
+	Revision     int64    `json:"-"`
 }
 
 type Role struct {
-	Id                string
-	Name              string
-	DirectAssignments []string
-	GroupAssignments  []string
+	Id                string   `json:"id"`
+	Name              string   `json:"name"`
+	DirectAssignments []string `json:"direct_assignments"`
+	GroupAssignments  []string `json:"group_assignments"`
+	// Admins manage who holds the role, including who its admins and delegates are.
+	Admins []string `json:"admins"`
+	// Delegates can assign the role to others, but cannot change its admins or delegates.
+	Delegates []string `json:"delegates"`
+	// System roles are built into the demo application and managed by it.
+	System bool `json:"system"`
+	// Permissions are the permissions granted to the role.
+	Permissions []string `json:"permissions"`
+	Revision    int64    `json:"-"`
 }
 
 type Project struct {
-	Id               string
-	Name             string
-	Owner            string
-	GroupAssignments []string
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Owner is empty once the user owning the project is deleted.
+	Owner            string   `json:"owner"`
+	GroupAssignments []string `json:"group_assignments"`
+	Revision         int64    `json:"-"`
 }
 
 // Client is a simple example client. While this client would normally be responsible for communicating with an upstream.
-// API, for this demo the client is only working with in-memory data.
+// API, for this demo the client is working with data stored in a local SQLite database.
 type Client struct {
-	db         *goqu.Database
+	// db runs the queries of the client, in a transaction if the client is the copy given to a mutator by withTx.
+	db         dbHandle
+	rootDB     *goqu.Database
 	rawDB      *sql.DB
 	dbFileName string
+	driver     string
+	dialect    dialect
+	dsn        string
+	tenant     string
+	tx         bool
 }
 
-func NewClient(dbFileName string, initDB bool) (*Client, error) {
-	c := &Client{}
+// ClientOption configures optional behavior of a Client.
+type ClientOption func(c *Client)
 
-	// Open the database file
+func NewClient(dbFileName string, initDB bool, opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		driver: DriverSQLite,
+		tenant: DefaultTenant,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	d, err := dialectFor(c.driver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open the database file, unless given another database
 	if dbFileName == "" {
 		dbFileName = "baton-demo.db"
 	}
+	if c.dsn == "" {
+		if c.driver != DriverSQLite {
+			return nil, fmt.Errorf("baton-demo: the %s driver needs a data source name", c.driver)
+		}
+		c.dsn = dbFileName
+	}
 
-	rawDB, err := sql.Open("sqlite", dbFileName)
+	rawDB, err := sql.Open(d.driver, d.dataSourceName(c.dsn))
 	if err != nil {
 		return nil, err
 	}
 
-	db := goqu.New("sqlite3", rawDB)
+	db := goqu.New(d.goqu, rawDB)
 	c.dbFileName = dbFileName
+	c.dialect = d
 	c.db = db
+	c.rootDB = db
 	c.rawDB = rawDB
 
 	err = c.initDB(initDB)
 	if err != nil {
-		log.Fatal(err)
+		_ = rawDB.Close()
 		return nil, err
 	}
 
+	_, err = c.GetTenant(context.Background())
+	if err != nil {
+		_ = rawDB.Close()
+		return nil, fmt.Errorf("baton-demo: tenant %s has not been initialized, run with --init-db to create it: %w", c.tenant, err)
+	}
+
 	return c, nil
 }
 
@@ -98,17 +152,65 @@ func (c *Client) validateDB() error {
 	return nil
 }
 
+// bumpRevision increments the revision counter of a table in the client's tenant and returns its new value.
+func (c *Client) bumpRevision(ctx context.Context, table string) (int64, error) {
+	q := c.db.Insert(revisions.Name()).Prepared(true)
+	q = q.Rows(goqu.Record{
+		"tenant_id": c.tenant,
+		"name":      table,
+		"revision":  1,
+	})
+	q = q.OnConflict(goqu.DoUpdate("tenant_id, name", goqu.Record{
+		"revision": goqu.L("? + 1", goqu.I(revisions.Name()+".revision")),
+	}))
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	sq := c.db.From(revisions.Name()).Prepared(true)
+	sq = sq.Select("revision")
+	sq = sq.Where(
+		goqu.C("tenant_id").Eq(c.tenant),
+		goqu.C("name").Eq(table),
+	)
+
+	query, args, err = sq.ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	var rev int64
+	err = c.db.QueryRowContext(ctx, query, args...).Scan(&rev)
+	if err != nil {
+		return 0, err
+	}
+
+	return rev, nil
+}
+
 func (c *Client) initDB(initDB bool) error {
 	err := c.validateDB()
 	if err != nil {
 		return err
 	}
 
-	// ensure all schemas exist
-	for _, t := range allTableDescriptors {
-		query, args := t.Schema()
+	ctx := context.Background()
 
-		_, err := c.db.Exec(query, args...)
+	err = c.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The default tenant always exists, other tenants are only created by initializing the database
+	if initDB || c.tenant == DefaultTenant {
+		err = c.createTenant(ctx, c.db)
 		if err != nil {
 			return err
 		}
@@ -116,38 +218,91 @@ func (c *Client) initDB(initDB bool) error {
 
 	if initDB {
 		seedData := generateDB()
-		err = c.db.WithTx(func(tx *goqu.TxDatabase) error {
+		seedData.forTenant(c.tenant)
+		err = c.rootDB.WithTx(func(tx *goqu.TxDatabase) error {
+			seeded := newGrantOptions(ctx, []GrantOption{WithSource(SourceSeed)})
+
 			baseUserQ := tx.Insert(users.Name()).Prepared(true)
 			baseUserQ = baseUserQ.OnConflict(goqu.DoNothing())
 			for _, user := range seedData.Users {
 				query, args, err := baseUserQ.Rows(goqu.Record{
-					"id":    user.Id,
-					"name":  user.Name,
-					"email": user.Email,
+					"id":        user.Id,
+					"tenant_id": c.tenant,
+					"name":      user.Name,
+					"email":     user.Email,
 				}).ToSQL()
 				if err != nil {
 					return err
 				}
 
-				_, err = tx.Exec(query, args...)
+				res, err := tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+
+				// Only objects that did not exist yet are recorded as inserted
+				inserted, err := res.RowsAffected()
 				if err != nil {
 					return err
 				}
+				if inserted > 0 {
+					err = c.recordChange(ctx, tx, ResourceTypeUser, user.Id, ChangeInsert)
+					if err != nil {
+						return err
+					}
+				}
 			}
 
 			baseGroupQ := tx.Insert(groups.Name()).Prepared(true)
 			baseGroupQ = baseGroupQ.OnConflict(goqu.DoNothing())
 			for _, group := range seedData.Groups {
 				query, args, err := baseGroupQ.Rows(goqu.Record{
-					"id":      group.Id,
-					"name":    group.Name,
-					"admins":  strings.Join(group.Admins, ","),
-					"members": strings.Join(group.Members, ","),
+					"id":        group.Id,
+					"tenant_id": c.tenant,
+					"name":      group.Name,
+					"admins":    strings.Join(group.Admins, ","),
 				}).ToSQL()
 				if err != nil {
 					return err
 				}
 
+				res, err := tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+
+				// Only objects that did not exist yet are recorded as inserted
+				inserted, err := res.RowsAffected()
+				if err != nil {
+					return err
+				}
+				if inserted > 0 {
+					err = c.recordChange(ctx, tx, ResourceTypeGroup, group.Id, ChangeInsert)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			baseGroupMemberQ := tx.Insert(groupMembers.Name()).Prepared(true)
+			baseGroupMemberQ = baseGroupMemberQ.OnConflict(goqu.DoNothing())
+			for _, group := range seedData.Groups {
+				var rows []interface{}
+				for _, userID := range group.Members {
+					rows = append(rows, seeded.row(groupMembers, group.Id, ResourceTypeUser, userID))
+				}
+				for _, memberGroupID := range group.MemberGroups {
+					rows = append(rows, seeded.row(groupMembers, group.Id, ResourceTypeGroup, memberGroupID))
+				}
+				if len(rows) == 0 {
+					continue
+				}
+
+				query, args, err := baseGroupMemberQ.Rows(rows...).ToSQL()
+				if err != nil {
+					return err
+				}
+
 				_, err = tx.Exec(query, args...)
 				if err != nil {
 					return err
@@ -158,34 +313,107 @@ func (c *Client) initDB(initDB bool) error {
 			baseRoleQ = baseRoleQ.OnConflict(goqu.DoNothing())
 			for _, role := range seedData.Roles {
 				query, args, err := baseRoleQ.Rows(goqu.Record{
-					"id":                 role.Id,
-					"name":               role.Name,
-					"direct_assignments": strings.Join(role.DirectAssignments, ","),
-					"group_assignments":  strings.Join(role.GroupAssignments, ","),
+					"id":        role.Id,
+					"tenant_id": c.tenant,
+					"name":      role.Name,
+					"admins":    strings.Join(role.Admins, ","),
+					"delegates": strings.Join(role.Delegates, ","),
+					"system":    role.System,
 				}).ToSQL()
 				if err != nil {
 					return err
 				}
 
-				_, err = tx.Exec(query, args...)
+				res, err := tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+
+				// Only objects that did not exist yet are recorded as inserted
+				inserted, err := res.RowsAffected()
 				if err != nil {
 					return err
 				}
+				if inserted > 0 {
+					err = c.recordChange(ctx, tx, ResourceTypeRole, role.Id, ChangeInsert)
+					if err != nil {
+						return err
+					}
+				}
 			}
 
 			baseProjectQ := tx.Insert(projects.Name()).Prepared(true)
 			baseProjectQ = baseProjectQ.OnConflict(goqu.DoNothing())
 			for _, project := range seedData.Projects {
 				query, args, err := baseProjectQ.Rows(goqu.Record{
-					"id":                project.Id,
-					"name":              project.Name,
-					"owner":             project.Owner,
-					"group_assignments": strings.Join(project.GroupAssignments, ","),
+					"id":        project.Id,
+					"tenant_id": c.tenant,
+					"name":      project.Name,
+					"owner":     project.Owner,
 				}).ToSQL()
 				if err != nil {
 					return err
 				}
 
+				res, err := tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+
+				// Only objects that did not exist yet are recorded as inserted
+				inserted, err := res.RowsAffected()
+				if err != nil {
+					return err
+				}
+				if inserted > 0 {
+					err = c.recordChange(ctx, tx, ResourceTypeProject, project.Id, ChangeInsert)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			baseRoleAssignmentQ := tx.Insert(roleAssignments.Name()).Prepared(true)
+			baseRoleAssignmentQ = baseRoleAssignmentQ.OnConflict(goqu.DoNothing())
+			for _, role := range seedData.Roles {
+				var rows []interface{}
+				for _, userID := range role.DirectAssignments {
+					rows = append(rows, seeded.row(roleAssignments, role.Id, ResourceTypeUser, userID))
+				}
+				for _, groupID := range role.GroupAssignments {
+					rows = append(rows, seeded.row(roleAssignments, role.Id, ResourceTypeGroup, groupID))
+				}
+				if len(rows) == 0 {
+					continue
+				}
+
+				query, args, err := baseRoleAssignmentQ.Rows(rows...).ToSQL()
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+			}
+
+			baseProjectAssignmentQ := tx.Insert(projectAssignments.Name()).Prepared(true)
+			baseProjectAssignmentQ = baseProjectAssignmentQ.OnConflict(goqu.DoNothing())
+			for _, project := range seedData.Projects {
+				var rows []interface{}
+				for _, groupID := range project.GroupAssignments {
+					rows = append(rows, seeded.row(projectAssignments, project.Id, ResourceTypeGroup, groupID))
+				}
+				if len(rows) == 0 {
+					continue
+				}
+
+				query, args, err := baseProjectAssignmentQ.Rows(rows...).ToSQL()
+				if err != nil {
+					return err
+				}
+
 				_, err = tx.Exec(query, args...)
 				if err != nil {
 					return err
@@ -210,6 +438,193 @@ func (c *Client) initDB(initDB bool) error {
 				}
 			}
 
+			baseAccountStateQ := tx.Insert(accountStates.Name()).Prepared(true)
+			baseAccountStateQ = baseAccountStateQ.OnConflict(goqu.DoNothing())
+			for _, state := range seedData.AccountStates {
+				query, args, err := baseAccountStateQ.Rows(goqu.Record{
+					"user_id":                 state.UserID,
+					"failed_logins":           state.FailedLogins,
+					"locked_at":               state.LockedAt,
+					"password_reset_required": state.PasswordResetRequired,
+					"mfa_enrolled":            state.MFAEnrolled,
+					"last_login_at":           state.LastLoginAt,
+				}).ToSQL()
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+			}
+
+			baseSessionQ := tx.Insert(sessions.Name()).Prepared(true)
+			baseSessionQ = baseSessionQ.OnConflict(goqu.DoNothing())
+			for _, session := range seedData.Sessions {
+				query, args, err := baseSessionQ.Rows(goqu.Record{
+					"id":         session.Id,
+					"user_id":    session.UserID,
+					"created_at": session.CreatedAt,
+					"expires_at": session.ExpiresAt,
+				}).ToSQL()
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+			}
+
+			baseServiceAccountQ := tx.Insert(serviceAccounts.Name()).Prepared(true)
+			baseServiceAccountQ = baseServiceAccountQ.OnConflict(goqu.DoNothing())
+			for _, sa := range seedData.ServiceAccounts {
+				query, args, err := baseServiceAccountQ.Rows(goqu.Record{
+					"id":        sa.Id,
+					"tenant_id": c.tenant,
+					"name":      sa.Name,
+					"owner":     sa.Owner,
+				}).ToSQL()
+				if err != nil {
+					return err
+				}
+
+				res, err := tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+
+				// Only objects that did not exist yet are recorded as inserted
+				inserted, err := res.RowsAffected()
+				if err != nil {
+					return err
+				}
+				if inserted > 0 {
+					err = c.recordChange(ctx, tx, ResourceTypeServiceAccount, sa.Id, ChangeInsert)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			baseAPIKeyQ := tx.Insert(apiKeys.Name()).Prepared(true)
+			baseAPIKeyQ = baseAPIKeyQ.OnConflict(goqu.DoNothing())
+			for _, key := range seedData.APIKeys {
+				// Nobody knows the secrets of the seeded keys, they have to be rotated before they can be used
+				_, secretHash, err := newAPIKeySecret()
+				if err != nil {
+					return err
+				}
+
+				query, args, err := baseAPIKeyQ.Rows(goqu.Record{
+					"id":                 key.Id,
+					"tenant_id":          c.tenant,
+					"service_account_id": key.ServiceAccountID,
+					"name":               key.Name,
+					"secret_hash":        secretHash,
+					"created_at":         key.CreatedAt,
+					"expires_at":         key.ExpiresAt,
+					"last_used_at":       key.LastUsedAt,
+				}).ToSQL()
+				if err != nil {
+					return err
+				}
+
+				res, err := tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+
+				// Only objects that did not exist yet are recorded as inserted
+				inserted, err := res.RowsAffected()
+				if err != nil {
+					return err
+				}
+				if inserted > 0 {
+					err = c.recordChange(ctx, tx, ResourceTypeAPIKey, key.Id, ChangeInsert)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			basePermissionQ := tx.Insert(permissions.Name()).Prepared(true)
+			basePermissionQ = basePermissionQ.OnConflict(goqu.DoNothing())
+			for _, permission := range seedData.Permissions {
+				query, args, err := basePermissionQ.Rows(goqu.Record{
+					"id":          permission.Id,
+					"tenant_id":   c.tenant,
+					"name":        permission.Name,
+					"description": permission.Description,
+				}).ToSQL()
+				if err != nil {
+					return err
+				}
+
+				res, err := tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+
+				// Only objects that did not exist yet are recorded as inserted
+				inserted, err := res.RowsAffected()
+				if err != nil {
+					return err
+				}
+				if inserted > 0 {
+					err = c.recordChange(ctx, tx, ResourceTypePermission, permission.Id, ChangeInsert)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			baseRolePermissionQ := tx.Insert(rolePermissions.Name()).Prepared(true)
+			baseRolePermissionQ = baseRolePermissionQ.OnConflict(goqu.DoNothing())
+			for _, role := range seedData.Roles {
+				var rows []interface{}
+				for _, permissionID := range role.Permissions {
+					rows = append(rows, goqu.Record{
+						"role_id":       role.Id,
+						"permission_id": permissionID,
+					})
+				}
+				if len(rows) == 0 {
+					continue
+				}
+
+				query, args, err := baseRolePermissionQ.Rows(rows...).ToSQL()
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+			}
+
+			baseAttributeQ := tx.Insert(resourceAttributes.Name()).Prepared(true)
+			baseAttributeQ = baseAttributeQ.OnConflict(goqu.DoNothing())
+			for _, attr := range seedData.Attributes {
+				query, args, err := baseAttributeQ.Rows(goqu.Record{
+					"resource_type": attr.ResourceType,
+					"resource_id":   attr.ResourceID,
+					"key":           attr.Key,
+					"value":         attr.Value,
+				}).ToSQL()
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(query, args...)
+				if err != nil {
+					return err
+				}
+			}
+
 			return nil
 		})
 		if err != nil {
@@ -220,37 +635,49 @@ func (c *Client) initDB(initDB bool) error {
 	return nil
 }
 
-// ListUsers returns all the users from the database.
-func (c *Client) ListUsers(ctx context.Context) ([]*User, error) {
+// ListUsers returns a page of users from the database, ordered by ID.
+func (c *Client) ListUsers(ctx context.Context, page PageOptions) ([]*User, string, error) {
 	err := c.validateDB()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	q := c.db.From(users.Name()).Prepared(true)
-	q = q.Select("id", "name", "email")
+	q = q.Select("id", "name", "email", "revision")
+	q = q.Where(c.inTenant(users))
+
+	q, offset, err := paginate(q, page)
+	if err != nil {
+		return nil, "", err
+	}
 
 	query, args, err := q.ToSQL()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	rows, err := c.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	defer rows.Close()
 
 	usersList := []*User{}
 	for rows.Next() {
 		user := &User{}
-		err = rows.Scan(&user.Id, &user.Name, &user.Email)
+		err = rows.Scan(&user.Id, &user.Name, &user.Email, &user.Revision)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		usersList = append(usersList, user)
 	}
 
-	return usersList, nil
+	err = rows.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return usersList, page.nextToken(offset, len(usersList)), nil
 }
 
 // GetUser returns the user requested if it exists, else returns an error.
@@ -261,7 +688,8 @@ func (c *Client) GetUser(ctx context.Context, userID string) (*User, error) {
 	}
 
 	q := c.db.From(users.Name()).Prepared(true)
-	q = q.Select("id", "name", "email")
+	q = q.Select("id", "name", "email", "revision")
+	q = q.Where(c.inTenant(users))
 	q = q.Where(goqu.C("id").Eq(userID))
 
 	query, args, err := q.ToSQL()
@@ -271,7 +699,10 @@ func (c *Client) GetUser(ctx context.Context, userID string) (*User, error) {
 
 	row := c.db.QueryRowContext(ctx, query, args...)
 	user := &User{}
-	err = row.Scan(&user.Id, &user.Name, &user.Email)
+	err = row.Scan(&user.Id, &user.Name, &user.Email, &user.Revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -279,149 +710,432 @@ func (c *Client) GetUser(ctx context.Context, userID string) (*User, error) {
 	return user, nil
 }
 
+// DeleteUser deletes a user along with their password, account state and sessions. The user is removed from every
+// group, role and list of managers, and the projects and service accounts they own are left without an owner.
 func (c *Client) DeleteUser(ctx context.Context, userID string) error {
-	err := c.validateDB()
-	if err != nil {
-		return err
-	}
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
 
-	// Check if user exists
-	_, err = c.GetUser(ctx, userID)
-	if err != nil {
-		return err
-	}
+		// Check if user exists
+		_, err = c.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
 
-	// Delete the user
-	q := c.db.Delete(users.Name()).Prepared(true)
-	q = q.Where(goqu.C("id").Eq(userID))
+		err = c.removeUserReferences(ctx, userID)
+		if err != nil {
+			return err
+		}
 
-	query, args, err := q.ToSQL()
-	if err != nil {
-		return err
-	}
+		_, err = c.bumpRevision(ctx, users.Name())
+		if err != nil {
+			return err
+		}
 
-	_, err = c.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
+		// Delete the rows that belong to the user along with it
+		for _, t := range []tableDescriptor{passwords, accountStates, sessions} {
+			q := c.db.Delete(t.Name()).Prepared(true)
+			q = q.Where(goqu.C("user_id").Eq(userID))
 
-	return nil
-}
+			query, args, err := q.ToSQL()
+			if err != nil {
+				return err
+			}
 
-func (c *Client) CreateUser(ctx context.Context, name, email, password string) (*User, error) {
-	err := c.validateDB()
-	if err != nil {
-		return nil, err
-	}
+			_, err = c.db.ExecContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+		}
 
-	user := &User{
-		Id:    ksuid.New().String(),
-		Name:  name,
-		Email: email,
-	}
+		// Delete the user
+		q := c.db.Delete(users.Name()).Prepared(true)
+		q = q.Where(goqu.C("id").Eq(userID))
 
-	q := c.db.Insert(users.Name()).Prepared(true)
-	q = q.Rows(goqu.Record{
-		"id":    user.Id,
-		"name":  user.Name,
-		"email": user.Email,
-	})
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
 
-	query, args, err := q.ToSQL()
-	if err != nil {
-		return nil, err
-	}
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
 
-	_, err = c.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return nil, err
-	}
+		err = c.deleteAttributes(ctx, ResourceTypeUser, userID)
+		if err != nil {
+			return err
+		}
 
-	passwordQ := c.db.Update(passwords.Name()).Prepared(true)
-	passwordQ = passwordQ.Set(goqu.Record{
-		"password": password,
+		err = c.recordChange(ctx, c.db, ResourceTypeUser, userID, ChangeDelete)
+		if err != nil {
+			return err
+		}
+
+		return nil
 	})
-	passwordQ = passwordQ.Where(goqu.C("user_id").Eq(user.Id))
+}
 
-	query, args, err = passwordQ.ToSQL()
-	if err != nil {
-		return nil, err
+// removeUserReferences removes a user from every assignment and list of managers, and clears the owner of the
+// projects and service accounts the user owns. Every resource it changes is stamped with a new revision.
+func (c *Client) removeUserReferences(ctx context.Context, userID string) error {
+	for _, t := range allAssignmentTables {
+		assignments, err := c.queryAssignments(ctx, t, goqu.Ex{
+			"principal_type": ResourceTypeUser,
+			"principal_id":   userID,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, a := range assignments {
+			err = c.removeAssignment(ctx, t, a.ResourceID, ResourceTypeUser, userID)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	_, err = c.db.ExecContext(ctx, query, args...)
+	managers := []struct {
+		table        tableDescriptor
+		resourceType string
+		column       string
+	}{
+		{groups, ResourceTypeGroup, "admins"},
+		{roles, ResourceTypeRole, "admins"},
+		{roles, ResourceTypeRole, "delegates"},
+	}
+	for _, m := range managers {
+		err := c.removeManager(ctx, m.table, m.resourceType, m.column, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Projects require an owner, owned service accounts refer to the owner by a foreign key
+	err := c.clearOwner(ctx, projects, ResourceTypeProject, userID, "")
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return user, nil
+	return c.clearOwner(ctx, serviceAccounts, ResourceTypeServiceAccount, userID, nil)
 }
 
-func (c *Client) ChangePassword(ctx context.Context, userID, password string) error {
-	err := c.validateDB()
+// removeManager removes a user from the comma separated list of managers stored in column of every resource of the
+// table.
+func (c *Client) removeManager(ctx context.Context, t tableDescriptor, resourceType, column, userID string) error {
+	q := c.db.From(t.Name()).Prepared(true)
+	q = q.Select("id", column)
+	q = q.Where(
+		c.inTenant(t),
+		goqu.C(column).Like("%"+userID+"%"),
+	)
+
+	query, args, err := q.ToSQL()
 	if err != nil {
 		return err
 	}
 
-	// Check if user exists
-	_, err = c.GetUser(ctx, userID)
+	rows, err := c.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
 
-	q := c.db.Update(passwords.Name()).Prepared(true)
-	q = q.Set(goqu.Record{
-		"password": password,
-	})
-	q = q.Where(goqu.C("user_id").Eq(userID))
+	managed := map[string][]string{}
+	for rows.Next() {
+		id, ids := "", ""
+		err = rows.Scan(&id, &ids)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if remaining, changed := removeID(splitIDs(ids), userID); changed {
+			managed[id] = remaining
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	for id, remaining := range managed {
+		rev, err := c.bumpRevision(ctx, t.Name())
+		if err != nil {
+			return err
+		}
+
+		uq := c.db.Update(t.Name()).Prepared(true)
+		uq = uq.Set(goqu.Record{
+			"revision": rev,
+			column:     strings.Join(remaining, ","),
+		})
+		uq = uq.Where(goqu.C("id").Eq(id))
+
+		query, args, err := uq.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		err = c.recordChange(ctx, c.db, resourceType, id, ChangeUpdate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clearOwner leaves the resources of the table owned by the user without an owner, storing none in their owner
+// column.
+func (c *Client) clearOwner(ctx context.Context, t tableDescriptor, resourceType, userID string, none interface{}) error {
+	q := c.db.From(t.Name()).Prepared(true)
+	q = q.Select("id")
+	q = q.Where(goqu.C("owner").Eq(userID))
 
 	query, args, err := q.ToSQL()
 	if err != nil {
 		return err
 	}
 
-	_, err = c.db.ExecContext(ctx, query, args...)
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	owned := []string{}
+	for rows.Next() {
+		id := ""
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		owned = append(owned, id)
+	}
+	err = rows.Err()
+	rows.Close()
 	if err != nil {
 		return err
 	}
 
+	for _, id := range owned {
+		rev, err := c.bumpRevision(ctx, t.Name())
+		if err != nil {
+			return err
+		}
+
+		uq := c.db.Update(t.Name()).Prepared(true)
+		uq = uq.Set(goqu.Record{
+			"owner":    none,
+			"revision": rev,
+		})
+		uq = uq.Where(goqu.C("id").Eq(id))
+
+		query, args, err := uq.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		err = c.recordChange(ctx, c.db, resourceType, id, ChangeUpdate)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// ListGroups returns all the groups from the database.
-func (c *Client) ListGroups(ctx context.Context) ([]*Group, error) {
+func (c *Client) CreateUser(ctx context.Context, name, email, password string) (*User, error) {
+	return inTx(ctx, c, func(c *Client) (*User, error) {
+		err := c.validateDB()
+		if err != nil {
+			return nil, err
+		}
+
+		user := &User{
+			Id:    ksuid.New().String(),
+			Name:  name,
+			Email: email,
+		}
+
+		user.Revision, err = c.bumpRevision(ctx, users.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		q := c.db.Insert(users.Name()).Prepared(true)
+		q = q.Rows(goqu.Record{
+			"id":        user.Id,
+			"tenant_id": c.tenant,
+			"name":      user.Name,
+			"email":     user.Email,
+			"revision":  user.Revision,
+		})
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.recordChange(ctx, c.db, ResourceTypeUser, user.Id, ChangeInsert)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = c.bumpRevision(ctx, passwords.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		passwordQ := c.db.Insert(passwords.Name()).Prepared(true)
+		passwordQ = passwordQ.Rows(goqu.Record{
+			"id":       ksuid.New().String(),
+			"user_id":  user.Id,
+			"password": password,
+		})
+
+		query, args, err = passwordQ.ToSQL()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	})
+}
+
+func (c *Client) ChangePassword(ctx context.Context, userID, password string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		// Check if user exists
+		_, err = c.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.bumpRevision(ctx, passwords.Name())
+		if err != nil {
+			return err
+		}
+
+		q := c.db.Update(passwords.Name()).Prepared(true)
+		q = q.Set(goqu.Record{
+			"password": password,
+		})
+		q = q.Where(goqu.C("user_id").Eq(userID))
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		// A new password satisfies a forced password reset
+		resetQ := c.db.Update(accountStates.Name()).Prepared(true)
+		resetQ = resetQ.Set(goqu.Record{
+			"password_reset_required": false,
+		})
+		resetQ = resetQ.Where(goqu.C("user_id").Eq(userID))
+
+		query, args, err = resetQ.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		err = c.recordChange(ctx, c.db, ResourceTypeUser, userID, ChangeUpdate)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// ListGroups returns a page of groups from the database, ordered by ID.
+func (c *Client) ListGroups(ctx context.Context, page PageOptions) ([]*Group, string, error) {
 	err := c.validateDB()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	q := c.db.From(groups.Name()).Prepared(true)
-	q = q.Select("id", "name", "admins", "members")
+	q = q.Select("id", "name", "admins", "revision")
+	q = q.Where(c.inTenant(groups))
+
+	q, offset, err := paginate(q, page)
+	if err != nil {
+		return nil, "", err
+	}
 
 	query, args, err := q.ToSQL()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	rows, err := c.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	defer rows.Close()
 
 	groupsList := []*Group{}
 	for rows.Next() {
 		group := &Group{}
 		admins := ""
-		members := ""
-		err = rows.Scan(&group.Id, &group.Name, &admins, &members)
+		err = rows.Scan(&group.Id, &group.Name, &admins, &group.Revision)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
-		group.Admins = strings.Split(admins, ",")
-		group.Members = strings.Split(members, ",")
+		group.Admins = splitIDs(admins)
 		groupsList = append(groupsList, group)
 	}
 
-	return groupsList, nil
+	err = rows.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = c.loadGroupMembers(ctx, groupsList...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return groupsList, page.nextToken(offset, len(groupsList)), nil
 }
 
 // GetGroup returns the group requested if it exists, else returns an error.
@@ -432,7 +1146,8 @@ func (c *Client) GetGroup(ctx context.Context, groupID string) (*Group, error) {
 	}
 
 	q := c.db.From(groups.Name()).Prepared(true)
-	q = q.Select("id", "name", "admins", "members")
+	q = q.Select("id", "name", "admins", "revision")
+	q = q.Where(c.inTenant(groups))
 	q = q.Where(goqu.C("id").Eq(groupID))
 
 	query, args, err := q.ToSQL()
@@ -443,234 +1158,238 @@ func (c *Client) GetGroup(ctx context.Context, groupID string) (*Group, error) {
 	row := c.db.QueryRowContext(ctx, query, args...)
 	group := &Group{}
 	admins := ""
-	members := ""
-	err = row.Scan(&group.Id, &group.Name, &admins, &members)
+	err = row.Scan(&group.Id, &group.Name, &admins, &group.Revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("group %s: %w", groupID, ErrNotFound)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	group.Admins = strings.Split(admins, ",")
-	group.Members = strings.Split(members, ",")
-
-	return group, nil
-}
-
-func (c *Client) GrantGroupMember(ctx context.Context, groupID, userID string) error {
-	err := c.validateDB()
-	if err != nil {
-		return err
-	}
+	group.Admins = splitIDs(admins)
 
-	group, err := c.GetGroup(ctx, groupID)
+	err = c.loadGroupMembers(ctx, group)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	user, err := c.GetUser(ctx, userID)
-	if err != nil {
-		return err
-	}
+	return group, nil
+}
 
-	// Check whether the user is already a member of the group
-	for _, u := range group.Members {
-		if u == user.Id {
-			return nil
+func (c *Client) GrantGroupMember(ctx context.Context, groupID, userID string, opts ...GrantOption) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
 		}
-	}
 
-	// Add the user to the group
-	group.Members = append(group.Members, userID)
-	q := c.db.Update(groups.Name()).Prepared(true)
-	q = q.Set(goqu.Record{
-		"members": strings.Join(group.Members, ","),
-	})
-	q = q.Where(goqu.C("id").Eq(groupID))
-
-	query, args, err := q.ToSQL()
-	if err != nil {
-		return err
-	}
+		_, err = c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
 
-	_, err = c.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
+		_, err = c.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return c.addAssignment(ctx, groupMembers, groupID, ResourceTypeUser, userID, opts)
+	})
 }
 
 func (c *Client) RevokeGroupMember(ctx context.Context, groupID, userID string) error {
-	err := c.validateDB()
-	if err != nil {
-		return err
-	}
-
-	group, err := c.GetGroup(ctx, groupID)
-	if err != nil {
-		return err
-	}
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
 
-	user, err := c.GetUser(ctx, userID)
-	if err != nil {
-		return err
-	}
+		_, err = c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
 
-	found := false
-	for i, u := range group.Members {
-		if u == user.Id {
-			group.Members = append(group.Members[:i], group.Members[i+1:]...)
-			found = true
+		_, err = c.GetUser(ctx, userID)
+		if err != nil {
+			return err
 		}
-	}
-	if !found {
-		return nil
-	}
 
-	q := c.db.Update(groups.Name()).Prepared(true)
-	q = q.Set(goqu.Record{
-		"members": strings.Join(group.Members, ","),
+		return c.removeAssignment(ctx, groupMembers, groupID, ResourceTypeUser, userID)
 	})
-	q = q.Where(goqu.C("id").Eq(groupID))
-
-	query, args, err := q.ToSQL()
-	if err != nil {
-		return err
-	}
-
-	_, err = c.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
 
 func (c *Client) GrantGroupAdmin(ctx context.Context, groupID, userID string) error {
-	err := c.validateDB()
-	if err != nil {
-		return err
-	}
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
 
-	group, err := c.GetGroup(ctx, groupID)
-	if err != nil {
-		return err
-	}
+		group, err := c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
 
-	user, err := c.GetUser(ctx, userID)
-	if err != nil {
-		return err
-	}
+		user, err := c.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
 
-	// Check whether the user is already an admin of the group
-	for _, u := range group.Admins {
-		if u == user.Id {
-			return nil
+		// Check whether the user is already an admin of the group
+		for _, u := range group.Admins {
+			if u == user.Id {
+				return nil
+			}
 		}
-	}
 
-	group.Admins = append(group.Admins, userID)
+		group.Admins = append(group.Admins, userID)
 
-	q := c.db.Update(groups.Name()).Prepared(true)
-	q = q.Set(goqu.Record{
-		"admins": strings.Join(group.Admins, ","),
-	})
-	q = q.Where(goqu.C("id").Eq(groupID))
+		rev, err := c.bumpRevision(ctx, groups.Name())
+		if err != nil {
+			return err
+		}
 
-	query, args, err := q.ToSQL()
-	if err != nil {
-		return err
-	}
+		q := c.db.Update(groups.Name()).Prepared(true)
+		q = q.Set(goqu.Record{
+			"revision": rev,
+			"admins":   strings.Join(group.Admins, ","),
+		})
+		q = q.Where(goqu.C("id").Eq(groupID))
 
-	_, err = c.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
 
-	return nil
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		err = c.recordChange(ctx, c.db, ResourceTypeGroup, groupID, ChangeUpdate)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
 }
 
 func (c *Client) RevokeGroupAdmin(ctx context.Context, groupID, userID string) error {
-	err := c.validateDB()
-	if err != nil {
-		return err
-	}
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
 
-	group, err := c.GetGroup(ctx, groupID)
-	if err != nil {
-		return err
-	}
+		group, err := c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
 
-	user, err := c.GetUser(ctx, userID)
-	if err != nil {
-		return err
-	}
+		user, err := c.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for i, u := range group.Admins {
+			if u == user.Id {
+				group.Admins = append(group.Admins[:i], group.Admins[i+1:]...)
+				found = true
+			}
+		}
+		if !found {
+			return nil
+		}
+
+		rev, err := c.bumpRevision(ctx, groups.Name())
+		if err != nil {
+			return err
+		}
+
+		q := c.db.Update(groups.Name()).Prepared(true)
+		q = q.Set(goqu.Record{
+			"revision": rev,
+			"admins":   strings.Join(group.Admins, ","),
+		})
+		q = q.Where(goqu.C("id").Eq(groupID))
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
 
-	found := false
-	for i, u := range group.Admins {
-		if u == user.Id {
-			group.Admins = append(group.Admins[:i], group.Admins[i+1:]...)
-			found = true
+		err = c.recordChange(ctx, c.db, ResourceTypeGroup, groupID, ChangeUpdate)
+		if err != nil {
+			return err
 		}
-	}
-	if !found {
-		return nil
-	}
 
-	q := c.db.Update(groups.Name()).Prepared(true)
-	q = q.Set(goqu.Record{
-		"admins": strings.Join(group.Admins, ","),
+		return nil
 	})
-	q = q.Where(goqu.C("id").Eq(groupID))
-
-	query, args, err := q.ToSQL()
-	if err != nil {
-		return err
-	}
-
-	_, err = c.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
 
-// ListRoles returns all the roles from the database.
-func (c *Client) ListRoles(ctx context.Context) ([]*Role, error) {
+// ListRoles returns a page of roles from the database, ordered by ID.
+func (c *Client) ListRoles(ctx context.Context, page PageOptions) ([]*Role, string, error) {
 	err := c.validateDB()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	q := c.db.From(roles.Name()).Prepared(true)
-	q = q.Select("id", "name", "direct_assignments", "group_assignments")
+	q = q.Select("id", "name", "admins", "delegates", "system", "revision")
+	q = q.Where(c.inTenant(roles))
+
+	q, offset, err := paginate(q, page)
+	if err != nil {
+		return nil, "", err
+	}
 
 	query, args, err := q.ToSQL()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	rows, err := c.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	defer rows.Close()
 
 	rolesList := []*Role{}
 	for rows.Next() {
 		role := &Role{}
-		directAssignments := ""
-		groupAssignments := ""
-		err = rows.Scan(&role.Id, &role.Name, &directAssignments, &groupAssignments)
+		admins, delegates := "", ""
+		err = rows.Scan(&role.Id, &role.Name, &admins, &delegates, &role.System, &role.Revision)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
-
-		role.DirectAssignments = strings.Split(directAssignments, ",")
-		role.GroupAssignments = strings.Split(groupAssignments, ",")
+		role.Admins = splitIDs(admins)
+		role.Delegates = splitIDs(delegates)
 		rolesList = append(rolesList, role)
 	}
 
-	return rolesList, nil
+	err = rows.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = c.loadRoleAssignments(ctx, rolesList...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = c.loadRolePermissions(ctx, rolesList...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return rolesList, page.nextToken(offset, len(rolesList)), nil
 }
 
 // GetRole returns the role requested if it exists, else returns an error.
@@ -681,7 +1400,8 @@ func (c *Client) GetRole(ctx context.Context, roleID string) (*Role, error) {
 	}
 
 	q := c.db.From(roles.Name()).Prepared(true)
-	q = q.Select("id", "name", "direct_assignments", "group_assignments")
+	q = q.Select("id", "name", "admins", "delegates", "system", "revision")
+	q = q.Where(c.inTenant(roles))
 	q = q.Where(goqu.C("id").Eq(roleID))
 
 	query, args, err := q.ToSQL()
@@ -691,101 +1411,210 @@ func (c *Client) GetRole(ctx context.Context, roleID string) (*Role, error) {
 
 	row := c.db.QueryRowContext(ctx, query, args...)
 	role := &Role{}
-	directAssignments := ""
-	groupAssignments := ""
-	err = row.Scan(&role.Id, &role.Name, &directAssignments, &groupAssignments)
+	admins, delegates := "", ""
+	err = row.Scan(&role.Id, &role.Name, &admins, &delegates, &role.System, &role.Revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("role %s: %w", roleID, ErrNotFound)
+	}
 	if err != nil {
 		return nil, err
 	}
+	role.Admins = splitIDs(admins)
+	role.Delegates = splitIDs(delegates)
 
-	role.DirectAssignments = strings.Split(directAssignments, ",")
-	role.GroupAssignments = strings.Split(groupAssignments, ",")
-
-	return role, nil
-}
-
-func (c *Client) GrantRole(ctx context.Context, userID, roleID string) error {
-	err := c.validateDB()
+	err = c.loadRoleAssignments(ctx, role)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Check if user exists
-	_, err = c.GetUser(ctx, userID)
+	err = c.loadRolePermissions(ctx, role)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Check if role exists
-	role, err := c.GetRole(ctx, roleID)
-	if err != nil {
-		return err
-	}
+	return role, nil
+}
 
-	// Check if the user is already assigned the role
-	for _, u := range role.DirectAssignments {
-		if u == userID {
-			return nil
+func (c *Client) GrantRole(ctx context.Context, userID, roleID string, opts ...GrantOption) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
 		}
-	}
 
-	// Update the role with the user
-	role.DirectAssignments = append(role.DirectAssignments, userID)
+		// Check if user exists
+		_, err = c.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
 
-	q := c.db.Update(roles.Name()).Prepared(true)
-	q = q.Set(goqu.Record{
-		"direct_assignments": strings.Join(role.DirectAssignments, ","),
+		// Check if role exists
+		role, err := c.GetRole(ctx, roleID)
+		if err != nil {
+			return err
+		}
+
+		err = role.checkMutable()
+		if err != nil {
+			return err
+		}
+
+		return c.addAssignment(ctx, roleAssignments, roleID, ResourceTypeUser, userID, opts)
 	})
-	q = q.Where(goqu.C("id").Eq(roleID))
+}
 
-	query, args, err := q.ToSQL()
-	if err != nil {
-		return err
-	}
+func (c *Client) RevokeRole(ctx context.Context, userID, roleID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
 
-	_, err = c.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
-	}
+		// Check if user exists
+		_, err = c.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		// Check if role exists
+		role, err := c.GetRole(ctx, roleID)
+		if err != nil {
+			return err
+		}
+
+		err = role.checkMutable()
+		if err != nil {
+			return err
+		}
+
+		return c.removeAssignment(ctx, roleAssignments, roleID, ResourceTypeUser, userID)
+	})
 }
 
-func (c *Client) RevokeRole(ctx context.Context, userID, roleID string) error {
+// GrantRoleGroup assigns a role to a group, and through it to the members of the group.
+func (c *Client) GrantRoleGroup(ctx context.Context, groupID, roleID string, opts ...GrantOption) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
+
+		role, err := c.GetRole(ctx, roleID)
+		if err != nil {
+			return err
+		}
+
+		err = role.checkMutable()
+		if err != nil {
+			return err
+		}
+
+		return c.addAssignment(ctx, roleAssignments, roleID, ResourceTypeGroup, groupID, opts)
+	})
+}
+
+// RevokeRoleGroup removes the assignment of a role to a group.
+func (c *Client) RevokeRoleGroup(ctx context.Context, groupID, roleID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
+
+		role, err := c.GetRole(ctx, roleID)
+		if err != nil {
+			return err
+		}
+
+		err = role.checkMutable()
+		if err != nil {
+			return err
+		}
+
+		return c.removeAssignment(ctx, roleAssignments, roleID, ResourceTypeGroup, groupID)
+	})
+}
+
+// GrantRoleAdmin makes a user an admin of a role.
+func (c *Client) GrantRoleAdmin(ctx context.Context, roleID, userID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		return c.updateRoleManagers(ctx, roleID, userID, "admins", func(r *Role) []string { return r.Admins }, addID)
+	})
+}
+
+// RevokeRoleAdmin removes a user from the admins of a role.
+func (c *Client) RevokeRoleAdmin(ctx context.Context, roleID, userID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		return c.updateRoleManagers(ctx, roleID, userID, "admins", func(r *Role) []string { return r.Admins }, removeID)
+	})
+}
+
+// GrantRoleDelegate makes a user a delegate of a role.
+func (c *Client) GrantRoleDelegate(ctx context.Context, roleID, userID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		return c.updateRoleManagers(ctx, roleID, userID, "delegates", func(r *Role) []string { return r.Delegates }, addID)
+	})
+}
+
+// RevokeRoleDelegate removes a user from the delegates of a role.
+func (c *Client) RevokeRoleDelegate(ctx context.Context, roleID, userID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		return c.updateRoleManagers(ctx, roleID, userID, "delegates", func(r *Role) []string { return r.Delegates }, removeID)
+	})
+}
+
+// updateRoleManagers applies update to the admins or the delegates of a role, which are stored in column, and stamps
+// the role with a new revision if they changed.
+func (c *Client) updateRoleManagers(
+	ctx context.Context,
+	roleID, userID, column string,
+	managers func(r *Role) []string,
+	update func([]string, string) ([]string, bool),
+) error {
 	err := c.validateDB()
 	if err != nil {
 		return err
 	}
 
-	// Check if user exists
-	_, err = c.GetUser(ctx, userID)
+	role, err := c.GetRole(ctx, roleID)
 	if err != nil {
 		return err
 	}
 
-	// Check if role exists
-	role, err := c.GetRole(ctx, roleID)
+	err = role.checkMutable()
 	if err != nil {
 		return err
 	}
 
-	found := false
-	// If the user is assigned the role, remove the assignment
-	for i, u := range role.DirectAssignments {
-		if u == userID {
-			role.DirectAssignments = append(role.DirectAssignments[:i], role.DirectAssignments[i+1:]...)
-			found = true
-			break
-		}
+	_, err = c.GetUser(ctx, userID)
+	if err != nil {
+		return err
 	}
 
-	if !found {
+	ids, changed := update(managers(role), userID)
+	if !changed {
 		return nil
 	}
 
+	rev, err := c.bumpRevision(ctx, roles.Name())
+	if err != nil {
+		return err
+	}
+
 	q := c.db.Update(roles.Name()).Prepared(true)
 	q = q.Set(goqu.Record{
-		"direct_assignments": strings.Join(role.DirectAssignments, ","),
+		"revision": rev,
+		column:     strings.Join(ids, ","),
 	})
 	q = q.Where(goqu.C("id").Eq(roleID))
 
@@ -798,43 +1627,83 @@ func (c *Client) RevokeRole(ctx context.Context, userID, roleID string) error {
 	if err != nil {
 		return err
 	}
-	return nil
+
+	return c.recordChange(ctx, c.db, ResourceTypeRole, roleID, ChangeUpdate)
+}
+
+// loadRoleAssignments fills in the users and groups assigned the given roles from the role assignments table.
+func (c *Client) loadRoleAssignments(ctx context.Context, rls ...*Role) error {
+	byID := make(map[string]*Role, len(rls))
+	ids := make([]interface{}, 0, len(rls))
+	for _, r := range rls {
+		r.DirectAssignments = []string{}
+		r.GroupAssignments = []string{}
+		byID[r.Id] = r
+		ids = append(ids, r.Id)
+	}
+
+	return c.loadAssignments(ctx, roleAssignments, ids, func(roleID, principalType, principalID string) error {
+		r := byID[roleID]
+		switch principalType {
+		case ResourceTypeUser:
+			r.DirectAssignments = append(r.DirectAssignments, principalID)
+		case ResourceTypeGroup:
+			r.GroupAssignments = append(r.GroupAssignments, principalID)
+		default:
+			return fmt.Errorf("role %s is assigned to unknown type %s", roleID, principalType)
+		}
+		return nil
+	})
 }
 
-// ListProjects returns all the projects from the database.
-func (c *Client) ListProjects(ctx context.Context) ([]*Project, error) {
+// ListProjects returns a page of projects from the database, ordered by ID.
+func (c *Client) ListProjects(ctx context.Context, page PageOptions) ([]*Project, string, error) {
 	err := c.validateDB()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	q := c.db.From(projects.Name()).Prepared(true)
-	q = q.Select("id", "name", "owner", "group_assignments")
+	q = q.Select("id", "name", "owner", "revision")
+	q = q.Where(c.inTenant(projects))
+
+	q, offset, err := paginate(q, page)
+	if err != nil {
+		return nil, "", err
+	}
 
 	query, args, err := q.ToSQL()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	rows, err := c.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	defer rows.Close()
 
 	projectsList := []*Project{}
 	for rows.Next() {
 		project := &Project{}
-		groupAssignments := ""
-		err = rows.Scan(&project.Id, &project.Name, &project.Owner, &groupAssignments)
+		err = rows.Scan(&project.Id, &project.Name, &project.Owner, &project.Revision)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
-
-		project.GroupAssignments = strings.Split(groupAssignments, ",")
 		projectsList = append(projectsList, project)
 	}
 
-	return projectsList, nil
+	err = rows.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = c.loadProjectAssignments(ctx, projectsList...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return projectsList, page.nextToken(offset, len(projectsList)), nil
 }
 
 // GetProject returns the project requested if it exists, else returns an error.
@@ -845,7 +1714,8 @@ func (c *Client) GetProject(ctx context.Context, projectID string) (*Project, er
 	}
 
 	q := c.db.From(projects.Name()).Prepared(true)
-	q = q.Select("id", "name", "owner", "group_assignments")
+	q = q.Select("id", "name", "owner", "revision")
+	q = q.Where(c.inTenant(projects))
 	q = q.Where(goqu.C("id").Eq(projectID))
 
 	query, args, err := q.ToSQL()
@@ -855,13 +1725,130 @@ func (c *Client) GetProject(ctx context.Context, projectID string) (*Project, er
 
 	row := c.db.QueryRowContext(ctx, query, args...)
 	project := &Project{}
-	groupAssignments := ""
-	err = row.Scan(&project.Id, &project.Name, &project.Owner, &groupAssignments)
+	err = row.Scan(&project.Id, &project.Name, &project.Owner, &project.Revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("project %s: %w", projectID, ErrNotFound)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	project.GroupAssignments = strings.Split(groupAssignments, ",")
+	err = c.loadProjectAssignments(ctx, project)
+	if err != nil {
+		return nil, err
+	}
 
 	return project, nil
 }
+
+// GrantProjectAccess gives a group, and through it the members of the group, access to a project.
+func (c *Client) GrantProjectAccess(ctx context.Context, projectID, groupID string, opts ...GrantOption) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetProject(ctx, projectID)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
+
+		return c.addAssignment(ctx, projectAssignments, projectID, ResourceTypeGroup, groupID, opts)
+	})
+}
+
+// RevokeProjectAccess removes the access of a group to a project.
+func (c *Client) RevokeProjectAccess(ctx context.Context, projectID, groupID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetProject(ctx, projectID)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
+
+		return c.removeAssignment(ctx, projectAssignments, projectID, ResourceTypeGroup, groupID)
+	})
+}
+
+// TransferProjectOwnership makes a user the owner of a project in place of its current owner.
+func (c *Client) TransferProjectOwnership(ctx context.Context, projectID, userID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		project, err := c.GetProject(ctx, projectID)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if project.Owner == userID {
+			return nil
+		}
+
+		rev, err := c.bumpRevision(ctx, projects.Name())
+		if err != nil {
+			return err
+		}
+
+		q := c.db.Update(projects.Name()).Prepared(true)
+		q = q.Set(goqu.Record{
+			"owner":    userID,
+			"revision": rev,
+		})
+		q = q.Where(goqu.C("id").Eq(projectID))
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		return c.recordChange(ctx, c.db, ResourceTypeProject, projectID, ChangeUpdate)
+	})
+}
+
+// loadProjectAssignments fills in the groups with access to the given projects from the project assignments table.
+func (c *Client) loadProjectAssignments(ctx context.Context, prjs ...*Project) error {
+	byID := make(map[string]*Project, len(prjs))
+	ids := make([]interface{}, 0, len(prjs))
+	for _, p := range prjs {
+		p.GroupAssignments = []string{}
+		byID[p.Id] = p
+		ids = append(ids, p.Id)
+	}
+
+	return c.loadAssignments(ctx, projectAssignments, ids, func(projectID, principalType, principalID string) error {
+		if principalType != ResourceTypeGroup {
+			return fmt.Errorf("project %s is assigned to unknown type %s", projectID, principalType)
+		}
+		p := byID[projectID]
+		p.GroupAssignments = append(p.GroupAssignments, principalID)
+		return nil
+	})
+}
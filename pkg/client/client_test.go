@@ -0,0 +1,149 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// TestDeleteUser checks that deleting a user leaves nothing referring to them behind.
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+
+	eachBackend(t, func(t *testing.T, b client.Backend) {
+		user, err := b.CreateUser(ctx, "leaver", "leaver@example.org", "hunter2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		groups, _, err := b.ListGroups(ctx, client.PageOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		group := groups[0]
+
+		roles, _, err := b.ListRoles(ctx, client.PageOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx := slices.IndexFunc(roles, func(r *client.Role) bool { return !r.System })
+		if idx < 0 {
+			t.Fatal("no role that isn't a system role")
+		}
+		role := roles[idx]
+
+		projects, _, err := b.ListProjects(ctx, client.PageOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		project := projects[0]
+
+		serviceAccounts, _, err := b.ListServiceAccounts(ctx, client.PageOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		serviceAccount := serviceAccounts[0]
+
+		for _, grant := range []func() error{
+			func() error { return b.GrantGroupMember(ctx, group.Id, user.Id) },
+			func() error { return b.GrantGroupAdmin(ctx, group.Id, user.Id) },
+			func() error { return b.GrantRole(ctx, user.Id, role.Id) },
+			func() error { return b.GrantRoleAdmin(ctx, role.Id, user.Id) },
+			func() error { return b.GrantRoleDelegate(ctx, role.Id, user.Id) },
+			func() error { return b.TransferProjectOwnership(ctx, project.Id, user.Id) },
+		} {
+			err = grant()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		_, err = b.Authenticate(ctx, user.Name, "hunter2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, cursor, err := b.ListChanges(ctx, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = b.DeleteUser(ctx, user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = b.DeleteUser(ctx, serviceAccount.Owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = b.GetUser(ctx, user.Id)
+		if !errors.Is(err, client.ErrNotFound) {
+			t.Errorf("getting the deleted user: got %v, want %v", err, client.ErrNotFound)
+		}
+		_, err = b.Authenticate(ctx, user.Name, "hunter2")
+		if !errors.Is(err, client.ErrInvalidCredentials) {
+			t.Errorf("logging in as the deleted user: got %v, want %v", err, client.ErrInvalidCredentials)
+		}
+
+		group, err = b.GetGroup(ctx, group.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.Contains(group.Members, user.Id) || slices.Contains(group.Admins, user.Id) {
+			t.Errorf("group %s still has the deleted user as a member or admin", group.Id)
+		}
+
+		role, err = b.GetRole(ctx, role.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.Contains(role.DirectAssignments, user.Id) || slices.Contains(role.Admins, user.Id) || slices.Contains(role.Delegates, user.Id) {
+			t.Errorf("role %s is still assigned to or managed by the deleted user", role.Id)
+		}
+
+		for _, r := range []struct{ resourceType, id string }{
+			{client.ResourceTypeGroup, group.Id},
+			{client.ResourceTypeRole, role.Id},
+		} {
+			assignments, err := b.ListAssignments(ctx, r.resourceType, r.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, a := range assignments {
+				if a.PrincipalID == user.Id {
+					t.Errorf("%s %s is still assigned to the deleted user", r.resourceType, r.id)
+				}
+			}
+		}
+
+		project, err = b.GetProject(ctx, project.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.Owner != "" {
+			t.Errorf("project owned by %q, want no owner", project.Owner)
+		}
+
+		serviceAccount, err = b.GetServiceAccount(ctx, serviceAccount.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if serviceAccount.Owner != "" {
+			t.Errorf("service account owned by %q, want no owner", serviceAccount.Owner)
+		}
+
+		changes, _, err := b.ListChanges(ctx, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{group.Id, role.Id, project.Id, serviceAccount.Id} {
+			if !slices.ContainsFunc(changes, func(c *client.Change) bool { return c.ResourceID == id }) {
+				t.Errorf("no change recorded for %s", id)
+			}
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// stressWriters is the number of goroutines writing to the same objects at once.
+const stressWriters = 24
+
+// TestConcurrentMutations runs overlapping read-modify-write mutations of the same group and role from several
+// clients sharing one database, as connector instances serving provisioning calls do. No write may be lost.
+func TestConcurrentMutations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "baton-demo.db")
+
+	clients := make([]*client.Client, 3)
+	for i := range clients {
+		c, err := client.NewClient(path, i == 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Close() })
+		clients[i] = c
+	}
+	c := clients[0]
+
+	groups, _, err := c.ListGroups(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := groups[0]
+
+	roles, _, err := c.ListRoles(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var role *client.Role
+	for _, r := range roles {
+		if !r.System {
+			role = r
+			break
+		}
+	}
+	if role == nil {
+		t.Fatal("no role that isn't a system role")
+	}
+
+	users := make([]*client.User, stressWriters)
+	run(t, stressWriters, clients, func(i int, c *client.Client) error {
+		u, err := c.CreateUser(ctx, fmt.Sprintf("stress-%d", i), fmt.Sprintf("stress-%d@example.com", i), "")
+		users[i] = u
+		return err
+	})
+
+	run(t, stressWriters, clients, func(i int, c *client.Client) error {
+		err := c.GrantGroupAdmin(ctx, group.Id, users[i].Id)
+		if err != nil {
+			return err
+		}
+		err = c.GrantRoleAdmin(ctx, role.Id, users[i].Id)
+		if err != nil {
+			return err
+		}
+		return c.GrantRole(ctx, users[i].Id, role.Id)
+	})
+
+	group, err = c.GetGroup(ctx, group.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, err = c.GetRole(ctx, role.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		if !contains(group.Admins, u.Id) {
+			t.Errorf("group %s lost admin %s", group.Name, u.Name)
+		}
+		if !contains(role.Admins, u.Id) {
+			t.Errorf("role %s lost admin %s", role.Name, u.Name)
+		}
+		if !contains(role.DirectAssignments, u.Id) {
+			t.Errorf("role %s lost assignment of %s", role.Name, u.Name)
+		}
+	}
+
+	run(t, stressWriters, clients, func(i int, c *client.Client) error {
+		err := c.RevokeGroupAdmin(ctx, group.Id, users[i].Id)
+		if err != nil {
+			return err
+		}
+		return c.RevokeRoleAdmin(ctx, role.Id, users[i].Id)
+	})
+
+	group, err = c.GetGroup(ctx, group.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, err = c.GetRole(ctx, role.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		if contains(group.Admins, u.Id) {
+			t.Errorf("group %s kept revoked admin %s", group.Name, u.Name)
+		}
+		if contains(role.Admins, u.Id) {
+			t.Errorf("role %s kept revoked admin %s", role.Name, u.Name)
+		}
+	}
+
+	// Every write is in the change log exactly once, in the order the writes committed
+	changes, _, err := c.ListChanges(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sort.SliceIsSorted(changes, func(i, j int) bool { return changes[i].Seq < changes[j].Seq }) {
+		t.Error("change log is out of order")
+	}
+	created := 0
+	for _, change := range changes {
+		if change.ResourceType == client.ResourceTypeUser && change.Operation == client.ChangeInsert {
+			for _, u := range users {
+				if change.ResourceID == u.Id {
+					created++
+				}
+			}
+		}
+	}
+	if created != stressWriters {
+		t.Errorf("change log has %d of the %d users created", created, stressWriters)
+	}
+}
+
+// TestConcurrentFailedLogins checks that failed logins are counted even when they overlap, and that a failed login
+// still records the attempt although it fails.
+func TestConcurrentFailedLogins(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := client.NewClient(filepath.Join(t.TempDir(), "baton-demo.db"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	u, err := c.CreateUser(ctx, "stress", "stress@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const attempts = 3
+	run(t, attempts, []*client.Client{c}, func(i int, c *client.Client) error {
+		_, err := c.Authenticate(ctx, u.Name, "wrong")
+		if err == nil {
+			return fmt.Errorf("login with a wrong password succeeded")
+		}
+		return nil
+	})
+
+	state, err := c.GetAccountState(ctx, u.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.FailedLogins != attempts {
+		t.Errorf("counted %d of %d failed logins", state.FailedLogins, attempts)
+	}
+}
+
+// run calls fn from n goroutines at once, spreading them over the clients, and fails the test if any call fails.
+func run(t *testing.T, n int, clients []*client.Client, fn func(i int, c *client.Client) error) {
+	t.Helper()
+
+	start := make(chan struct{})
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs <- fn(i, clients[i%len(clients)])
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
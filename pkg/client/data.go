@@ -1,15 +1,29 @@
 package client
 
+import (
+	"time"
+)
+
 type database struct {
-	Users     []*User
-	Groups    []*Group
-	Roles     []*Role
-	Projects  []*Project
-	Passwords map[string]string
+	Users         []*User
+	Groups        []*Group
+	Roles         []*Role
+	Projects      []*Project
+	Passwords     map[string]string
+	AccountStates []*AccountState
+	Sessions      []*Session
+
+	ServiceAccounts []*ServiceAccount
+	APIKeys         []*APIKey
+
+	Permissions []*Permission
+
+	Attributes []*Attribute
 }
 
 func generateDB() *database {
 	db := &database{}
+	now := time.Now().UTC().Truncate(time.Second)
 
 	db.Users = []*User{
 		{
@@ -58,6 +72,9 @@ func generateDB() *database {
 				"2IC0Wn5oRQqVVn3COFl1O1zSzV6", // Alice
 				"2IC0WoNfqUPT7mgO4FOaViIxBrR", // Bob
 			},
+			MemberGroups: []string{
+				"3Kscz1E7u7nTtsV6jSrJGmHvJI6", // Platform
+			},
 		},
 		{
 			Id:   "2IC0WjepYDBsRp6b7cqrumGsVGt",
@@ -69,6 +86,25 @@ func generateDB() *database {
 				"2IC0Wn7DaxV1xqDpdg7jJRiPtCp", // Dan
 			},
 		},
+		{
+			Id:   "3Kscz1E7u7nTtsV6jSrJGmHvJI6",
+			Name: "Platform",
+			Admins: []string{
+				"2IC0Wo34fcTerFEgWmyffXmfrW8", // Carol
+			},
+			Members: []string{},
+			MemberGroups: []string{
+				"3Kscz2XAPWCGapNN6R8LICQeZ2P", // SRE
+			},
+		},
+		{
+			Id:     "3Kscz2XAPWCGapNN6R8LICQeZ2P",
+			Name:   "SRE",
+			Admins: []string{},
+			Members: []string{
+				"2IC0WoaHVvl2GIQppXQH0flK1yJ", // Frank
+			},
+		},
 	}
 
 	db.Roles = []*Role{
@@ -81,6 +117,22 @@ func generateDB() *database {
 			GroupAssignments: []string{
 				"2IC0WmAPkihbFdZhEPsch5N5WNO", // Engineers
 			},
+			Admins: []string{
+				"2IC0Wn5oRQqVVn3COFl1O1zSzV6", // Alice
+			},
+			Delegates: []string{
+				"2IC0Wo34fcTerFEgWmyffXmfrW8", // Carol
+			},
+			Permissions: []string{
+				"3KseR1G1fb9d1QbA5t3pfX7enWZ", // documents:read
+				"3KseR1v92DdhZTpi6P1Hv4ctspO", // documents:write
+				"3KseQxaaAQtMF2htlvtOaLjzEzF", // documents:share
+				"3KseQwgTKzuNnV195yXKv2FpgJx", // comments:read
+				"3KseR1Mdhg71sv2vVslB9BuM0P5", // comments:write
+				"3KseQwXBf7rgFxwhZC5HkRuIOo2", // projects:read
+				"3KseR2ULV26R7KtqkPNBbhehbgf", // projects:update
+				"3KseR3A4lHReKikz78tvk6untuj", // reports:export
+			},
 		},
 		{
 			Id:                "2IC0WkRTFmsXH4P9TjiQnd29XMT",
@@ -90,9 +142,54 @@ func generateDB() *database {
 				"2IC0WmAPkihbFdZhEPsch5N5WNO", // Engineers
 				"2IC0WjepYDBsRp6b7cqrumGsVGt", // Sales
 			},
+			Admins: []string{}, // No admins
+			Delegates: []string{
+				"2IC0WoNfqUPT7mgO4FOaViIxBrR", // Bob
+			},
+			Permissions: []string{
+				"3KseR1G1fb9d1QbA5t3pfX7enWZ", // documents:read
+				"3KseQwgTKzuNnV195yXKv2FpgJx", // comments:read
+				"3KseQwXBf7rgFxwhZC5HkRuIOo2", // projects:read
+			},
+		},
+		{
+			Id:   "3KseziSY8ObLAsq6ix3KRv5i7yz",
+			Name: "Administrator",
+			DirectAssignments: []string{
+				"2IC0Wn5oRQqVVn3COFl1O1zSzV6", // Alice
+			},
+			GroupAssignments: []string{}, // No group assignments
+			Admins:           []string{},
+			Delegates:        []string{},
+			Permissions: []string{
+				"3KseR1G1fb9d1QbA5t3pfX7enWZ", // documents:read
+				"3KseR1v92DdhZTpi6P1Hv4ctspO", // documents:write
+				"3KseQwelq1S9kkqZjzaUgvISaAx", // documents:delete
+				"3KseQxaaAQtMF2htlvtOaLjzEzF", // documents:share
+				"3KseQwgTKzuNnV195yXKv2FpgJx", // comments:read
+				"3KseR1Mdhg71sv2vVslB9BuM0P5", // comments:write
+				"3KseQwXBf7rgFxwhZC5HkRuIOo2", // projects:read
+				"3KseR2ULV26R7KtqkPNBbhehbgf", // projects:update
+				"3KseR3A4lHReKikz78tvk6untuj", // reports:export
+				"3KseR2mXTARUxtV5WBLdVYDm8Sj", // audit_log:read
+			},
+			System: true,
 		},
 	}
 
+	db.Permissions = []*Permission{
+		{Id: "3KseR1G1fb9d1QbA5t3pfX7enWZ", Name: "documents:read", Description: "View documents"},
+		{Id: "3KseR1v92DdhZTpi6P1Hv4ctspO", Name: "documents:write", Description: "Create and edit documents"},
+		{Id: "3KseQwelq1S9kkqZjzaUgvISaAx", Name: "documents:delete", Description: "Delete documents"},
+		{Id: "3KseQxaaAQtMF2htlvtOaLjzEzF", Name: "documents:share", Description: "Share documents with people outside the organization"},
+		{Id: "3KseQwgTKzuNnV195yXKv2FpgJx", Name: "comments:read", Description: "View comments on documents"},
+		{Id: "3KseR1Mdhg71sv2vVslB9BuM0P5", Name: "comments:write", Description: "Comment on documents"},
+		{Id: "3KseQwXBf7rgFxwhZC5HkRuIOo2", Name: "projects:read", Description: "View projects"},
+		{Id: "3KseR2ULV26R7KtqkPNBbhehbgf", Name: "projects:update", Description: "Change the settings of projects"},
+		{Id: "3KseR3A4lHReKikz78tvk6untuj", Name: "reports:export", Description: "Export reports"},
+		{Id: "3KseR2mXTARUxtV5WBLdVYDm8Sj", Name: "audit_log:read", Description: "View the audit log"},
+	}
+
 	db.Projects = []*Project{
 		{
 			Id:    "2IC0WqENS0dCRHiJ0YvPAidl0D5",
@@ -113,15 +210,143 @@ func generateDB() *database {
 		},
 	}
 
+	lockedAt := now.Add(-2 * time.Hour)
+	aliceLastLogin := now.Add(-30 * time.Minute)
+	db.AccountStates = []*AccountState{
+		{
+			UserID:      "2IC0Wn5oRQqVVn3COFl1O1zSzV6", // Alice
+			MFAEnrolled: true,
+			LastLoginAt: &aliceLastLogin,
+		},
+		{
+			UserID:      "2IC0Wo34fcTerFEgWmyffXmfrW8", // Carol
+			MFAEnrolled: true,
+		},
+		{
+			UserID:       "2IC0Wn7DaxV1xqDpdg7jJRiPtCp", // Dan
+			FailedLogins: 5,
+			LockedAt:     &lockedAt,
+		},
+	}
+
+	db.Sessions = []*Session{
+		{
+			Id:        "3KscFUcu9ipS7KnuWNYrKUzbxXn",
+			UserID:    "2IC0Wn5oRQqVVn3COFl1O1zSzV6", // Alice
+			CreatedAt: now.Add(-3 * time.Hour),
+			ExpiresAt: now.Add(21 * time.Hour),
+		},
+		{
+			Id:        "3KscFVtdLn5yiOY3oDXrfTa6uRo",
+			UserID:    "2IC0Wn5oRQqVVn3COFl1O1zSzV6", // Alice
+			CreatedAt: now.Add(-30 * time.Minute),
+			ExpiresAt: now.Add(23*time.Hour + 30*time.Minute),
+		},
+		{
+			Id:        "3KscFW7WOPWyZk535JcrVS6ShHk",
+			UserID:    "2IC0WoNfqUPT7mgO4FOaViIxBrR", // Bob
+			CreatedAt: now.Add(-1 * time.Hour),
+			ExpiresAt: now.Add(23 * time.Hour),
+		},
+	}
+
+	db.ServiceAccounts = []*ServiceAccount{
+		{
+			Id:    "3KscitOHIUhJDGT7ypNvY3q17zI",
+			Name:  "ci-deployer",
+			Owner: "2IC0WoNfqUPT7mgO4FOaViIxBrR", // Bob
+		},
+		{
+			Id:    "3KsciwB61La9ReFge6IL7rptNbM",
+			Name:  "sales-reporting",
+			Owner: "2IC0WoaHVvl2GIQppXQH0flK1yJ", // Frank
+		},
+	}
+
+	githubActionsExpiresAt := now.Add(20 * 24 * time.Hour)
+	githubActionsLastUsedAt := now.Add(-15 * time.Minute)
+	legacyExpiresAt := now.Add(-40 * 24 * time.Hour)
+	legacyLastUsedAt := now.Add(-45 * 24 * time.Hour)
+	db.APIKeys = []*APIKey{
+		{
+			Id:               "3KscixK6defCCFa191nTC73ERDe",
+			ServiceAccountID: "3KscitOHIUhJDGT7ypNvY3q17zI", // ci-deployer
+			Name:             "github-actions",
+			CreatedAt:        now.Add(-70 * 24 * time.Hour),
+			ExpiresAt:        &githubActionsExpiresAt,
+			LastUsedAt:       &githubActionsLastUsedAt,
+		},
+		{
+			Id:               "3KsciwiOFBXT17LcSeUemv7aPF3",
+			ServiceAccountID: "3KscitOHIUhJDGT7ypNvY3q17zI", // ci-deployer
+			Name:             "legacy-jenkins",
+			CreatedAt:        now.Add(-130 * 24 * time.Hour),
+			ExpiresAt:        &legacyExpiresAt,
+			LastUsedAt:       &legacyLastUsedAt,
+		},
+		{
+			Id:               "3KscixdFEq2023toOtUezdpEcos",
+			ServiceAccountID: "3KsciwB61La9ReFge6IL7rptNbM", // sales-reporting
+			Name:             "crm-sync",
+			CreatedAt:        now.Add(-10 * 24 * time.Hour),
+		},
+	}
+
+	db.Attributes = []*Attribute{
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0Wn5oRQqVVn3COFl1O1zSzV6", Key: "department", Value: "Engineering"}, // Alice
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0Wn5oRQqVVn3COFl1O1zSzV6", Key: "title", Value: "Staff Engineer"},
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0Wn5oRQqVVn3COFl1O1zSzV6", Key: "location", Value: "Berlin"},
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0WoNfqUPT7mgO4FOaViIxBrR", Key: "department", Value: "Engineering"}, // Bob
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0WoNfqUPT7mgO4FOaViIxBrR", Key: "title", Value: "Engineering Manager"},
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0WoNfqUPT7mgO4FOaViIxBrR", Key: "location", Value: "New York"},
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0Wo34fcTerFEgWmyffXmfrW8", Key: "department", Value: "Engineering"}, // Carol
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0Wo34fcTerFEgWmyffXmfrW8", Key: "title", Value: "Site Reliability Engineer"},
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0Wn7DaxV1xqDpdg7jJRiPtCp", Key: "department", Value: "Sales"}, // Dan
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0Wn7DaxV1xqDpdg7jJRiPtCp", Key: "title", Value: "Account Executive"},
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0WoaHVvl2GIQppXQH0flK1yJ", Key: "department", Value: "Sales"}, // Frank
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0WoaHVvl2GIQppXQH0flK1yJ", Key: "title", Value: "Head of Sales"},
+		{ResourceType: ResourceTypeUser, ResourceID: "2IC0WoaHVvl2GIQppXQH0flK1yJ", Key: "employment_type", Value: "contractor"},
+
+		{ResourceType: ResourceTypeGroup, ResourceID: "2IC0WmAPkihbFdZhEPsch5N5WNO", Key: "group_color", Value: "green"}, // Engineers
+		{ResourceType: ResourceTypeGroup, ResourceID: "2IC0WmAPkihbFdZhEPsch5N5WNO", Key: "cost_center", Value: "CC-100"},
+		{ResourceType: ResourceTypeGroup, ResourceID: "2IC0WjepYDBsRp6b7cqrumGsVGt", Key: "group_color", Value: "green"}, // Sales
+		{ResourceType: ResourceTypeGroup, ResourceID: "2IC0WjepYDBsRp6b7cqrumGsVGt", Key: "cost_center", Value: "CC-200"},
+		{ResourceType: ResourceTypeGroup, ResourceID: "3Kscz1E7u7nTtsV6jSrJGmHvJI6", Key: "group_color", Value: "green"}, // Platform
+		{ResourceType: ResourceTypeGroup, ResourceID: "3Kscz2XAPWCGapNN6R8LICQeZ2P", Key: "group_color", Value: "green"}, // SRE
+		{ResourceType: ResourceTypeGroup, ResourceID: "3Kscz2XAPWCGapNN6R8LICQeZ2P", Key: "on_call", Value: "true"},
+
+		{ResourceType: ResourceTypeRole, ResourceID: "2IC0WmaHecJdzo5jYnQiTh2BVlB", Key: "risk_level", Value: "high"}, // Editor
+		{ResourceType: ResourceTypeRole, ResourceID: "2IC0WmaHecJdzo5jYnQiTh2BVlB", Key: "requires_approval", Value: "true"},
+		{ResourceType: ResourceTypeRole, ResourceID: "2IC0WkRTFmsXH4P9TjiQnd29XMT", Key: "risk_level", Value: "low"}, // Reader
+
+		{ResourceType: ResourceTypeServiceAccount, ResourceID: "3KscitOHIUhJDGT7ypNvY3q17zI", Key: "environment", Value: "production"}, // ci-deployer
+		{ResourceType: ResourceTypeServiceAccount, ResourceID: "3KsciwB61La9ReFge6IL7rptNbM", Key: "environment", Value: "staging"},    // sales-reporting
+	}
+
 	return db
 }
 
 var allTableDescriptors = []tableDescriptor{
+	schemaVersions,
+	tenants,
 	users,
 	groups,
+	groupMembers,
 	roles,
+	roleAssignments,
 	projects,
+	projectAssignments,
 	passwords,
+	revisions,
+	changes,
+	accountStates,
+	sessions,
+	logins,
+	serviceAccounts,
+	apiKeys,
+	resourceAttributes,
+	permissions,
+	rolePermissions,
 }
 
 type tableDescriptor interface {
@@ -138,7 +363,8 @@ func (t *usersTable) Name() string {
 }
 
 func (t *usersTable) Schema() (string, []interface{}) {
-	return "CREATE TABLE IF NOT EXISTS users (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, email TEXT)", []interface{}{}
+	return "CREATE TABLE IF NOT EXISTS users (id TEXT PRIMARY KEY, tenant_id TEXT NOT NULL, name TEXT NOT NULL, email TEXT, revision INTEGER NOT NULL DEFAULT 0, " +
+		"UNIQUE(tenant_id, name), FOREIGN KEY(tenant_id) REFERENCES tenants(id))", []interface{}{}
 }
 
 var groups = (*groupsTable)(nil)
@@ -150,7 +376,35 @@ func (t *groupsTable) Name() string {
 }
 
 func (t *groupsTable) Schema() (string, []interface{}) {
-	return "CREATE TABLE IF NOT EXISTS groups (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, admins TEXT NOT NULL, members TEXT NOT NULL)", []interface{}{}
+	return "CREATE TABLE IF NOT EXISTS groups (id TEXT PRIMARY KEY, tenant_id TEXT NOT NULL, name TEXT NOT NULL, admins TEXT NOT NULL, revision INTEGER NOT NULL DEFAULT 0, " +
+		"UNIQUE(tenant_id, name), FOREIGN KEY(tenant_id) REFERENCES tenants(id))", []interface{}{}
+}
+
+var groupMembers = (*groupMembersTable)(nil)
+
+// groupMembersTable holds the members of every group. Members are principals, either users or other groups.
+type groupMembersTable struct{}
+
+func (t *groupMembersTable) Name() string {
+	return "group_members"
+}
+
+func (t *groupMembersTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS group_members (group_id TEXT NOT NULL, principal_type TEXT NOT NULL, principal_id TEXT NOT NULL, expires_at TIMESTAMP, " +
+		"granted_by TEXT NOT NULL DEFAULT '', granted_at TIMESTAMP NOT NULL, reason TEXT NOT NULL DEFAULT '', source TEXT NOT NULL, " +
+		"PRIMARY KEY(group_id, principal_type, principal_id), FOREIGN KEY(group_id) REFERENCES groups(id))", []interface{}{}
+}
+
+func (t *groupMembersTable) resourceType() string {
+	return ResourceTypeGroup
+}
+
+func (t *groupMembersTable) resourceColumn() string {
+	return "group_id"
+}
+
+func (t *groupMembersTable) resourceTable() tableDescriptor {
+	return groups
 }
 
 var roles = (*rolesTable)(nil)
@@ -162,7 +416,35 @@ func (t *rolesTable) Name() string {
 }
 
 func (t *rolesTable) Schema() (string, []interface{}) {
-	return "CREATE TABLE IF NOT EXISTS roles (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, direct_assignments TEXT NOT NULL, group_assignments TEXT NOT NULL)", []interface{}{}
+	return "CREATE TABLE IF NOT EXISTS roles (id TEXT PRIMARY KEY, tenant_id TEXT NOT NULL, name TEXT NOT NULL, admins TEXT NOT NULL DEFAULT '', delegates TEXT NOT NULL DEFAULT '', " +
+		"system BOOLEAN NOT NULL DEFAULT FALSE, revision INTEGER NOT NULL DEFAULT 0, UNIQUE(tenant_id, name), FOREIGN KEY(tenant_id) REFERENCES tenants(id))", []interface{}{}
+}
+
+var roleAssignments = (*roleAssignmentsTable)(nil)
+
+// roleAssignmentsTable holds the principals assigned every role, either users or groups.
+type roleAssignmentsTable struct{}
+
+func (t *roleAssignmentsTable) Name() string {
+	return "role_assignments"
+}
+
+func (t *roleAssignmentsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS role_assignments (role_id TEXT NOT NULL, principal_type TEXT NOT NULL, principal_id TEXT NOT NULL, expires_at TIMESTAMP, " +
+		"granted_by TEXT NOT NULL DEFAULT '', granted_at TIMESTAMP NOT NULL, reason TEXT NOT NULL DEFAULT '', source TEXT NOT NULL, " +
+		"PRIMARY KEY(role_id, principal_type, principal_id), FOREIGN KEY(role_id) REFERENCES roles(id))", []interface{}{}
+}
+
+func (t *roleAssignmentsTable) resourceType() string {
+	return ResourceTypeRole
+}
+
+func (t *roleAssignmentsTable) resourceColumn() string {
+	return "role_id"
+}
+
+func (t *roleAssignmentsTable) resourceTable() tableDescriptor {
+	return roles
 }
 
 var projects = (*projectsTable)(nil)
@@ -174,7 +456,35 @@ func (t *projectsTable) Name() string {
 }
 
 func (t *projectsTable) Schema() (string, []interface{}) {
-	return "CREATE TABLE IF NOT EXISTS projects (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, owner TEXT NOT NULL, group_assignments TEXT NOT NULL)", []interface{}{}
+	return "CREATE TABLE IF NOT EXISTS projects (id TEXT PRIMARY KEY, tenant_id TEXT NOT NULL, name TEXT NOT NULL, owner TEXT NOT NULL, revision INTEGER NOT NULL DEFAULT 0, " +
+		"UNIQUE(tenant_id, name), FOREIGN KEY(tenant_id) REFERENCES tenants(id))", []interface{}{}
+}
+
+var projectAssignments = (*projectAssignmentsTable)(nil)
+
+// projectAssignmentsTable holds the groups with access to every project.
+type projectAssignmentsTable struct{}
+
+func (t *projectAssignmentsTable) Name() string {
+	return "project_assignments"
+}
+
+func (t *projectAssignmentsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS project_assignments (project_id TEXT NOT NULL, principal_type TEXT NOT NULL, principal_id TEXT NOT NULL, expires_at TIMESTAMP, " +
+		"granted_by TEXT NOT NULL DEFAULT '', granted_at TIMESTAMP NOT NULL, reason TEXT NOT NULL DEFAULT '', source TEXT NOT NULL, " +
+		"PRIMARY KEY(project_id, principal_type, principal_id), FOREIGN KEY(project_id) REFERENCES projects(id))", []interface{}{}
+}
+
+func (t *projectAssignmentsTable) resourceType() string {
+	return ResourceTypeProject
+}
+
+func (t *projectAssignmentsTable) resourceColumn() string {
+	return "project_id"
+}
+
+func (t *projectAssignmentsTable) resourceTable() tableDescriptor {
+	return projects
 }
 
 var passwords = (*passwordsTable)(nil)
@@ -188,3 +498,149 @@ func (t *passwordsTable) Name() string {
 func (t *passwordsTable) Schema() (string, []interface{}) {
 	return "CREATE TABLE IF NOT EXISTS passwords (id TEXT PRIMARY KEY, password TEXT NOT NULL, user_id TEXT NOT NULL, FOREIGN KEY(user_id) REFERENCES users(id))", []interface{}{}
 }
+
+var revisions = (*revisionsTable)(nil)
+
+// revisionsTable holds the revision counter of every other table, counted separately for each tenant.
+type revisionsTable struct{}
+
+func (t *revisionsTable) Name() string {
+	return "revisions"
+}
+
+func (t *revisionsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS revisions (tenant_id TEXT NOT NULL, name TEXT NOT NULL, revision INTEGER NOT NULL, " +
+		"PRIMARY KEY(tenant_id, name), FOREIGN KEY(tenant_id) REFERENCES tenants(id))", []interface{}{}
+}
+
+var changes = (*changesTable)(nil)
+
+// changesTable is the change log, an append-only record of every write to a resource. Every tenant reads only the
+// changes to its own resources.
+type changesTable struct{}
+
+func (t *changesTable) Name() string {
+	return "changes"
+}
+
+func (t *changesTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS changes (seq INTEGER PRIMARY KEY AUTOINCREMENT, tenant_id TEXT NOT NULL, resource_type TEXT NOT NULL, " +
+		"resource_id TEXT NOT NULL, operation TEXT NOT NULL, created_at TIMESTAMP NOT NULL, FOREIGN KEY(tenant_id) REFERENCES tenants(id))", []interface{}{}
+}
+
+func (t *changesTable) postgresSchema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS changes (seq BIGSERIAL PRIMARY KEY, tenant_id TEXT NOT NULL, resource_type TEXT NOT NULL, " +
+		"resource_id TEXT NOT NULL, operation TEXT NOT NULL, created_at TIMESTAMP NOT NULL, FOREIGN KEY(tenant_id) REFERENCES tenants(id))", []interface{}{}
+}
+
+var accountStates = (*accountStatesTable)(nil)
+
+// accountStatesTable holds the lockout counters and security flags of user accounts.
+type accountStatesTable struct{}
+
+func (t *accountStatesTable) Name() string {
+	return "account_states"
+}
+
+func (t *accountStatesTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS account_states (user_id TEXT PRIMARY KEY, failed_logins INTEGER NOT NULL DEFAULT 0, locked_at TIMESTAMP, " +
+		"password_reset_required BOOLEAN NOT NULL DEFAULT FALSE, mfa_enrolled BOOLEAN NOT NULL DEFAULT FALSE, last_login_at TIMESTAMP, FOREIGN KEY(user_id) REFERENCES users(id))", []interface{}{}
+}
+
+var sessions = (*sessionsTable)(nil)
+
+type sessionsTable struct{}
+
+func (t *sessionsTable) Name() string {
+	return "sessions"
+}
+
+func (t *sessionsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS sessions (id TEXT PRIMARY KEY, user_id TEXT NOT NULL, created_at TIMESTAMP NOT NULL, expires_at TIMESTAMP NOT NULL, " +
+		"revoked_at TIMESTAMP, FOREIGN KEY(user_id) REFERENCES users(id))", []interface{}{}
+}
+
+var logins = (*loginsTable)(nil)
+
+// loginsTable records every login attempt, successful or not. Attempts for unknown logins have no user ID.
+type loginsTable struct{}
+
+func (t *loginsTable) Name() string {
+	return "logins"
+}
+
+func (t *loginsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS logins (id TEXT PRIMARY KEY, user_id TEXT, login TEXT NOT NULL, succeeded BOOLEAN NOT NULL, " +
+		"failure_reason TEXT NOT NULL, created_at TIMESTAMP NOT NULL)", []interface{}{}
+}
+
+var serviceAccounts = (*serviceAccountsTable)(nil)
+
+// serviceAccountsTable holds the service accounts. The owner of a service account whose owner was deleted is NULL.
+type serviceAccountsTable struct{}
+
+func (t *serviceAccountsTable) Name() string {
+	return "service_accounts"
+}
+
+func (t *serviceAccountsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS service_accounts (id TEXT PRIMARY KEY, tenant_id TEXT NOT NULL, name TEXT NOT NULL, owner TEXT, revision INTEGER NOT NULL DEFAULT 0, " +
+		"UNIQUE(tenant_id, name), FOREIGN KEY(tenant_id) REFERENCES tenants(id), FOREIGN KEY(owner) REFERENCES users(id))", []interface{}{}
+}
+
+var apiKeys = (*apiKeysTable)(nil)
+
+// apiKeysTable holds the API keys of service accounts. Only a hash of each secret is stored.
+type apiKeysTable struct{}
+
+func (t *apiKeysTable) Name() string {
+	return "api_keys"
+}
+
+func (t *apiKeysTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS api_keys (id TEXT PRIMARY KEY, tenant_id TEXT NOT NULL, service_account_id TEXT NOT NULL, name TEXT NOT NULL, secret_hash TEXT NOT NULL, " +
+		"created_at TIMESTAMP NOT NULL, expires_at TIMESTAMP, last_used_at TIMESTAMP, revision INTEGER NOT NULL DEFAULT 0, " +
+		"FOREIGN KEY(tenant_id) REFERENCES tenants(id), FOREIGN KEY(service_account_id) REFERENCES service_accounts(id))", []interface{}{}
+}
+
+var resourceAttributes = (*resourceAttributesTable)(nil)
+
+// resourceAttributesTable holds the custom attributes of resources of every type, one row per attribute.
+type resourceAttributesTable struct{}
+
+func (t *resourceAttributesTable) Name() string {
+	return "resource_attributes"
+}
+
+func (t *resourceAttributesTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS resource_attributes (resource_type TEXT NOT NULL, resource_id TEXT NOT NULL, key TEXT NOT NULL, value TEXT NOT NULL, " +
+		"PRIMARY KEY(resource_type, resource_id, key))", []interface{}{}
+}
+
+var permissions = (*permissionsTable)(nil)
+
+// permissionsTable is the catalog of the permissions that can be granted to roles.
+type permissionsTable struct{}
+
+func (t *permissionsTable) Name() string {
+	return "permissions"
+}
+
+func (t *permissionsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS permissions (id TEXT PRIMARY KEY, tenant_id TEXT NOT NULL, name TEXT NOT NULL, description TEXT NOT NULL DEFAULT '', " +
+		"revision INTEGER NOT NULL DEFAULT 0, UNIQUE(tenant_id, name), FOREIGN KEY(tenant_id) REFERENCES tenants(id))", []interface{}{}
+}
+
+var rolePermissions = (*rolePermissionsTable)(nil)
+
+// rolePermissionsTable holds the permissions granted to every role.
+type rolePermissionsTable struct{}
+
+func (t *rolePermissionsTable) Name() string {
+	return "role_permissions"
+}
+
+func (t *rolePermissionsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS role_permissions (role_id TEXT NOT NULL, permission_id TEXT NOT NULL, " +
+		"PRIMARY KEY(role_id, permission_id), FOREIGN KEY(role_id) REFERENCES roles(id), FOREIGN KEY(permission_id) REFERENCES permissions(id))", []interface{}{}
+}
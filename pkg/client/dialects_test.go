@@ -0,0 +1,78 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// TestPostgresSchema checks the statements creating the tables in Postgres for SQLite-only syntax, since they only
+// run against a database when a Postgres DSN is given to the tests.
+func TestPostgresSchema(t *testing.T) {
+	for _, table := range allTableDescriptors {
+		query, _ := schemaFor(table, DriverPostgres)
+
+		if !strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS "+table.Name()+" (") {
+			t.Errorf("%s: %q doesn't create the table", table.Name(), query)
+		}
+		for _, sqliteOnly := range []string{"AUTOINCREMENT", "WITHOUT ROWID", "STRICT"} {
+			if strings.Contains(strings.ToUpper(query), sqliteOnly) {
+				t.Errorf("%s: %q uses %s, which Postgres doesn't support", table.Name(), query, sqliteOnly)
+			}
+		}
+	}
+
+	query, _ := schemaFor(changes, DriverSQLite)
+	if !strings.Contains(query, "AUTOINCREMENT") {
+		t.Errorf("SQLite change log %q doesn't number changes with AUTOINCREMENT", query)
+	}
+}
+
+// TestPostgresQueries checks that the queries relying on upserts render for Postgres.
+func TestPostgresQueries(t *testing.T) {
+	d, err := dialectFor(DriverPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := goqu.Dialect(d.goqu)
+
+	q := db.Insert(revisions.Name()).Prepared(true)
+	q = q.Rows(goqu.Record{"tenant_id": DefaultTenant, "name": users.Name(), "revision": 1})
+	q = q.OnConflict(goqu.DoUpdate("tenant_id, name", goqu.Record{
+		"revision": goqu.L("? + 1", goqu.I(revisions.Name()+".revision")),
+	}))
+
+	query, _, err := q.ToSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "revisions" ("name", "revision", "tenant_id") VALUES ($1, $2, $3) ` +
+		`ON CONFLICT (tenant_id, name) DO UPDATE SET "revision"="revisions"."revision" + 1`
+	if query != want {
+		t.Errorf("got %s, want %s", query, want)
+	}
+}
+
+func TestDataSourceName(t *testing.T) {
+	for _, tc := range []struct {
+		driver, dsn, want string
+	}{
+		{DriverSQLite, "baton-demo.db", "baton-demo.db?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"},
+		{DriverSQLite, "file:baton-demo.db?mode=rwc", "file:baton-demo.db?mode=rwc&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"},
+		{DriverPostgres, "postgres://localhost:5432/demo?sslmode=disable", "postgres://localhost:5432/demo?sslmode=disable"},
+	} {
+		d, err := dialectFor(tc.driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.dataSourceName(tc.dsn); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.driver, got, tc.want)
+		}
+	}
+
+	_, err := dialectFor("oracle")
+	if err == nil {
+		t.Error("unknown driver: got no error")
+	}
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"fmt"
+)
+
+// ImmutableError is returned when a change is refused because the resource it touches is managed by the demo
+// application itself, such as the assignments of a system role.
+type ImmutableError struct {
+	ResourceType string
+	ResourceID   string
+	Reason       string
+}
+
+func (e *ImmutableError) Error() string {
+	return fmt.Sprintf("%s %s is immutable: %s", e.ResourceType, e.ResourceID, e.Reason)
+}
+
+// checkMutable returns an ImmutableError if the role is a system role, whose holders, managers and permissions cannot
+// be changed.
+func (r *Role) checkMutable() error {
+	if r.System {
+		return &ImmutableError{
+			ResourceType: ResourceTypeRole,
+			ResourceID:   r.Id,
+			Reason:       "system roles are managed by the demo application",
+		}
+	}
+	return nil
+}
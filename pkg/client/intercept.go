@@ -0,0 +1,421 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// OperationClass groups backend operations by the kind of work they do upstream.
+type OperationClass string
+
+const (
+	OperationClassList   OperationClass = "list"
+	OperationClassGet    OperationClass = "get"
+	OperationClassMutate OperationClass = "mutate"
+)
+
+// Operation describes a single Backend call that is about to be made.
+type Operation struct {
+	// Name is the name of the Backend method, for example "ListUsers".
+	Name  string
+	Class OperationClass
+	// PageToken is the page requested by a list operation. It is empty for the first page and for other classes.
+	PageToken string
+}
+
+// Interceptor is called before every Backend operation. Returning an error fails the operation without calling the
+// wrapped backend.
+type Interceptor func(ctx context.Context, op Operation) error
+
+// Intercept wraps a backend so that every operation is passed through the interceptors, in order, before it reaches
+// the backend.
+func Intercept(b Backend, interceptors ...Interceptor) Backend {
+	if len(interceptors) == 0 {
+		return b
+	}
+
+	return &interceptedBackend{
+		backend:      b,
+		interceptors: interceptors,
+	}
+}
+
+type interceptedBackend struct {
+	backend      Backend
+	interceptors []Interceptor
+}
+
+func (i *interceptedBackend) before(ctx context.Context, name string, class OperationClass) error {
+	return i.beforePage(ctx, name, class, "")
+}
+
+func (i *interceptedBackend) beforePage(ctx context.Context, name string, class OperationClass, pageToken string) error {
+	op := Operation{
+		Name:      name,
+		Class:     class,
+		PageToken: pageToken,
+	}
+
+	for _, interceptor := range i.interceptors {
+		err := interceptor(ctx, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *interceptedBackend) ListUsers(ctx context.Context, page PageOptions) ([]*User, string, error) {
+	if err := i.beforePage(ctx, "ListUsers", OperationClassList, page.Token); err != nil {
+		return nil, "", err
+	}
+	return i.backend.ListUsers(ctx, page)
+}
+
+func (i *interceptedBackend) GetUser(ctx context.Context, userID string) (*User, error) {
+	if err := i.before(ctx, "GetUser", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetUser(ctx, userID)
+}
+
+func (i *interceptedBackend) CreateUser(ctx context.Context, name, email, password string) (*User, error) {
+	if err := i.before(ctx, "CreateUser", OperationClassMutate); err != nil {
+		return nil, err
+	}
+	return i.backend.CreateUser(ctx, name, email, password)
+}
+
+func (i *interceptedBackend) DeleteUser(ctx context.Context, userID string) error {
+	if err := i.before(ctx, "DeleteUser", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.DeleteUser(ctx, userID)
+}
+
+func (i *interceptedBackend) ChangePassword(ctx context.Context, userID, password string) error {
+	if err := i.before(ctx, "ChangePassword", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.ChangePassword(ctx, userID, password)
+}
+
+func (i *interceptedBackend) Authenticate(ctx context.Context, login, password string) (*Session, error) {
+	if err := i.before(ctx, "Authenticate", OperationClassMutate); err != nil {
+		return nil, err
+	}
+	return i.backend.Authenticate(ctx, login, password)
+}
+
+func (i *interceptedBackend) GetAccountState(ctx context.Context, userID string) (*AccountState, error) {
+	if err := i.before(ctx, "GetAccountState", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetAccountState(ctx, userID)
+}
+
+func (i *interceptedBackend) UnlockAccount(ctx context.Context, userID string) error {
+	if err := i.before(ctx, "UnlockAccount", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.UnlockAccount(ctx, userID)
+}
+
+func (i *interceptedBackend) RequirePasswordReset(ctx context.Context, userID string) error {
+	if err := i.before(ctx, "RequirePasswordReset", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RequirePasswordReset(ctx, userID)
+}
+
+func (i *interceptedBackend) ResetMFA(ctx context.Context, userID string) error {
+	if err := i.before(ctx, "ResetMFA", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.ResetMFA(ctx, userID)
+}
+
+func (i *interceptedBackend) RevokeSessions(ctx context.Context, userID string) (int, error) {
+	if err := i.before(ctx, "RevokeSessions", OperationClassMutate); err != nil {
+		return 0, err
+	}
+	return i.backend.RevokeSessions(ctx, userID)
+}
+
+func (i *interceptedBackend) ListGroups(ctx context.Context, page PageOptions) ([]*Group, string, error) {
+	if err := i.beforePage(ctx, "ListGroups", OperationClassList, page.Token); err != nil {
+		return nil, "", err
+	}
+	return i.backend.ListGroups(ctx, page)
+}
+
+func (i *interceptedBackend) GetGroup(ctx context.Context, groupID string) (*Group, error) {
+	if err := i.before(ctx, "GetGroup", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetGroup(ctx, groupID)
+}
+
+func (i *interceptedBackend) GrantGroupMember(ctx context.Context, groupID, userID string, opts ...GrantOption) error {
+	if err := i.before(ctx, "GrantGroupMember", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.GrantGroupMember(ctx, groupID, userID, opts...)
+}
+
+func (i *interceptedBackend) RevokeGroupMember(ctx context.Context, groupID, userID string) error {
+	if err := i.before(ctx, "RevokeGroupMember", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RevokeGroupMember(ctx, groupID, userID)
+}
+
+func (i *interceptedBackend) GrantGroupMemberGroup(ctx context.Context, groupID, memberGroupID string, opts ...GrantOption) error {
+	if err := i.before(ctx, "GrantGroupMemberGroup", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.GrantGroupMemberGroup(ctx, groupID, memberGroupID, opts...)
+}
+
+func (i *interceptedBackend) RevokeGroupMemberGroup(ctx context.Context, groupID, memberGroupID string) error {
+	if err := i.before(ctx, "RevokeGroupMemberGroup", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RevokeGroupMemberGroup(ctx, groupID, memberGroupID)
+}
+
+func (i *interceptedBackend) GrantGroupAdmin(ctx context.Context, groupID, userID string) error {
+	if err := i.before(ctx, "GrantGroupAdmin", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.GrantGroupAdmin(ctx, groupID, userID)
+}
+
+func (i *interceptedBackend) RevokeGroupAdmin(ctx context.Context, groupID, userID string) error {
+	if err := i.before(ctx, "RevokeGroupAdmin", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RevokeGroupAdmin(ctx, groupID, userID)
+}
+
+func (i *interceptedBackend) ListRoles(ctx context.Context, page PageOptions) ([]*Role, string, error) {
+	if err := i.beforePage(ctx, "ListRoles", OperationClassList, page.Token); err != nil {
+		return nil, "", err
+	}
+	return i.backend.ListRoles(ctx, page)
+}
+
+func (i *interceptedBackend) GetRole(ctx context.Context, roleID string) (*Role, error) {
+	if err := i.before(ctx, "GetRole", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetRole(ctx, roleID)
+}
+
+func (i *interceptedBackend) GrantRole(ctx context.Context, userID, roleID string, opts ...GrantOption) error {
+	if err := i.before(ctx, "GrantRole", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.GrantRole(ctx, userID, roleID, opts...)
+}
+
+func (i *interceptedBackend) RevokeRole(ctx context.Context, userID, roleID string) error {
+	if err := i.before(ctx, "RevokeRole", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RevokeRole(ctx, userID, roleID)
+}
+
+func (i *interceptedBackend) GrantRoleGroup(ctx context.Context, groupID, roleID string, opts ...GrantOption) error {
+	if err := i.before(ctx, "GrantRoleGroup", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.GrantRoleGroup(ctx, groupID, roleID, opts...)
+}
+
+func (i *interceptedBackend) RevokeRoleGroup(ctx context.Context, groupID, roleID string) error {
+	if err := i.before(ctx, "RevokeRoleGroup", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RevokeRoleGroup(ctx, groupID, roleID)
+}
+
+func (i *interceptedBackend) GrantRoleAdmin(ctx context.Context, roleID, userID string) error {
+	if err := i.before(ctx, "GrantRoleAdmin", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.GrantRoleAdmin(ctx, roleID, userID)
+}
+
+func (i *interceptedBackend) RevokeRoleAdmin(ctx context.Context, roleID, userID string) error {
+	if err := i.before(ctx, "RevokeRoleAdmin", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RevokeRoleAdmin(ctx, roleID, userID)
+}
+
+func (i *interceptedBackend) GrantRoleDelegate(ctx context.Context, roleID, userID string) error {
+	if err := i.before(ctx, "GrantRoleDelegate", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.GrantRoleDelegate(ctx, roleID, userID)
+}
+
+func (i *interceptedBackend) RevokeRoleDelegate(ctx context.Context, roleID, userID string) error {
+	if err := i.before(ctx, "RevokeRoleDelegate", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RevokeRoleDelegate(ctx, roleID, userID)
+}
+
+func (i *interceptedBackend) ListPermissions(ctx context.Context, page PageOptions) ([]*Permission, string, error) {
+	if err := i.beforePage(ctx, "ListPermissions", OperationClassList, page.Token); err != nil {
+		return nil, "", err
+	}
+	return i.backend.ListPermissions(ctx, page)
+}
+
+func (i *interceptedBackend) GetPermission(ctx context.Context, permissionID string) (*Permission, error) {
+	if err := i.before(ctx, "GetPermission", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetPermission(ctx, permissionID)
+}
+
+func (i *interceptedBackend) GrantRolePermission(ctx context.Context, roleID, permissionID string) error {
+	if err := i.before(ctx, "GrantRolePermission", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.GrantRolePermission(ctx, roleID, permissionID)
+}
+
+func (i *interceptedBackend) RevokeRolePermission(ctx context.Context, roleID, permissionID string) error {
+	if err := i.before(ctx, "RevokeRolePermission", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RevokeRolePermission(ctx, roleID, permissionID)
+}
+
+func (i *interceptedBackend) ListProjects(ctx context.Context, page PageOptions) ([]*Project, string, error) {
+	if err := i.beforePage(ctx, "ListProjects", OperationClassList, page.Token); err != nil {
+		return nil, "", err
+	}
+	return i.backend.ListProjects(ctx, page)
+}
+
+func (i *interceptedBackend) GetProject(ctx context.Context, projectID string) (*Project, error) {
+	if err := i.before(ctx, "GetProject", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetProject(ctx, projectID)
+}
+
+func (i *interceptedBackend) GrantProjectAccess(ctx context.Context, projectID, groupID string, opts ...GrantOption) error {
+	if err := i.before(ctx, "GrantProjectAccess", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.GrantProjectAccess(ctx, projectID, groupID, opts...)
+}
+
+func (i *interceptedBackend) RevokeProjectAccess(ctx context.Context, projectID, groupID string) error {
+	if err := i.before(ctx, "RevokeProjectAccess", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.RevokeProjectAccess(ctx, projectID, groupID)
+}
+
+func (i *interceptedBackend) TransferProjectOwnership(ctx context.Context, projectID, userID string) error {
+	if err := i.before(ctx, "TransferProjectOwnership", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.TransferProjectOwnership(ctx, projectID, userID)
+}
+
+func (i *interceptedBackend) ListAssignments(ctx context.Context, resourceType, resourceID string) ([]*Assignment, error) {
+	if err := i.before(ctx, "ListAssignments", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.ListAssignments(ctx, resourceType, resourceID)
+}
+
+func (i *interceptedBackend) ListExpiredAssignments(ctx context.Context, at time.Time) ([]*Assignment, error) {
+	if err := i.before(ctx, "ListExpiredAssignments", OperationClassList); err != nil {
+		return nil, err
+	}
+	return i.backend.ListExpiredAssignments(ctx, at)
+}
+
+func (i *interceptedBackend) ListServiceAccounts(ctx context.Context, page PageOptions) ([]*ServiceAccount, string, error) {
+	if err := i.beforePage(ctx, "ListServiceAccounts", OperationClassList, page.Token); err != nil {
+		return nil, "", err
+	}
+	return i.backend.ListServiceAccounts(ctx, page)
+}
+
+func (i *interceptedBackend) GetServiceAccount(ctx context.Context, serviceAccountID string) (*ServiceAccount, error) {
+	if err := i.before(ctx, "GetServiceAccount", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetServiceAccount(ctx, serviceAccountID)
+}
+
+func (i *interceptedBackend) ListAPIKeys(ctx context.Context, serviceAccountID string, page PageOptions) ([]*APIKey, string, error) {
+	if err := i.beforePage(ctx, "ListAPIKeys", OperationClassList, page.Token); err != nil {
+		return nil, "", err
+	}
+	return i.backend.ListAPIKeys(ctx, serviceAccountID, page)
+}
+
+func (i *interceptedBackend) GetAPIKey(ctx context.Context, keyID string) (*APIKey, error) {
+	if err := i.before(ctx, "GetAPIKey", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetAPIKey(ctx, keyID)
+}
+
+func (i *interceptedBackend) RotateAPIKey(ctx context.Context, keyID string) (*APIKey, string, error) {
+	if err := i.before(ctx, "RotateAPIKey", OperationClassMutate); err != nil {
+		return nil, "", err
+	}
+	return i.backend.RotateAPIKey(ctx, keyID)
+}
+
+func (i *interceptedBackend) GetAttributes(ctx context.Context, resourceType, resourceID string) (map[string]string, error) {
+	if err := i.before(ctx, "GetAttributes", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetAttributes(ctx, resourceType, resourceID)
+}
+
+func (i *interceptedBackend) SetAttribute(ctx context.Context, resourceType, resourceID, key, value string) error {
+	if err := i.before(ctx, "SetAttribute", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.SetAttribute(ctx, resourceType, resourceID, key, value)
+}
+
+func (i *interceptedBackend) DeleteAttribute(ctx context.Context, resourceType, resourceID, key string) error {
+	if err := i.before(ctx, "DeleteAttribute", OperationClassMutate); err != nil {
+		return err
+	}
+	return i.backend.DeleteAttribute(ctx, resourceType, resourceID, key)
+}
+
+func (i *interceptedBackend) GetTenant(ctx context.Context) (*Tenant, error) {
+	if err := i.before(ctx, "GetTenant", OperationClassGet); err != nil {
+		return nil, err
+	}
+	return i.backend.GetTenant(ctx)
+}
+
+func (i *interceptedBackend) ListChanges(ctx context.Context, since int64) ([]*Change, int64, error) {
+	if err := i.before(ctx, "ListChanges", OperationClassList); err != nil {
+		return nil, 0, err
+	}
+	return i.backend.ListChanges(ctx, since)
+}
+
+func (i *interceptedBackend) Close() error {
+	return i.backend.Close()
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// ErrMembershipCycle is returned when adding a group to another group would make a group a member of itself.
+var ErrMembershipCycle = errors.New("membership cycle")
+
+// GrantGroupMemberGroup makes a group a member of another group. It fails with ErrMembershipCycle if the group
+// being added already contains the other group, directly or through nested groups.
+func (c *Client) GrantGroupMemberGroup(ctx context.Context, groupID, memberGroupID string, opts ...GrantOption) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetGroup(ctx, memberGroupID)
+		if err != nil {
+			return err
+		}
+
+		cycle, err := c.containsGroup(ctx, memberGroupID, groupID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return fmt.Errorf("group %s cannot be a member of group %s: %w", memberGroupID, groupID, ErrMembershipCycle)
+		}
+
+		return c.addAssignment(ctx, groupMembers, groupID, ResourceTypeGroup, memberGroupID, opts)
+	})
+}
+
+// RevokeGroupMemberGroup removes a group from the members of another group.
+func (c *Client) RevokeGroupMemberGroup(ctx context.Context, groupID, memberGroupID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetGroup(ctx, memberGroupID)
+		if err != nil {
+			return err
+		}
+
+		return c.removeAssignment(ctx, groupMembers, groupID, ResourceTypeGroup, memberGroupID)
+	})
+}
+
+// containsGroup reports whether needle is groupID itself or one of its nested member groups, at any depth.
+func (c *Client) containsGroup(ctx context.Context, groupID, needle string) (bool, error) {
+	seen := map[string]bool{}
+	queue := []string{groupID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if current == needle {
+			return true, nil
+		}
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+
+		q := c.db.From(groupMembers.Name()).Prepared(true)
+		q = q.Select("principal_id")
+		q = q.Where(
+			goqu.C("group_id").Eq(current),
+			goqu.C("principal_type").Eq(ResourceTypeGroup),
+		)
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return false, err
+		}
+
+		rows, err := c.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return false, err
+		}
+
+		for rows.Next() {
+			memberGroupID := ""
+			err = rows.Scan(&memberGroupID)
+			if err != nil {
+				rows.Close()
+				return false, err
+			}
+			queue = append(queue, memberGroupID)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// loadGroupMembers fills in the members and member groups of the given groups from the membership table.
+func (c *Client) loadGroupMembers(ctx context.Context, grps ...*Group) error {
+	byID := make(map[string]*Group, len(grps))
+	ids := make([]interface{}, 0, len(grps))
+	for _, g := range grps {
+		g.Members = []string{}
+		g.MemberGroups = []string{}
+		byID[g.Id] = g
+		ids = append(ids, g.Id)
+	}
+
+	return c.loadAssignments(ctx, groupMembers, ids, func(groupID, principalType, principalID string) error {
+		g := byID[groupID]
+		switch principalType {
+		case ResourceTypeUser:
+			g.Members = append(g.Members, principalID)
+		case ResourceTypeGroup:
+			g.MemberGroups = append(g.MemberGroups, principalID)
+		default:
+			return fmt.Errorf("group %s has a member of unknown type %s", groupID, principalType)
+		}
+		return nil
+	})
+}
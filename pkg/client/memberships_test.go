@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Seeded groups: Engineers contains Platform, which contains SRE. Sales contains no groups.
+const (
+	engineersGroupID = "2IC0WmAPkihbFdZhEPsch5N5WNO"
+	salesGroupID     = "2IC0WjepYDBsRp6b7cqrumGsVGt"
+	platformGroupID  = "3Kscz1E7u7nTtsV6jSrJGmHvJI6"
+	sreGroupID       = "3Kscz2XAPWCGapNN6R8LICQeZ2P"
+)
+
+// eachCyclicBackend runs fn against a freshly seeded instance of every backend, along with a function nesting a group
+// in another without the cycle guard, as data written before the guard existed may be.
+func eachCyclicBackend(t *testing.T, fn func(t *testing.T, b Backend, nest func(groupID, memberGroupID string))) {
+	ctx := context.Background()
+
+	t.Run("sqlite", func(t *testing.T) {
+		c, err := NewClient(filepath.Join(t.TempDir(), "baton-demo.db"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Close() })
+
+		fn(t, c, func(groupID, memberGroupID string) {
+			err := c.addAssignment(ctx, groupMembers, groupID, ResourceTypeGroup, memberGroupID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	})
+	t.Run("memory", func(t *testing.T) {
+		m := NewMemoryBackend(true)
+
+		fn(t, m, func(groupID, memberGroupID string) {
+			g, err := m.group(groupID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g.MemberGroups, _ = addID(g.MemberGroups, memberGroupID)
+			m.assign(ResourceTypeGroup, groupID, ResourceTypeGroup, memberGroupID, newGrantOptions(ctx, nil))
+		})
+	})
+}
+
+func TestGrantGroupMemberGroupRejectsCycles(t *testing.T) {
+	ctx := context.Background()
+
+	eachCyclicBackend(t, func(t *testing.T, b Backend, _ func(string, string)) {
+		for _, tc := range []struct {
+			name                   string
+			groupID, memberGroupID string
+		}{
+			{"itself", engineersGroupID, engineersGroupID},
+			{"child", platformGroupID, engineersGroupID},
+			{"grandchild", sreGroupID, engineersGroupID},
+		} {
+			err := b.GrantGroupMemberGroup(ctx, tc.groupID, tc.memberGroupID)
+			if !errors.Is(err, ErrMembershipCycle) {
+				t.Errorf("nesting a group in its %s: got %v, want %v", tc.name, err, ErrMembershipCycle)
+			}
+
+			g, err := b.GetGroup(ctx, tc.groupID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range g.MemberGroups {
+				if id == tc.memberGroupID {
+					t.Errorf("nesting a group in its %s was refused but stored", tc.name)
+				}
+			}
+		}
+
+		err := b.GrantGroupMemberGroup(ctx, salesGroupID, engineersGroupID)
+		if err != nil {
+			t.Errorf("nesting a group in an unrelated group: %v", err)
+		}
+	})
+}
+
+func TestGrantGroupMemberGroupOnCyclicData(t *testing.T) {
+	ctx := context.Background()
+
+	eachCyclicBackend(t, func(t *testing.T, b Backend, nest func(string, string)) {
+		// Close the loop Engineers > Platform > SRE > Engineers
+		nest(sreGroupID, engineersGroupID)
+
+		done := make(chan error, 1)
+		go func() {
+			// Checking for a cycle walks the groups nested in Engineers, the loop must not make it walk forever
+			err := b.GrantGroupMemberGroup(ctx, salesGroupID, engineersGroupID)
+			if err == nil {
+				err = b.GrantGroupMemberGroup(ctx, platformGroupID, salesGroupID)
+			}
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrMembershipCycle) {
+				t.Errorf("got %v, want %v", err, ErrMembershipCycle)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("checking for a membership cycle did not end on cyclic data")
+		}
+	})
+}
@@ -0,0 +1,1458 @@
+package client
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// MemoryBackend is a Backend that keeps all of its data in process memory.
+// It mirrors the behavior of the SQLite Client, which makes it useful for fast connector tests that
+// should not touch the filesystem. All data is lost when the backend is closed.
+type MemoryBackend struct {
+	mu        sync.RWMutex
+	users     []*User
+	groups    []*Group
+	roles     []*Role
+	projects  []*Project
+	passwords map[string]string
+	revisions map[string]int64
+	changes   []*Change
+	// assignments holds every assignment of a group, role or project, the IDs of the assigned principals are also
+	// stored on the resources themselves.
+	assignments map[assignmentKey]*Assignment
+	// attributes holds the attributes of every resource, by resource type and ID.
+	attributes map[string]map[string]string
+
+	accountStates map[string]*AccountState
+	sessions      []*Session
+	logins        []*loginAttempt
+
+	serviceAccounts []*ServiceAccount
+	apiKeys         []*APIKey
+	apiKeySecrets   map[string]string
+
+	permissions []*Permission
+
+	// createdAt is the creation time of the only tenant a MemoryBackend holds, the default one.
+	createdAt time.Time
+}
+
+type assignmentKey struct {
+	resourceType  string
+	resourceID    string
+	principalType string
+	principalID   string
+}
+
+// loginAttempt is an entry of the logins table.
+type loginAttempt struct {
+	Id            string
+	UserID        string
+	Login         string
+	FailureReason string
+	CreatedAt     time.Time
+}
+
+// NewMemoryBackend returns an empty in-memory backend, seeded with the demo data if initDB is true.
+func NewMemoryBackend(initDB bool) *MemoryBackend {
+	m := &MemoryBackend{
+		passwords:     make(map[string]string),
+		revisions:     make(map[string]int64),
+		assignments:   make(map[assignmentKey]*Assignment),
+		attributes:    make(map[string]map[string]string),
+		accountStates: make(map[string]*AccountState),
+		apiKeySecrets: make(map[string]string),
+		createdAt:     time.Now().UTC(),
+	}
+
+	if initDB {
+		seedData := generateDB()
+		seeded := newGrantOptions(context.Background(), []GrantOption{WithSource(SourceSeed)})
+		for _, u := range seedData.Users {
+			m.users = append(m.users, u.clone())
+			m.recordChange(ResourceTypeUser, u.Id, ChangeInsert)
+		}
+		for _, g := range seedData.Groups {
+			m.groups = append(m.groups, g.clone())
+			m.recordChange(ResourceTypeGroup, g.Id, ChangeInsert)
+			for _, userID := range g.Members {
+				m.assign(ResourceTypeGroup, g.Id, ResourceTypeUser, userID, seeded)
+			}
+			for _, memberGroupID := range g.MemberGroups {
+				m.assign(ResourceTypeGroup, g.Id, ResourceTypeGroup, memberGroupID, seeded)
+			}
+		}
+		for _, r := range seedData.Roles {
+			m.roles = append(m.roles, r.clone())
+			m.recordChange(ResourceTypeRole, r.Id, ChangeInsert)
+			for _, userID := range r.DirectAssignments {
+				m.assign(ResourceTypeRole, r.Id, ResourceTypeUser, userID, seeded)
+			}
+			for _, groupID := range r.GroupAssignments {
+				m.assign(ResourceTypeRole, r.Id, ResourceTypeGroup, groupID, seeded)
+			}
+		}
+		for _, p := range seedData.Permissions {
+			m.permissions = append(m.permissions, p.clone())
+			m.recordChange(ResourceTypePermission, p.Id, ChangeInsert)
+		}
+		for _, r := range m.roles {
+			slices.Sort(r.Permissions)
+			for _, permissionID := range r.Permissions {
+				if p, err := m.permission(permissionID); err == nil {
+					p.Roles, _ = addID(p.Roles, r.Id)
+				}
+			}
+		}
+		for _, p := range m.permissions {
+			slices.Sort(p.Roles)
+		}
+		for _, p := range seedData.Projects {
+			m.projects = append(m.projects, p.clone())
+			m.recordChange(ResourceTypeProject, p.Id, ChangeInsert)
+			for _, groupID := range p.GroupAssignments {
+				m.assign(ResourceTypeProject, p.Id, ResourceTypeGroup, groupID, seeded)
+			}
+		}
+		for userID, password := range seedData.Passwords {
+			m.passwords[userID] = password
+		}
+		for _, state := range seedData.AccountStates {
+			m.accountStates[state.UserID] = state.clone()
+		}
+		for _, session := range seedData.Sessions {
+			m.sessions = append(m.sessions, session.clone())
+		}
+		for _, sa := range seedData.ServiceAccounts {
+			m.serviceAccounts = append(m.serviceAccounts, sa.clone())
+			m.recordChange(ResourceTypeServiceAccount, sa.Id, ChangeInsert)
+		}
+		for _, key := range seedData.APIKeys {
+			m.apiKeys = append(m.apiKeys, key.clone())
+			m.recordChange(ResourceTypeAPIKey, key.Id, ChangeInsert)
+		}
+		for _, attr := range seedData.Attributes {
+			attrs, ok := m.attributes[attr.ResourceType+":"+attr.ResourceID]
+			if !ok {
+				attrs = make(map[string]string)
+				m.attributes[attr.ResourceType+":"+attr.ResourceID] = attrs
+			}
+			attrs[attr.Key] = attr.Value
+		}
+	}
+
+	return m
+}
+
+func (m *MemoryBackend) Close() error {
+	return nil
+}
+
+// ListUsers returns a page of users, ordered by ID.
+func (m *MemoryBackend) ListUsers(ctx context.Context, page PageOptions) ([]*User, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ret := make([]*User, 0, len(m.users))
+	for _, u := range m.users {
+		ret = append(ret, u.clone())
+	}
+	slices.SortFunc(ret, func(a, b *User) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return paginateSlice(ret, page)
+}
+
+// GetUser returns the user requested if it exists, else returns an error.
+func (m *MemoryBackend) GetUser(ctx context.Context, userID string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, err := m.user(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.clone(), nil
+}
+
+func (m *MemoryBackend) CreateUser(ctx context.Context, name, email, password string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Name == name {
+			return nil, fmt.Errorf("user with name %s already exists", name)
+		}
+	}
+
+	user := &User{
+		Id:       ksuid.New().String(),
+		Name:     name,
+		Email:    email,
+		Revision: m.bumpRevision(users.Name()),
+	}
+	m.users = append(m.users, user)
+	m.recordChange(ResourceTypeUser, user.Id, ChangeInsert)
+	m.passwords[user.Id] = password
+	m.bumpRevision(passwords.Name())
+
+	return user.clone(), nil
+}
+
+func (m *MemoryBackend) DeleteUser(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.user(userID); err != nil {
+		return err
+	}
+
+	// Remove every reference to the user first, the resources that referred to the user change along with it
+	for _, g := range m.groups {
+		var member, admin bool
+		g.Members, member = removeID(g.Members, userID)
+		g.Admins, admin = removeID(g.Admins, userID)
+		if m.unassign(ResourceTypeGroup, g.Id, ResourceTypeUser, userID) || member || admin {
+			g.Revision = m.bumpRevision(groups.Name())
+			m.recordChange(ResourceTypeGroup, g.Id, ChangeUpdate)
+		}
+	}
+	for _, r := range m.roles {
+		var assigned, admin, delegate bool
+		r.DirectAssignments, assigned = removeID(r.DirectAssignments, userID)
+		r.Admins, admin = removeID(r.Admins, userID)
+		r.Delegates, delegate = removeID(r.Delegates, userID)
+		if m.unassign(ResourceTypeRole, r.Id, ResourceTypeUser, userID) || assigned || admin || delegate {
+			r.Revision = m.bumpRevision(roles.Name())
+			m.recordChange(ResourceTypeRole, r.Id, ChangeUpdate)
+		}
+	}
+	for _, p := range m.projects {
+		if p.Owner == userID {
+			p.Owner = ""
+			p.Revision = m.bumpRevision(projects.Name())
+			m.recordChange(ResourceTypeProject, p.Id, ChangeUpdate)
+		}
+	}
+	for _, sa := range m.serviceAccounts {
+		if sa.Owner == userID {
+			sa.Owner = ""
+			sa.Revision = m.bumpRevision(serviceAccounts.Name())
+			m.recordChange(ResourceTypeServiceAccount, sa.Id, ChangeUpdate)
+		}
+	}
+
+	delete(m.passwords, userID)
+	m.bumpRevision(passwords.Name())
+	delete(m.accountStates, userID)
+	m.bumpRevision(accountStates.Name())
+	m.sessions = slices.DeleteFunc(m.sessions, func(s *Session) bool {
+		return s.UserID == userID
+	})
+	m.bumpRevision(sessions.Name())
+
+	m.users = slices.DeleteFunc(m.users, func(u *User) bool {
+		return u.Id == userID
+	})
+	m.bumpRevision(users.Name())
+	m.recordChange(ResourceTypeUser, userID, ChangeDelete)
+	delete(m.attributes, ResourceTypeUser+":"+userID)
+
+	return nil
+}
+
+func (m *MemoryBackend) ChangePassword(ctx context.Context, userID, password string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.user(userID); err != nil {
+		return err
+	}
+
+	m.passwords[userID] = password
+	m.bumpRevision(passwords.Name())
+	// A new password satisfies a forced password reset
+	if state, ok := m.accountStates[userID]; ok {
+		state.PasswordResetRequired = false
+	}
+	m.recordChange(ResourceTypeUser, userID, ChangeUpdate)
+
+	return nil
+}
+
+// GetAccountState returns the security state of the user's account.
+func (m *MemoryBackend) GetAccountState(ctx context.Context, userID string) (*AccountState, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, err := m.user(userID); err != nil {
+		return nil, err
+	}
+
+	state := &AccountState{
+		UserID: userID,
+	}
+	if s, ok := m.accountStates[userID]; ok {
+		state = s.clone()
+	}
+
+	at := now(ctx)
+	state.ActiveSessions = 0
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.active(at) {
+			state.ActiveSessions++
+		}
+	}
+
+	return state, nil
+}
+
+// Authenticate checks the password of the user whose name or email is login and starts a new session for them.
+// Every attempt is recorded. Failed attempts count towards a lockout, successful ones reset the count and update the
+// user's last login. Users required to reset their password get no session until they have changed it.
+func (m *MemoryBackend) Authenticate(ctx context.Context, login, password string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at := now(ctx)
+
+	var user *User
+	for _, u := range m.users {
+		if u.Name == login || u.Email == login {
+			user = u
+			break
+		}
+	}
+	storedPassword, ok := "", false
+	if user != nil {
+		storedPassword, ok = m.passwords[user.Id]
+	}
+	if !ok {
+		m.recordLogin(at, "", login, loginFailureInvalidCredentials)
+		return nil, ErrInvalidCredentials
+	}
+
+	state, ok := m.accountStates[user.Id]
+	if !ok {
+		state = &AccountState{
+			UserID: user.Id,
+		}
+		m.accountStates[user.Id] = state
+	}
+
+	if state.Locked() {
+		m.recordLogin(at, user.Id, login, loginFailureLocked)
+		return nil, fmt.Errorf("user %s: %w", user.Id, ErrAccountLocked)
+	}
+
+	m.bumpRevision(accountStates.Name())
+	m.recordChange(ResourceTypeUser, user.Id, ChangeUpdate)
+
+	// Users created without a password cannot log in at all, not even with an empty password
+	if storedPassword == "" || subtle.ConstantTimeCompare([]byte(storedPassword), []byte(password)) != 1 {
+		state.FailedLogins++
+		if state.FailedLogins >= maxFailedLogins {
+			state.LockedAt = &at
+		}
+		m.recordLogin(at, user.Id, login, loginFailureInvalidCredentials)
+		return nil, ErrInvalidCredentials
+	}
+
+	if state.PasswordResetRequired {
+		m.recordLogin(at, user.Id, login, loginFailurePasswordReset)
+		return nil, fmt.Errorf("user %s: %w", user.Id, ErrPasswordResetRequired)
+	}
+
+	state.FailedLogins = 0
+	state.LastLoginAt = &at
+
+	session := &Session{
+		Id:        ksuid.New().String(),
+		UserID:    user.Id,
+		CreatedAt: at,
+		ExpiresAt: at.Add(sessionLifetime),
+	}
+	m.sessions = append(m.sessions, session)
+	m.bumpRevision(sessions.Name())
+	m.recordLogin(at, user.Id, login, "")
+
+	return session.clone(), nil
+}
+
+// UnlockAccount lifts a lockout and resets the failed login counter of the user's account.
+func (m *MemoryBackend) UnlockAccount(ctx context.Context, userID string) error {
+	return m.updateAccountState(userID, func(state *AccountState) {
+		state.FailedLogins = 0
+		state.LockedAt = nil
+	})
+}
+
+// RequirePasswordReset forces the user to choose a new password the next time they log in.
+func (m *MemoryBackend) RequirePasswordReset(ctx context.Context, userID string) error {
+	return m.updateAccountState(userID, func(state *AccountState) {
+		state.PasswordResetRequired = true
+	})
+}
+
+// ResetMFA removes the user's MFA enrollment, they will have to enroll again the next time they log in.
+func (m *MemoryBackend) ResetMFA(ctx context.Context, userID string) error {
+	return m.updateAccountState(userID, func(state *AccountState) {
+		state.MFAEnrolled = false
+	})
+}
+
+// RevokeSessions revokes every active session of the user and returns how many were revoked.
+func (m *MemoryBackend) RevokeSessions(ctx context.Context, userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.user(userID); err != nil {
+		return 0, err
+	}
+
+	m.bumpRevision(sessions.Name())
+
+	now := time.Now().UTC()
+	revoked := 0
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.active(now) {
+			session.RevokedAt = &now
+			revoked++
+		}
+	}
+	m.recordChange(ResourceTypeUser, userID, ChangeUpdate)
+
+	return revoked, nil
+}
+
+// ListGroups returns a page of groups, ordered by ID.
+func (m *MemoryBackend) ListGroups(ctx context.Context, page PageOptions) ([]*Group, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ret := make([]*Group, 0, len(m.groups))
+	for _, g := range m.groups {
+		ret = append(ret, g.clone())
+	}
+	slices.SortFunc(ret, func(a, b *Group) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return paginateSlice(ret, page)
+}
+
+// GetGroup returns the group requested if it exists, else returns an error.
+func (m *MemoryBackend) GetGroup(ctx context.Context, groupID string) (*Group, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	g, err := m.group(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.clone(), nil
+}
+
+func (m *MemoryBackend) GrantGroupMember(ctx context.Context, groupID, userID string, opts ...GrantOption) error {
+	return m.updateGroup(groupID, userID, func(g *Group) bool {
+		g.Members, _ = addID(g.Members, userID)
+		return m.assign(ResourceTypeGroup, groupID, ResourceTypeUser, userID, newGrantOptions(ctx, opts))
+	})
+}
+
+func (m *MemoryBackend) RevokeGroupMember(ctx context.Context, groupID, userID string) error {
+	return m.updateGroup(groupID, userID, func(g *Group) bool {
+		g.Members, _ = removeID(g.Members, userID)
+		return m.unassign(ResourceTypeGroup, groupID, ResourceTypeUser, userID)
+	})
+}
+
+// GrantGroupMemberGroup makes a group a member of another group. It fails with ErrMembershipCycle if the group
+// being added already contains the other group, directly or through nested groups.
+func (m *MemoryBackend) GrantGroupMemberGroup(ctx context.Context, groupID, memberGroupID string, opts ...GrantOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, err := m.group(groupID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.group(memberGroupID); err != nil {
+		return err
+	}
+
+	if m.containsGroup(memberGroupID, groupID) {
+		return fmt.Errorf("group %s cannot be a member of group %s: %w", memberGroupID, groupID, ErrMembershipCycle)
+	}
+
+	g.MemberGroups, _ = addID(g.MemberGroups, memberGroupID)
+	if m.assign(ResourceTypeGroup, groupID, ResourceTypeGroup, memberGroupID, newGrantOptions(ctx, opts)) {
+		g.Revision = m.bumpRevision(groups.Name())
+		m.recordChange(ResourceTypeGroup, groupID, ChangeUpdate)
+	}
+
+	return nil
+}
+
+// RevokeGroupMemberGroup removes a group from the members of another group.
+func (m *MemoryBackend) RevokeGroupMemberGroup(ctx context.Context, groupID, memberGroupID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, err := m.group(groupID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.group(memberGroupID); err != nil {
+		return err
+	}
+
+	g.MemberGroups, _ = removeID(g.MemberGroups, memberGroupID)
+	if m.unassign(ResourceTypeGroup, groupID, ResourceTypeGroup, memberGroupID) {
+		g.Revision = m.bumpRevision(groups.Name())
+		m.recordChange(ResourceTypeGroup, groupID, ChangeUpdate)
+	}
+
+	return nil
+}
+
+func (m *MemoryBackend) GrantGroupAdmin(ctx context.Context, groupID, userID string) error {
+	return m.updateGroup(groupID, userID, func(g *Group) bool {
+		var changed bool
+		g.Admins, changed = addID(g.Admins, userID)
+		return changed
+	})
+}
+
+func (m *MemoryBackend) RevokeGroupAdmin(ctx context.Context, groupID, userID string) error {
+	return m.updateGroup(groupID, userID, func(g *Group) bool {
+		var changed bool
+		g.Admins, changed = removeID(g.Admins, userID)
+		return changed
+	})
+}
+
+// ListRoles returns a page of roles, ordered by ID.
+func (m *MemoryBackend) ListRoles(ctx context.Context, page PageOptions) ([]*Role, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ret := make([]*Role, 0, len(m.roles))
+	for _, r := range m.roles {
+		ret = append(ret, r.clone())
+	}
+	slices.SortFunc(ret, func(a, b *Role) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return paginateSlice(ret, page)
+}
+
+// GetRole returns the role requested if it exists, else returns an error.
+func (m *MemoryBackend) GetRole(ctx context.Context, roleID string) (*Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	r, err := m.role(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.clone(), nil
+}
+
+func (m *MemoryBackend) GrantRole(ctx context.Context, userID, roleID string, opts ...GrantOption) error {
+	return m.updateRole(roleID, userID, func(r *Role) bool {
+		r.DirectAssignments, _ = addID(r.DirectAssignments, userID)
+		return m.assign(ResourceTypeRole, roleID, ResourceTypeUser, userID, newGrantOptions(ctx, opts))
+	})
+}
+
+func (m *MemoryBackend) RevokeRole(ctx context.Context, userID, roleID string) error {
+	return m.updateRole(roleID, userID, func(r *Role) bool {
+		r.DirectAssignments, _ = removeID(r.DirectAssignments, userID)
+		return m.unassign(ResourceTypeRole, roleID, ResourceTypeUser, userID)
+	})
+}
+
+// GrantRoleGroup assigns a role to a group, and through it to the members of the group.
+func (m *MemoryBackend) GrantRoleGroup(ctx context.Context, groupID, roleID string, opts ...GrantOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.group(groupID); err != nil {
+		return err
+	}
+
+	r, err := m.role(roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkMutable(); err != nil {
+		return err
+	}
+
+	r.GroupAssignments, _ = addID(r.GroupAssignments, groupID)
+	if m.assign(ResourceTypeRole, roleID, ResourceTypeGroup, groupID, newGrantOptions(ctx, opts)) {
+		r.Revision = m.bumpRevision(roles.Name())
+		m.recordChange(ResourceTypeRole, roleID, ChangeUpdate)
+	}
+
+	return nil
+}
+
+// RevokeRoleGroup removes the assignment of a role to a group.
+func (m *MemoryBackend) RevokeRoleGroup(ctx context.Context, groupID, roleID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.group(groupID); err != nil {
+		return err
+	}
+
+	r, err := m.role(roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkMutable(); err != nil {
+		return err
+	}
+
+	r.GroupAssignments, _ = removeID(r.GroupAssignments, groupID)
+	if m.unassign(ResourceTypeRole, roleID, ResourceTypeGroup, groupID) {
+		r.Revision = m.bumpRevision(roles.Name())
+		m.recordChange(ResourceTypeRole, roleID, ChangeUpdate)
+	}
+
+	return nil
+}
+
+// GrantRoleAdmin makes a user an admin of a role.
+func (m *MemoryBackend) GrantRoleAdmin(ctx context.Context, roleID, userID string) error {
+	return m.updateRole(roleID, userID, func(r *Role) bool {
+		var changed bool
+		r.Admins, changed = addID(r.Admins, userID)
+		return changed
+	})
+}
+
+// RevokeRoleAdmin removes a user from the admins of a role.
+func (m *MemoryBackend) RevokeRoleAdmin(ctx context.Context, roleID, userID string) error {
+	return m.updateRole(roleID, userID, func(r *Role) bool {
+		var changed bool
+		r.Admins, changed = removeID(r.Admins, userID)
+		return changed
+	})
+}
+
+// GrantRoleDelegate makes a user a delegate of a role.
+func (m *MemoryBackend) GrantRoleDelegate(ctx context.Context, roleID, userID string) error {
+	return m.updateRole(roleID, userID, func(r *Role) bool {
+		var changed bool
+		r.Delegates, changed = addID(r.Delegates, userID)
+		return changed
+	})
+}
+
+// RevokeRoleDelegate removes a user from the delegates of a role.
+func (m *MemoryBackend) RevokeRoleDelegate(ctx context.Context, roleID, userID string) error {
+	return m.updateRole(roleID, userID, func(r *Role) bool {
+		var changed bool
+		r.Delegates, changed = removeID(r.Delegates, userID)
+		return changed
+	})
+}
+
+// ListPermissions returns a page of permissions, ordered by ID.
+func (m *MemoryBackend) ListPermissions(ctx context.Context, page PageOptions) ([]*Permission, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ret := make([]*Permission, 0, len(m.permissions))
+	for _, p := range m.permissions {
+		ret = append(ret, p.clone())
+	}
+	slices.SortFunc(ret, func(a, b *Permission) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return paginateSlice(ret, page)
+}
+
+// GetPermission returns the permission requested if it exists, else returns an error.
+func (m *MemoryBackend) GetPermission(ctx context.Context, permissionID string) (*Permission, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, err := m.permission(permissionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.clone(), nil
+}
+
+// GrantRolePermission grants a permission to a role.
+func (m *MemoryBackend) GrantRolePermission(ctx context.Context, roleID, permissionID string) error {
+	return m.updateRolePermission(roleID, permissionID, addID)
+}
+
+// RevokeRolePermission takes a permission away from a role.
+func (m *MemoryBackend) RevokeRolePermission(ctx context.Context, roleID, permissionID string) error {
+	return m.updateRolePermission(roleID, permissionID, removeID)
+}
+
+// updateRolePermission applies update to the permissions of a role and the roles of a permission, and stamps both
+// with a new revision if they changed.
+func (m *MemoryBackend) updateRolePermission(roleID, permissionID string, update func([]string, string) ([]string, bool)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, err := m.role(roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkMutable(); err != nil {
+		return err
+	}
+
+	p, err := m.permission(permissionID)
+	if err != nil {
+		return err
+	}
+
+	var changed bool
+	r.Permissions, changed = update(r.Permissions, permissionID)
+	if !changed {
+		return nil
+	}
+	p.Roles, _ = update(p.Roles, roleID)
+	slices.Sort(r.Permissions)
+	slices.Sort(p.Roles)
+
+	r.Revision = m.bumpRevision(roles.Name())
+	m.recordChange(ResourceTypeRole, roleID, ChangeUpdate)
+	p.Revision = m.bumpRevision(permissions.Name())
+	m.recordChange(ResourceTypePermission, permissionID, ChangeUpdate)
+
+	return nil
+}
+
+// ListProjects returns a page of projects, ordered by ID.
+func (m *MemoryBackend) ListProjects(ctx context.Context, page PageOptions) ([]*Project, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ret := make([]*Project, 0, len(m.projects))
+	for _, p := range m.projects {
+		ret = append(ret, p.clone())
+	}
+	slices.SortFunc(ret, func(a, b *Project) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return paginateSlice(ret, page)
+}
+
+// GetProject returns the project requested if it exists, else returns an error.
+func (m *MemoryBackend) GetProject(ctx context.Context, projectID string) (*Project, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, err := m.project(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.clone(), nil
+}
+
+// GrantProjectAccess gives a group, and through it the members of the group, access to a project.
+func (m *MemoryBackend) GrantProjectAccess(ctx context.Context, projectID, groupID string, opts ...GrantOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, err := m.project(projectID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.group(groupID); err != nil {
+		return err
+	}
+
+	p.GroupAssignments, _ = addID(p.GroupAssignments, groupID)
+	if m.assign(ResourceTypeProject, projectID, ResourceTypeGroup, groupID, newGrantOptions(ctx, opts)) {
+		p.Revision = m.bumpRevision(projects.Name())
+		m.recordChange(ResourceTypeProject, projectID, ChangeUpdate)
+	}
+
+	return nil
+}
+
+// RevokeProjectAccess removes the access of a group to a project.
+func (m *MemoryBackend) RevokeProjectAccess(ctx context.Context, projectID, groupID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, err := m.project(projectID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.group(groupID); err != nil {
+		return err
+	}
+
+	p.GroupAssignments, _ = removeID(p.GroupAssignments, groupID)
+	if m.unassign(ResourceTypeProject, projectID, ResourceTypeGroup, groupID) {
+		p.Revision = m.bumpRevision(projects.Name())
+		m.recordChange(ResourceTypeProject, projectID, ChangeUpdate)
+	}
+
+	return nil
+}
+
+// TransferProjectOwnership makes a user the owner of a project in place of its current owner.
+func (m *MemoryBackend) TransferProjectOwnership(ctx context.Context, projectID, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, err := m.project(projectID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.user(userID); err != nil {
+		return err
+	}
+
+	if p.Owner == userID {
+		return nil
+	}
+
+	p.Owner = userID
+	p.Revision = m.bumpRevision(projects.Name())
+	m.recordChange(ResourceTypeProject, projectID, ChangeUpdate)
+
+	return nil
+}
+
+// ListAssignments returns every assignment of a resource, ordered by principal.
+func (m *MemoryBackend) ListAssignments(ctx context.Context, resourceType, resourceID string) ([]*Assignment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, err := assignmentTableFor(resourceType); err != nil {
+		return nil, err
+	}
+
+	ret := []*Assignment{}
+	for _, a := range m.assignments {
+		if a.ResourceType == resourceType && a.ResourceID == resourceID {
+			ret = append(ret, a.clone())
+		}
+	}
+	sortAssignments(ret)
+
+	return ret, nil
+}
+
+// ListExpiredAssignments returns every assignment that expired at or before at, ordered by resource and principal.
+func (m *MemoryBackend) ListExpiredAssignments(ctx context.Context, at time.Time) ([]*Assignment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ret := []*Assignment{}
+	for _, a := range m.assignments {
+		if a.Expired(at) {
+			ret = append(ret, a.clone())
+		}
+	}
+	sortAssignments(ret)
+
+	return ret, nil
+}
+
+// ListServiceAccounts returns a page of service accounts, ordered by ID.
+func (m *MemoryBackend) ListServiceAccounts(ctx context.Context, page PageOptions) ([]*ServiceAccount, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ret := make([]*ServiceAccount, 0, len(m.serviceAccounts))
+	for _, sa := range m.serviceAccounts {
+		ret = append(ret, sa.clone())
+	}
+	slices.SortFunc(ret, func(a, b *ServiceAccount) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return paginateSlice(ret, page)
+}
+
+// GetServiceAccount returns the service account requested if it exists, else returns an error.
+func (m *MemoryBackend) GetServiceAccount(ctx context.Context, serviceAccountID string) (*ServiceAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sa, err := m.serviceAccount(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return sa.clone(), nil
+}
+
+// ListAPIKeys returns a page of the API keys of a service account, ordered by ID.
+func (m *MemoryBackend) ListAPIKeys(ctx context.Context, serviceAccountID string, page PageOptions) ([]*APIKey, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ret := []*APIKey{}
+	for _, key := range m.apiKeys {
+		if key.ServiceAccountID == serviceAccountID {
+			ret = append(ret, key.clone())
+		}
+	}
+	slices.SortFunc(ret, func(a, b *APIKey) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return paginateSlice(ret, page)
+}
+
+// GetAPIKey returns the API key requested if it exists, else returns an error.
+func (m *MemoryBackend) GetAPIKey(ctx context.Context, keyID string) (*APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, err := m.apiKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.clone(), nil
+}
+
+// RotateAPIKey replaces the secret of an API key, which restarts its lifetime. It returns the updated key along with
+// the new secret, which is not stored and cannot be retrieved again.
+func (m *MemoryBackend) RotateAPIKey(ctx context.Context, keyID string) (*APIKey, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.apiKey(keyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, secretHash, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	at := now(ctx)
+	expiresAt := at.Add(apiKeyLifetime)
+	key.CreatedAt = at
+	key.ExpiresAt = &expiresAt
+	key.LastUsedAt = nil
+	key.Revision = m.bumpRevision(apiKeys.Name())
+	m.apiKeySecrets[keyID] = secretHash
+	m.recordChange(ResourceTypeAPIKey, keyID, ChangeUpdate)
+
+	return key.clone(), secret, nil
+}
+
+// GetAttributes returns the attributes of a resource by key. A resource without attributes has an empty map.
+func (m *MemoryBackend) GetAttributes(ctx context.Context, resourceType, resourceID string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, err := resourceTableFor(resourceType); err != nil {
+		return nil, err
+	}
+
+	attrs := make(map[string]string)
+	maps.Copy(attrs, m.attributes[resourceType+":"+resourceID])
+
+	return attrs, nil
+}
+
+// SetAttribute sets an attribute of a resource, replacing its previous value.
+func (m *MemoryBackend) SetAttribute(ctx context.Context, resourceType, resourceID, key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.touchResource(resourceType, resourceID)
+	if err != nil {
+		return err
+	}
+
+	attrs, ok := m.attributes[resourceType+":"+resourceID]
+	if !ok {
+		attrs = make(map[string]string)
+		m.attributes[resourceType+":"+resourceID] = attrs
+	}
+	attrs[key] = value
+
+	return nil
+}
+
+// DeleteAttribute removes an attribute from a resource. Removing an attribute the resource does not have is not an
+// error.
+func (m *MemoryBackend) DeleteAttribute(ctx context.Context, resourceType, resourceID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.touchResource(resourceType, resourceID)
+	if err != nil {
+		return err
+	}
+
+	delete(m.attributes[resourceType+":"+resourceID], key)
+
+	return nil
+}
+
+// GetTenant returns the default tenant, a MemoryBackend holds no other.
+func (m *MemoryBackend) GetTenant(ctx context.Context) (*Tenant, error) {
+	return &Tenant{
+		Id:        DefaultTenant,
+		CreatedAt: m.createdAt,
+	}, nil
+}
+
+// ListChanges returns every change recorded after the cursor since, oldest first, along with the cursor to use to
+// list the changes that happen next.
+func (m *MemoryBackend) ListChanges(ctx context.Context, since int64) ([]*Change, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor := since
+	ret := []*Change{}
+	for _, change := range m.changes {
+		if change.Seq <= since {
+			continue
+		}
+		c := *change
+		ret = append(ret, &c)
+		cursor = change.Seq
+	}
+
+	return ret, cursor, nil
+}
+
+// updateAccountState applies fn to the user's account state, creating the state if the user has none yet.
+func (m *MemoryBackend) updateAccountState(userID string, fn func(state *AccountState)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.user(userID); err != nil {
+		return err
+	}
+
+	state, ok := m.accountStates[userID]
+	if !ok {
+		state = &AccountState{
+			UserID: userID,
+		}
+		m.accountStates[userID] = state
+	}
+
+	m.bumpRevision(accountStates.Name())
+	fn(state)
+	m.recordChange(ResourceTypeUser, userID, ChangeUpdate)
+
+	return nil
+}
+
+// updateGroup applies fn to the stored group after checking that both the group and the user exist.
+// fn reports whether it changed the group, in which case the group is stamped with a new revision.
+func (m *MemoryBackend) updateGroup(groupID, userID string, fn func(g *Group) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, err := m.group(groupID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.user(userID); err != nil {
+		return err
+	}
+
+	if fn(g) {
+		g.Revision = m.bumpRevision(groups.Name())
+		m.recordChange(ResourceTypeGroup, groupID, ChangeUpdate)
+	}
+
+	return nil
+}
+
+// updateRole applies fn to the stored role after checking that both the role and the user exist.
+// fn reports whether it changed the role, in which case the role is stamped with a new revision.
+func (m *MemoryBackend) updateRole(roleID, userID string, fn func(r *Role) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.user(userID); err != nil {
+		return err
+	}
+
+	r, err := m.role(roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkMutable(); err != nil {
+		return err
+	}
+
+	if fn(r) {
+		r.Revision = m.bumpRevision(roles.Name())
+		m.recordChange(ResourceTypeRole, roleID, ChangeUpdate)
+	}
+
+	return nil
+}
+
+// assign records the assignment of a resource to a principal, and reports whether the principal was not assigned yet
+// or the expiry of the assignment changed.
+func (m *MemoryBackend) assign(resourceType, resourceID, principalType, principalID string, o *grantOptions) bool {
+	key := assignmentKey{
+		resourceType:  resourceType,
+		resourceID:    resourceID,
+		principalType: principalType,
+		principalID:   principalID,
+	}
+
+	a, ok := m.assignments[key]
+	if ok && equalTimes(a.ExpiresAt, o.expiresAt) {
+		return false
+	}
+
+	m.assignments[key] = &Assignment{
+		ResourceType:  resourceType,
+		ResourceID:    resourceID,
+		PrincipalType: principalType,
+		PrincipalID:   principalID,
+		ExpiresAt:     o.expiresAt,
+		GrantedBy:     o.grantedBy,
+		GrantedAt:     o.grantedAt,
+		Reason:        o.reason,
+		Source:        o.source,
+	}
+
+	return true
+}
+
+// unassign removes the assignment of a resource to a principal, and reports whether the principal was assigned.
+func (m *MemoryBackend) unassign(resourceType, resourceID, principalType, principalID string) bool {
+	key := assignmentKey{
+		resourceType:  resourceType,
+		resourceID:    resourceID,
+		principalType: principalType,
+		principalID:   principalID,
+	}
+
+	if _, ok := m.assignments[key]; !ok {
+		return false
+	}
+
+	delete(m.assignments, key)
+	return true
+}
+
+// touchResource stamps a resource with a new revision and records the change, because one of its attributes is about
+// to change. It fails with ErrNotFound if the resource does not exist.
+func (m *MemoryBackend) touchResource(resourceType, resourceID string) error {
+	t, err := resourceTableFor(resourceType)
+	if err != nil {
+		return err
+	}
+
+	var revision *int64
+	switch resourceType {
+	case ResourceTypeUser:
+		u, err := m.user(resourceID)
+		if err != nil {
+			return err
+		}
+		revision = &u.Revision
+	case ResourceTypeGroup:
+		g, err := m.group(resourceID)
+		if err != nil {
+			return err
+		}
+		revision = &g.Revision
+	case ResourceTypeRole:
+		r, err := m.role(resourceID)
+		if err != nil {
+			return err
+		}
+		revision = &r.Revision
+	case ResourceTypeProject:
+		p, err := m.project(resourceID)
+		if err != nil {
+			return err
+		}
+		revision = &p.Revision
+	case ResourceTypeServiceAccount:
+		sa, err := m.serviceAccount(resourceID)
+		if err != nil {
+			return err
+		}
+		revision = &sa.Revision
+	case ResourceTypeAPIKey:
+		key, err := m.apiKey(resourceID)
+		if err != nil {
+			return err
+		}
+		revision = &key.Revision
+	}
+
+	*revision = m.bumpRevision(t.Name())
+	m.recordChange(resourceType, resourceID, ChangeUpdate)
+
+	return nil
+}
+
+func (m *MemoryBackend) serviceAccount(serviceAccountID string) (*ServiceAccount, error) {
+	for _, sa := range m.serviceAccounts {
+		if sa.Id == serviceAccountID {
+			return sa, nil
+		}
+	}
+	return nil, fmt.Errorf("service account %s: %w", serviceAccountID, ErrNotFound)
+}
+
+func (m *MemoryBackend) apiKey(keyID string) (*APIKey, error) {
+	for _, key := range m.apiKeys {
+		if key.Id == keyID {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("api key %s: %w", keyID, ErrNotFound)
+}
+
+// containsGroup reports whether needle is groupID itself or one of its nested member groups, at any depth.
+func (m *MemoryBackend) containsGroup(groupID, needle string) bool {
+	seen := map[string]bool{}
+	queue := []string{groupID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if current == needle {
+			return true
+		}
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+
+		if g, err := m.group(current); err == nil {
+			queue = append(queue, g.MemberGroups...)
+		}
+	}
+
+	return false
+}
+
+func (m *MemoryBackend) user(userID string) (*User, error) {
+	for _, u := range m.users {
+		if u.Id == userID {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("user %s: %w", userID, ErrNotFound)
+}
+
+func (m *MemoryBackend) group(groupID string) (*Group, error) {
+	for _, g := range m.groups {
+		if g.Id == groupID {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("group %s: %w", groupID, ErrNotFound)
+}
+
+func (m *MemoryBackend) project(projectID string) (*Project, error) {
+	for _, p := range m.projects {
+		if p.Id == projectID {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("project %s: %w", projectID, ErrNotFound)
+}
+
+func (m *MemoryBackend) role(roleID string) (*Role, error) {
+	for _, r := range m.roles {
+		if r.Id == roleID {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("role %s: %w", roleID, ErrNotFound)
+}
+
+func (m *MemoryBackend) permission(permissionID string) (*Permission, error) {
+	for _, p := range m.permissions {
+		if p.Id == permissionID {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("permission %s: %w", permissionID, ErrNotFound)
+}
+
+// recordLogin appends a login attempt to the login log. A failure reason of "" records a successful login.
+func (m *MemoryBackend) recordLogin(at time.Time, userID, login, failureReason string) {
+	m.logins = append(m.logins, &loginAttempt{
+		Id:            ksuid.New().String(),
+		UserID:        userID,
+		Login:         login,
+		FailureReason: failureReason,
+		CreatedAt:     at,
+	})
+}
+
+// recordChange appends an entry to the change log.
+func (m *MemoryBackend) recordChange(resourceType, resourceID string, op ChangeOperation) {
+	m.changes = append(m.changes, &Change{
+		Seq:          int64(len(m.changes)) + 1,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Operation:    op,
+		CreatedAt:    time.Now().UTC(),
+	})
+}
+
+// bumpRevision increments the revision counter of a table and returns its new value.
+func (m *MemoryBackend) bumpRevision(table string) int64 {
+	m.revisions[table]++
+	return m.revisions[table]
+}
+
+// addID adds id to ids if it is not already present, and reports whether it did.
+func addID(ids []string, id string) ([]string, bool) {
+	if slices.Contains(ids, id) {
+		return ids, false
+	}
+	return append(ids, id), true
+}
+
+// removeID removes id from ids, and reports whether it was present.
+func removeID(ids []string, id string) ([]string, bool) {
+	if !slices.Contains(ids, id) {
+		return ids, false
+	}
+	return slices.DeleteFunc(ids, func(s string) bool {
+		return s == id
+	}), true
+}
+
+// equalTimes reports whether two optional times are both unset, or both set to the same instant.
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func (u *User) clone() *User {
+	ret := *u
+	return &ret
+}
+
+func (g *Group) clone() *Group {
+	ret := *g
+	ret.Admins = slices.Clone(g.Admins)
+	ret.Members = slices.Clone(g.Members)
+	ret.MemberGroups = slices.Clone(g.MemberGroups)
+	return &ret
+}
+
+func (r *Role) clone() *Role {
+	ret := *r
+	ret.DirectAssignments = slices.Clone(r.DirectAssignments)
+	ret.GroupAssignments = slices.Clone(r.GroupAssignments)
+	ret.Admins = slices.Clone(r.Admins)
+	ret.Delegates = slices.Clone(r.Delegates)
+	ret.Permissions = slices.Clone(r.Permissions)
+	return &ret
+}
+
+func (p *Permission) clone() *Permission {
+	ret := *p
+	ret.Roles = slices.Clone(p.Roles)
+	return &ret
+}
+
+func (p *Project) clone() *Project {
+	ret := *p
+	ret.GroupAssignments = slices.Clone(p.GroupAssignments)
+	return &ret
+}
+
+func (a *Assignment) clone() *Assignment {
+	ret := *a
+	if a.ExpiresAt != nil {
+		expiresAt := *a.ExpiresAt
+		ret.ExpiresAt = &expiresAt
+	}
+	return &ret
+}
+
+func (a *AccountState) clone() *AccountState {
+	ret := *a
+	if a.LockedAt != nil {
+		lockedAt := *a.LockedAt
+		ret.LockedAt = &lockedAt
+	}
+	if a.LastLoginAt != nil {
+		lastLoginAt := *a.LastLoginAt
+		ret.LastLoginAt = &lastLoginAt
+	}
+	return &ret
+}
+
+func (s *Session) clone() *Session {
+	ret := *s
+	if s.RevokedAt != nil {
+		revokedAt := *s.RevokedAt
+		ret.RevokedAt = &revokedAt
+	}
+	return &ret
+}
+
+func (sa *ServiceAccount) clone() *ServiceAccount {
+	ret := *sa
+	return &ret
+}
+
+func (k *APIKey) clone() *APIKey {
+	ret := *k
+	if k.ExpiresAt != nil {
+		expiresAt := *k.ExpiresAt
+		ret.ExpiresAt = &expiresAt
+	}
+	if k.LastUsedAt != nil {
+		lastUsedAt := *k.LastUsedAt
+		ret.LastUsedAt = &lastUsedAt
+	}
+	return &ret
+}
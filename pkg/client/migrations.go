@@ -0,0 +1,319 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// ErrUnknownSchema is returned by NewClient for a database whose schema it can neither use nor migrate.
+var ErrUnknownSchema = errors.New("unknown database schema")
+
+// schemaVersion is the version of the schema of the tables in allTableDescriptors. Whenever the schema changes it is
+// raised, and a migration upgrading databases at the previous version is appended to migrations.
+const schemaVersion = 1
+
+// noSchema is the schema version of a database without any table yet.
+const noSchema = -1
+
+// migration upgrades the schema and the data of a database at the version it is indexed by in migrations to the next
+// version.
+type migration func(ctx context.Context, c *Client) error
+
+// migrations upgrade the databases created by earlier versions. Version 0 is the schema of the first release, which
+// had no schema version.
+var migrations = []migration{
+	migrateFirstRelease,
+}
+
+var schemaVersions = (*schemaVersionsTable)(nil)
+
+// schemaVersionsTable holds the single row recording the schema version of the database.
+type schemaVersionsTable struct{}
+
+func (t *schemaVersionsTable) Name() string {
+	return "schema_version"
+}
+
+func (t *schemaVersionsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)", []interface{}{}
+}
+
+// migrate creates the tables of a new database, or brings the schema of a database created by an earlier version up
+// to date. Clients opening the same database at once wait for each other, since a transaction takes the write lock.
+func (c *Client) migrate(ctx context.Context) error {
+	return c.withTx(ctx, func(c *Client) error {
+		version, err := c.schemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version > schemaVersion {
+			return fmt.Errorf("baton-demo: the database has schema version %d, this version of baton-demo only knows up to %d: %w",
+				version, schemaVersion, ErrUnknownSchema)
+		}
+
+		if version != noSchema {
+			for v := version; v < schemaVersion; v++ {
+				err = migrations[v](ctx, c)
+				if err != nil {
+					return fmt.Errorf("baton-demo: migrating the database from schema version %d: %w", v, err)
+				}
+			}
+		}
+
+		// Ensure all tables exist, tables added without changing the others need no migration
+		err = c.createTables(ctx)
+		if err != nil {
+			return err
+		}
+
+		if version == schemaVersion {
+			return nil
+		}
+		return c.setSchemaVersion(ctx)
+	})
+}
+
+func (c *Client) createTables(ctx context.Context) error {
+	for _, t := range allTableDescriptors {
+		query, args := schemaFor(t, c.driver)
+
+		_, err := c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaVersion returns the schema version of the database. Databases created before the schema was versioned are at
+// version 0 if their tables are those of the first release, other databases without a version are rejected.
+func (c *Client) schemaVersion(ctx context.Context) (int, error) {
+	versionColumns, err := c.columns(ctx, schemaVersions.Name())
+	if err != nil {
+		return 0, err
+	}
+	if len(versionColumns) > 0 {
+		var version int
+		err = c.db.QueryRowContext(ctx, "SELECT version FROM "+schemaVersions.Name()).Scan(&version)
+		if err != nil {
+			return 0, err
+		}
+		return version, nil
+	}
+
+	userColumns, err := c.columns(ctx, users.Name())
+	if err != nil {
+		return 0, err
+	}
+	if len(userColumns) == 0 {
+		return noSchema, nil
+	}
+
+	groupColumns, err := c.columns(ctx, groups.Name())
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(userColumns, "tenant_id") && slices.Contains(groupColumns, "members") {
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("baton-demo: the database was created by a development version of baton-demo, "+
+		"recreate it by deleting it and running with --init-db: %w", ErrUnknownSchema)
+}
+
+func (c *Client) setSchemaVersion(ctx context.Context) error {
+	_, err := c.db.ExecContext(ctx, "DELETE FROM "+schemaVersions.Name())
+	if err != nil {
+		return err
+	}
+
+	query, args, err := c.db.Insert(schemaVersions.Name()).Prepared(true).Rows(goqu.Record{"version": schemaVersion}).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// columns returns the names of the columns of a table, or none if the table doesn't exist.
+func (c *Client) columns(ctx context.Context, table string) ([]string, error) {
+	rows, err := c.db.QueryContext(ctx, c.dialect.columnsQuery, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, name)
+	}
+
+	return ret, rows.Err()
+}
+
+// migrateFirstRelease migrates a database of the first release, whose users, groups, roles and projects kept the
+// principals assigned to them in comma separated columns. Its tables are rebuilt in the current schema, with every
+// object in the default tenant and every assignment recorded as made by the migration.
+func migrateFirstRelease(ctx context.Context, c *Client) error {
+	legacy := []tableDescriptor{users, groups, roles, projects, passwords}
+
+	// Move the tables of the first release out of the way of the tables replacing them
+	for _, t := range legacy {
+		_, err := c.db.ExecContext(ctx, "ALTER TABLE "+t.Name()+" RENAME TO legacy_"+t.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.createTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	q := c.db.Insert(tenants.Name()).Prepared(true)
+	q = q.Rows(goqu.Record{"id": DefaultTenant, "created_at": now(ctx)})
+	q = q.OnConflict(goqu.DoNothing())
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	migrated := newGrantOptions(ctx, []GrantOption{WithSource(SourceMigration)})
+
+	for _, t := range []struct {
+		from string
+		// into are the columns of the new table set to the values selected from the legacy table
+		into     []interface{}
+		selected []interface{}
+		// assignments are the columns holding the IDs of the principals assigned each object, which are copied to
+		// the table of assignments of the object's type
+		assignments []legacyAssignments
+	}{
+		{
+			from:     "legacy_users",
+			into:     []interface{}{"id", "tenant_id", "name", "email"},
+			selected: []interface{}{"id", goqu.V(DefaultTenant), "name", "email"},
+		},
+		{
+			from:        "legacy_groups",
+			into:        []interface{}{"id", "tenant_id", "name", "admins"},
+			selected:    []interface{}{"id", goqu.V(DefaultTenant), "name", "admins"},
+			assignments: []legacyAssignments{{"members", groupMembers, ResourceTypeUser}},
+		},
+		{
+			from:     "legacy_roles",
+			into:     []interface{}{"id", "tenant_id", "name"},
+			selected: []interface{}{"id", goqu.V(DefaultTenant), "name"},
+			assignments: []legacyAssignments{
+				{"direct_assignments", roleAssignments, ResourceTypeUser},
+				{"group_assignments", roleAssignments, ResourceTypeGroup},
+			},
+		},
+		{
+			from:        "legacy_projects",
+			into:        []interface{}{"id", "tenant_id", "name", "owner"},
+			selected:    []interface{}{"id", goqu.V(DefaultTenant), "name", "owner"},
+			assignments: []legacyAssignments{{"group_assignments", projectAssignments, ResourceTypeGroup}},
+		},
+		{
+			from:     "legacy_passwords",
+			into:     []interface{}{"id", "password", "user_id"},
+			selected: []interface{}{"id", "password", "user_id"},
+		},
+	} {
+		q := c.db.Insert(strings.TrimPrefix(t.from, "legacy_")).Prepared(true)
+		q = q.Cols(t.into...).FromQuery(c.db.From(t.from).Select(t.selected...))
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range t.assignments {
+			err = c.migrateAssignments(ctx, t.from, a, migrated)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Drop the legacy tables in reverse, passwords before the users they refer to
+	for i := len(legacy) - 1; i >= 0; i-- {
+		_, err = c.db.ExecContext(ctx, "DROP TABLE legacy_"+legacy[i].Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// legacyAssignments is a column of a legacy table holding the comma separated IDs of the principals assigned an
+// object.
+type legacyAssignments struct {
+	column        string
+	table         assignmentTable
+	principalType string
+}
+
+func (c *Client) migrateAssignments(ctx context.Context, from string, a legacyAssignments, opts *grantOptions) error {
+	query, args, err := c.db.From(from).Prepared(true).Select("id", a.column).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	var records []goqu.Record
+	for rows.Next() {
+		var id, principalIDs string
+		err = rows.Scan(&id, &principalIDs)
+		if err != nil {
+			_ = rows.Close()
+			return err
+		}
+
+		for _, principalID := range splitIDs(principalIDs) {
+			records = append(records, opts.row(a.table, id, a.principalType, principalID))
+		}
+	}
+	err = rows.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		query, args, err := c.db.Insert(a.table.Name()).Prepared(true).Rows(record).OnConflict(goqu.DoNothing()).ToSQL()
+		if err != nil {
+			return err
+		}
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
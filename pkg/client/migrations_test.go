@@ -0,0 +1,174 @@
+package client_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// firstReleaseSchema creates the tables of the first release, which had no schema version.
+var firstReleaseSchema = []string{
+	"CREATE TABLE users (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, email TEXT)",
+	"CREATE TABLE groups (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, admins TEXT NOT NULL, members TEXT NOT NULL)",
+	"CREATE TABLE roles (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, direct_assignments TEXT NOT NULL, group_assignments TEXT NOT NULL)",
+	"CREATE TABLE projects (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, owner TEXT NOT NULL, group_assignments TEXT NOT NULL)",
+	"CREATE TABLE passwords (id TEXT PRIMARY KEY, password TEXT NOT NULL, user_id TEXT NOT NULL, FOREIGN KEY(user_id) REFERENCES users(id))",
+}
+
+// newFirstReleaseDB writes a database of the first release holding two users, a group, a role and a project, and
+// returns its path.
+func newFirstReleaseDB(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "baton-demo.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	statements := append(slices.Clone(firstReleaseSchema),
+		"INSERT INTO users VALUES ('u1', 'victor', 'victor@example.org'), ('u2', 'wendy', 'wendy@example.org')",
+		// Empty lists were stored as empty strings
+		"INSERT INTO groups VALUES ('g1', 'Ops', 'u1', 'u1,u2'), ('g2', 'Empty', '', '')",
+		"INSERT INTO roles VALUES ('r1', 'Operator', 'u2', 'g1')",
+		"INSERT INTO projects VALUES ('p1', 'Pager', 'u1', 'g1')",
+		"INSERT INTO passwords VALUES ('pw1', 'hunter2', 'u1')",
+	)
+	for _, s := range statements {
+		_, err = db.Exec(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path
+}
+
+func TestMigrateFirstRelease(t *testing.T) {
+	ctx := context.Background()
+	path := newFirstReleaseDB(t)
+
+	c, err := client.NewClient(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	users, _, err := c.ListUsers(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Errorf("%d users, want 2", len(users))
+	}
+
+	group, err := c.GetGroup(ctx, "g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(group.Admins, []string{"u1"}) || !slices.Equal(group.Members, []string{"u1", "u2"}) {
+		t.Errorf("group admins %v and members %v, want [u1] and [u1 u2]", group.Admins, group.Members)
+	}
+
+	empty, err := c.GetGroup(ctx, "g2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.Admins) != 0 || len(empty.Members) != 0 {
+		t.Errorf("empty group has admins %v and members %v", empty.Admins, empty.Members)
+	}
+
+	role, err := c.GetRole(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(role.DirectAssignments, []string{"u2"}) || !slices.Equal(role.GroupAssignments, []string{"g1"}) {
+		t.Errorf("role assigned to users %v and groups %v, want [u2] and [g1]", role.DirectAssignments, role.GroupAssignments)
+	}
+
+	project, err := c.GetProject(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Owner != "u1" || !slices.Equal(project.GroupAssignments, []string{"g1"}) {
+		t.Errorf("project owned by %s and assigned to %v, want u1 and [g1]", project.Owner, project.GroupAssignments)
+	}
+
+	assignments, err := c.ListAssignments(ctx, client.ResourceTypeGroup, "g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range assignments {
+		if a.Source != client.SourceMigration {
+			t.Errorf("assignment of %s to %s from %s, want %s", a.ResourceID, a.PrincipalID, a.Source, client.SourceMigration)
+		}
+	}
+
+	_, err = c.Authenticate(ctx, "victor", "hunter2")
+	if err != nil {
+		t.Errorf("logging in with a password of the first release: %v", err)
+	}
+
+	// Writes go on with the revisions and the change log of the current schema
+	_, cursor, err := c.ListChanges(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := c.CreateUser(ctx, "xavier", "xavier@example.org", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Revision != 1 {
+		t.Errorf("revision %d of the first user created, want 1", user.Revision)
+	}
+	changes, _, err := c.ListChanges(ctx, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].ResourceID != user.Id {
+		t.Errorf("changes %v, want the creation of %s", changedIDs(changes), user.Id)
+	}
+
+	// Opening the migrated database again leaves it as it is
+	again, err := client.NewClient(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer again.Close()
+
+	users, _, err = again.ListUsers(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 {
+		t.Errorf("%d users after opening the database again, want 3", len(users))
+	}
+}
+
+func TestUnknownSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baton-demo.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A users table that is neither the first release's nor versioned
+	_, err = db.Exec("CREATE TABLE users (id TEXT PRIMARY KEY, name TEXT NOT NULL, email TEXT, revision INTEGER NOT NULL DEFAULT 0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, initDB := range []bool{false, true} {
+		_, err = client.NewClient(path, initDB)
+		if !errors.Is(err, client.ErrUnknownSchema) {
+			t.Errorf("init %t: got %v, want %v", initDB, err, client.ErrUnknownSchema)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// Permission is a single action that can be taken in the demo application. Roles are granted permissions, which
+// is what lets the users and groups assigned a role do anything.
+type Permission struct {
+	Id          string
+	Name        string
+	Description string
+	// Roles are the roles that are granted the permission.
+	Roles    []string
+	Revision int64
+}
+
+// ListPermissions returns a page of permissions from the database, ordered by ID.
+func (c *Client) ListPermissions(ctx context.Context, page PageOptions) ([]*Permission, string, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, "", err
+	}
+
+	q := c.db.From(permissions.Name()).Prepared(true)
+	q = q.Select("id", "name", "description", "revision")
+	q = q.Where(c.inTenant(permissions))
+
+	q, offset, err := paginate(q, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	permissionsList := []*Permission{}
+	for rows.Next() {
+		p := &Permission{}
+		err = rows.Scan(&p.Id, &p.Name, &p.Description, &p.Revision)
+		if err != nil {
+			return nil, "", err
+		}
+		permissionsList = append(permissionsList, p)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = c.loadPermissionRoles(ctx, permissionsList...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return permissionsList, page.nextToken(offset, len(permissionsList)), nil
+}
+
+// GetPermission returns the permission requested if it exists, else returns an error.
+func (c *Client) GetPermission(ctx context.Context, permissionID string) (*Permission, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, err
+	}
+
+	q := c.db.From(permissions.Name()).Prepared(true)
+	q = q.Select("id", "name", "description", "revision")
+	q = q.Where(c.inTenant(permissions))
+	q = q.Where(goqu.C("id").Eq(permissionID))
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Permission{}
+	err = c.db.QueryRowContext(ctx, query, args...).Scan(&p.Id, &p.Name, &p.Description, &p.Revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("permission %s: %w", permissionID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.loadPermissionRoles(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GrantRolePermission grants a permission to a role.
+func (c *Client) GrantRolePermission(ctx context.Context, roleID, permissionID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		role, err := c.GetRole(ctx, roleID)
+		if err != nil {
+			return err
+		}
+
+		err = role.checkMutable()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetPermission(ctx, permissionID)
+		if err != nil {
+			return err
+		}
+
+		q := c.db.Insert(rolePermissions.Name()).Prepared(true)
+		q = q.Rows(goqu.Record{
+			"role_id":       roleID,
+			"permission_id": permissionID,
+		})
+		q = q.OnConflict(goqu.DoNothing())
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		res, err := c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		return c.rolePermissionsChanged(ctx, roleID, permissionID, res)
+	})
+}
+
+// RevokeRolePermission takes a permission away from a role.
+func (c *Client) RevokeRolePermission(ctx context.Context, roleID, permissionID string) error {
+	return c.withTx(ctx, func(c *Client) error {
+		err := c.validateDB()
+		if err != nil {
+			return err
+		}
+
+		role, err := c.GetRole(ctx, roleID)
+		if err != nil {
+			return err
+		}
+
+		err = role.checkMutable()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.GetPermission(ctx, permissionID)
+		if err != nil {
+			return err
+		}
+
+		q := c.db.Delete(rolePermissions.Name()).Prepared(true)
+		q = q.Where(
+			goqu.C("role_id").Eq(roleID),
+			goqu.C("permission_id").Eq(permissionID),
+		)
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		res, err := c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		return c.rolePermissionsChanged(ctx, roleID, permissionID, res)
+	})
+}
+
+// rolePermissionsChanged stamps both the role and the permission with a new revision and records the changes if the
+// write res affected any rows.
+func (c *Client) rolePermissionsChanged(ctx context.Context, roleID, permissionID string, res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return nil
+	}
+
+	for _, changed := range []struct {
+		table        tableDescriptor
+		resourceType string
+		id           string
+	}{
+		{roles, ResourceTypeRole, roleID},
+		{permissions, ResourceTypePermission, permissionID},
+	} {
+		rev, err := c.bumpRevision(ctx, changed.table.Name())
+		if err != nil {
+			return err
+		}
+
+		q := c.db.Update(changed.table.Name()).Prepared(true)
+		q = q.Set(goqu.Record{
+			"revision": rev,
+		})
+		q = q.Where(goqu.C("id").Eq(changed.id))
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		err = c.recordChange(ctx, c.db, changed.resourceType, changed.id, ChangeUpdate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadPermissionRoles fills in the roles granted the given permissions.
+func (c *Client) loadPermissionRoles(ctx context.Context, perms ...*Permission) error {
+	byID := make(map[string]*Permission, len(perms))
+	ids := make([]interface{}, 0, len(perms))
+	for _, p := range perms {
+		p.Roles = []string{}
+		byID[p.Id] = p
+		ids = append(ids, p.Id)
+	}
+
+	return c.loadRolePermissionPairs(ctx, "permission_id", ids, func(roleID, permissionID string) {
+		p := byID[permissionID]
+		p.Roles = append(p.Roles, roleID)
+	})
+}
+
+// loadRolePermissions fills in the permissions granted to the given roles.
+func (c *Client) loadRolePermissions(ctx context.Context, rls ...*Role) error {
+	byID := make(map[string]*Role, len(rls))
+	ids := make([]interface{}, 0, len(rls))
+	for _, r := range rls {
+		r.Permissions = []string{}
+		byID[r.Id] = r
+		ids = append(ids, r.Id)
+	}
+
+	return c.loadRolePermissionPairs(ctx, "role_id", ids, func(roleID, permissionID string) {
+		r := byID[roleID]
+		r.Permissions = append(r.Permissions, permissionID)
+	})
+}
+
+// loadRolePermissionPairs calls fn with every role and permission pair whose column is one of the IDs, ordered by
+// role and then permission.
+func (c *Client) loadRolePermissionPairs(ctx context.Context, column string, ids []interface{}, fn func(roleID, permissionID string)) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	q := c.db.From(rolePermissions.Name()).Prepared(true)
+	q = q.Select("role_id", "permission_id")
+	q = q.Where(goqu.C(column).In(ids...))
+	q = q.Order(goqu.C("role_id").Asc(), goqu.C("permission_id").Asc())
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		roleID, permissionID := "", ""
+		err = rows.Scan(&roleID, &permissionID)
+		if err != nil {
+			return err
+		}
+		fn(roleID, permissionID)
+	}
+
+	return rows.Err()
+}
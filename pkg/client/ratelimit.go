@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quota allows Limit operations per Window. Unused capacity accumulates up to Limit, so short bursts are allowed.
+type Quota struct {
+	Limit  int64
+	Window time.Duration
+}
+
+// ParseQuota parses a quota in the form "<limit>/<window>", for example "100/1m".
+func ParseQuota(s string) (Quota, error) {
+	limit, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return Quota{}, fmt.Errorf("invalid quota %q: expected <limit>/<window>", s)
+	}
+
+	l, err := strconv.ParseInt(strings.TrimSpace(limit), 10, 64)
+	if err != nil || l <= 0 {
+		return Quota{}, fmt.Errorf("invalid quota %q: limit must be a positive integer", s)
+	}
+
+	w, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || w <= 0 {
+		return Quota{}, fmt.Errorf("invalid quota %q: window must be a positive duration", s)
+	}
+
+	return Quota{Limit: l, Window: w}, nil
+}
+
+// tokenBucket holds the state of a single quota.
+type tokenBucket struct {
+	quota  Quota
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accrued since the last call.
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return
+	}
+
+	b.tokens += elapsed.Seconds() * float64(b.quota.Limit) / b.quota.Window.Seconds()
+	if b.tokens > float64(b.quota.Limit) {
+		b.tokens = float64(b.quota.Limit)
+	}
+	b.last = now
+}
+
+// nextToken returns when the next whole token becomes available.
+func (b *tokenBucket) nextToken(now time.Time) time.Time {
+	missing := 1 - b.tokens
+	if missing <= 0 {
+		return now
+	}
+
+	perToken := b.quota.Window.Seconds() / float64(b.quota.Limit)
+	return now.Add(time.Duration(missing * perToken * float64(time.Second)))
+}
+
+// RateLimiter enforces a token bucket quota for each class of backend operation, simulating an upstream API quota.
+// Use its Intercept method with Intercept to apply it to a Backend.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[OperationClass]*tokenBucket
+	now     func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter enforcing the given quotas. Operation classes without a quota are not limited.
+func NewRateLimiter(quotas map[OperationClass]Quota) *RateLimiter {
+	r := &RateLimiter{
+		buckets: make(map[OperationClass]*tokenBucket),
+		now:     time.Now,
+	}
+
+	start := r.now()
+	for class, q := range quotas {
+		r.buckets[class] = &tokenBucket{
+			quota:  q,
+			tokens: float64(q.Limit),
+			last:   start,
+		}
+	}
+
+	return r
+}
+
+// Intercept is an Interceptor that takes a token from the bucket of the operation's class, or fails the operation
+// with a RateLimitError if the bucket is empty.
+func (r *RateLimiter) Intercept(ctx context.Context, op Operation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.buckets[op.Class]
+	if !ok {
+		return nil
+	}
+
+	now := r.now()
+	b.refill(now)
+
+	if b.tokens < 1 {
+		return &RateLimitError{
+			Operation: op.Name,
+			Limit:     b.quota.Limit,
+			Remaining: 0,
+			ResetAt:   b.nextToken(now),
+		}
+	}
+
+	b.tokens--
+
+	return nil
+}
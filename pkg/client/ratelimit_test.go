@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestRateLimiter returns a RateLimiter whose clock only moves when the returned function advances it.
+func newTestRateLimiter(quotas map[OperationClass]Quota) (*RateLimiter, func(d time.Duration)) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r := NewRateLimiter(quotas)
+	r.now = func() time.Time { return clock }
+	for _, b := range r.buckets {
+		b.last = clock
+	}
+
+	return r, func(d time.Duration) { clock = clock.Add(d) }
+}
+
+// retryAfter makes a list call and returns how long the rate limit it ran into asks to wait, or -1 if the call was
+// allowed.
+func retryAfter(t *testing.T, r *RateLimiter) time.Duration {
+	t.Helper()
+
+	err := r.Intercept(context.Background(), Operation{Name: "ListUsers", Class: OperationClassList})
+	if err == nil {
+		return -1
+	}
+
+	var rlErr *RateLimitError
+	if !errors.As(err, &rlErr) {
+		t.Fatalf("got %v, want a rate limit error", err)
+	}
+	if rlErr.Limit != 10 || rlErr.Remaining != 0 {
+		t.Errorf("limit %d with %d remaining, want 10 with 0 remaining", rlErr.Limit, rlErr.Remaining)
+	}
+	return rlErr.ResetAt.Sub(r.now())
+}
+
+func TestRateLimiter(t *testing.T) {
+	// A token every 6 seconds
+	r, advance := newTestRateLimiter(map[OperationClass]Quota{
+		OperationClassList: {Limit: 10, Window: time.Minute},
+	})
+
+	// The bucket starts full, so the whole quota can be used at once
+	for i := 0; i < 10; i++ {
+		wait := retryAfter(t, r)
+		if wait != -1 {
+			t.Fatalf("call %d of a full bucket rate limited for %s", i, wait)
+		}
+	}
+
+	// An empty bucket waits for a whole token
+	wait := retryAfter(t, r)
+	if wait != 6*time.Second {
+		t.Errorf("empty bucket: retry after %s, want 6s", wait)
+	}
+
+	// Half a token has accrued, the other half is still missing
+	advance(3 * time.Second)
+	wait = retryAfter(t, r)
+	if wait != 3*time.Second {
+		t.Errorf("half a token: retry after %s, want 3s", wait)
+	}
+
+	advance(3 * time.Second)
+	wait = retryAfter(t, r)
+	if wait != -1 {
+		t.Errorf("a whole token: rate limited for %s", wait)
+	}
+	wait = retryAfter(t, r)
+	if wait != 6*time.Second {
+		t.Errorf("token used: retry after %s, want 6s", wait)
+	}
+
+	// Idling refills the bucket no further than the quota
+	advance(time.Hour)
+	for i := 0; i < 10; i++ {
+		wait := retryAfter(t, r)
+		if wait != -1 {
+			t.Fatalf("call %d of a refilled bucket rate limited for %s", i, wait)
+		}
+	}
+	wait = retryAfter(t, r)
+	if wait != 6*time.Second {
+		t.Errorf("burst over the quota: retry after %s, want 6s", wait)
+	}
+
+	// Classes without a quota are not limited
+	err := r.Intercept(context.Background(), Operation{Name: "GetUser", Class: OperationClassGet})
+	if err != nil {
+		t.Errorf("unlimited class: %v", err)
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	q, err := ParseQuota("100/1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Limit != 100 || q.Window != time.Minute {
+		t.Errorf("got %d per %s, want 100 per 1m0s", q.Limit, q.Window)
+	}
+
+	for _, s := range []string{"", "100", "0/1m", "-1/1m", "x/1m", "100/0s", "100/soon"} {
+		_, err = ParseQuota(s)
+		if err == nil {
+			t.Errorf("%q: got no error", s)
+		}
+	}
+}
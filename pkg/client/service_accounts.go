@@ -0,0 +1,286 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// apiKeyLifetime is how long an API key is valid after it was created or rotated.
+const apiKeyLifetime = 90 * 24 * time.Hour
+
+// ServiceAccount is a non-human identity. A service account is owned by a User, who is responsible for it, until
+// that user is deleted. Owner is empty then.
+type ServiceAccount struct {
+	Id       string
+	Name     string
+	Owner    string
+	Revision int64
+}
+
+// APIKey is a credential of a service account. The secret itself is only ever returned when the key is rotated.
+type APIKey struct {
+	Id               string
+	ServiceAccountID string
+	Name             string
+	CreatedAt        time.Time
+	// ExpiresAt is unset for keys that never expire.
+	ExpiresAt *time.Time
+	// LastUsedAt is unset for keys that were never used.
+	LastUsedAt *time.Time
+	Revision   int64
+}
+
+// newAPIKeySecret returns a new random API key secret and the hash that is stored in its place.
+func newAPIKeySecret() (string, string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret := "demo_" + base64.RawURLEncoding.EncodeToString(b)
+	return secret, hashAPIKeySecret(secret), nil
+}
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ListServiceAccounts returns a page of service accounts from the database, ordered by ID.
+func (c *Client) ListServiceAccounts(ctx context.Context, page PageOptions) ([]*ServiceAccount, string, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, "", err
+	}
+
+	q := c.db.From(serviceAccounts.Name()).Prepared(true)
+	q = q.Select("id", "name", goqu.COALESCE(goqu.C("owner"), "").As("owner"), "revision")
+	q = q.Where(c.inTenant(serviceAccounts))
+
+	q, offset, err := paginate(q, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	serviceAccountsList := []*ServiceAccount{}
+	for rows.Next() {
+		sa := &ServiceAccount{}
+		err = rows.Scan(&sa.Id, &sa.Name, &sa.Owner, &sa.Revision)
+		if err != nil {
+			return nil, "", err
+		}
+		serviceAccountsList = append(serviceAccountsList, sa)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return serviceAccountsList, page.nextToken(offset, len(serviceAccountsList)), nil
+}
+
+// GetServiceAccount returns the service account requested if it exists, else returns an error.
+func (c *Client) GetServiceAccount(ctx context.Context, serviceAccountID string) (*ServiceAccount, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, err
+	}
+
+	q := c.db.From(serviceAccounts.Name()).Prepared(true)
+	q = q.Select("id", "name", goqu.COALESCE(goqu.C("owner"), "").As("owner"), "revision")
+	q = q.Where(c.inTenant(serviceAccounts))
+	q = q.Where(goqu.C("id").Eq(serviceAccountID))
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	row := c.db.QueryRowContext(ctx, query, args...)
+	sa := &ServiceAccount{}
+	err = row.Scan(&sa.Id, &sa.Name, &sa.Owner, &sa.Revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("service account %s: %w", serviceAccountID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return sa, nil
+}
+
+// ListAPIKeys returns a page of the API keys of a service account, ordered by ID.
+func (c *Client) ListAPIKeys(ctx context.Context, serviceAccountID string, page PageOptions) ([]*APIKey, string, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, "", err
+	}
+
+	q := c.db.From(apiKeys.Name()).Prepared(true)
+	q = q.Select("id", "service_account_id", "name", "created_at", "expires_at", "last_used_at", "revision")
+	q = q.Where(c.inTenant(apiKeys))
+	q = q.Where(goqu.C("service_account_id").Eq(serviceAccountID))
+
+	q, offset, err := paginate(q, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	apiKeysList := []*APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		apiKeysList = append(apiKeysList, key)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return apiKeysList, page.nextToken(offset, len(apiKeysList)), nil
+}
+
+// GetAPIKey returns the API key requested if it exists, else returns an error.
+func (c *Client) GetAPIKey(ctx context.Context, keyID string) (*APIKey, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, err
+	}
+
+	q := c.db.From(apiKeys.Name()).Prepared(true)
+	q = q.Select("id", "service_account_id", "name", "created_at", "expires_at", "last_used_at", "revision")
+	q = q.Where(c.inTenant(apiKeys))
+	q = q.Where(goqu.C("id").Eq(keyID))
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := scanAPIKey(c.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("api key %s: %w", keyID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// RotateAPIKey replaces the secret of an API key, which restarts its lifetime. It returns the updated key along with
+// the new secret, which is not stored and cannot be retrieved again.
+func (c *Client) RotateAPIKey(ctx context.Context, keyID string) (*APIKey, string, error) {
+	secret, secretHash, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := inTx(ctx, c, func(c *Client) (*APIKey, error) {
+		key, err := c.GetAPIKey(ctx, keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		key.Revision, err = c.bumpRevision(ctx, apiKeys.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		at := now(ctx)
+		expiresAt := at.Add(apiKeyLifetime)
+		key.CreatedAt = at
+		key.ExpiresAt = &expiresAt
+		key.LastUsedAt = nil
+
+		q := c.db.Update(apiKeys.Name()).Prepared(true)
+		q = q.Set(goqu.Record{
+			"secret_hash":  secretHash,
+			"created_at":   key.CreatedAt,
+			"expires_at":   key.ExpiresAt,
+			"last_used_at": nil,
+			"revision":     key.Revision,
+		})
+		q = q.Where(goqu.C("id").Eq(keyID))
+
+		query, args, err := q.ToSQL()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = c.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.recordChange(ctx, c.db, ResourceTypeAPIKey, keyID, ChangeUpdate)
+		if err != nil {
+			return nil, err
+		}
+
+		return key, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	key := &APIKey{}
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(&key.Id, &key.ServiceAccountID, &key.Name, &key.CreatedAt, &expiresAt, &lastUsedAt, &key.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return key, nil
+}
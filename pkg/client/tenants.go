@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/segmentio/ksuid"
+)
+
+// DefaultTenant is the tenant of a client that isn't given one.
+const DefaultTenant = "default"
+
+// Tenant is an isolated copy of the demo application sharing a database with other tenants. Every user, group, role,
+// project, permission, service account and API key belongs to exactly one tenant, and a client only sees the
+// resources of its own.
+type Tenant struct {
+	Id        string
+	CreatedAt time.Time
+}
+
+// WithTenant scopes a client to a tenant. Initializing the database creates the tenant and seeds it with its own copy
+// of the demo data, otherwise the tenant must already exist. An empty tenant is the default tenant.
+func WithTenant(tenant string) ClientOption {
+	return func(c *Client) {
+		if tenant != "" {
+			c.tenant = tenant
+		}
+	}
+}
+
+var tenants = (*tenantsTable)(nil)
+
+type tenantsTable struct{}
+
+func (t *tenantsTable) Name() string {
+	return "tenants"
+}
+
+func (t *tenantsTable) Schema() (string, []interface{}) {
+	return "CREATE TABLE IF NOT EXISTS tenants (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL)", []interface{}{}
+}
+
+// GetTenant returns the tenant the client is scoped to.
+func (c *Client) GetTenant(ctx context.Context) (*Tenant, error) {
+	err := c.validateDB()
+	if err != nil {
+		return nil, err
+	}
+
+	q := c.db.From(tenants.Name()).Prepared(true)
+	q = q.Select("id", "created_at")
+	q = q.Where(goqu.C("id").Eq(c.tenant))
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := &Tenant{}
+	err = c.db.QueryRowContext(ctx, query, args...).Scan(&tenant.Id, &tenant.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("tenant %s: %w", c.tenant, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tenant, nil
+}
+
+// createTenant creates the tenant of the client if it doesn't exist yet.
+func (c *Client) createTenant(ctx context.Context, db execer) error {
+	q := c.db.Insert(tenants.Name()).Prepared(true)
+	q = q.Rows(goqu.Record{
+		"id":         c.tenant,
+		"created_at": now(ctx),
+	})
+	q = q.OnConflict(goqu.DoNothing())
+
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// inTenant restricts a query of a table of resources to the resources of the client's tenant.
+func (c *Client) inTenant(t tableDescriptor) exp.BooleanExpression {
+	return goqu.T(t.Name()).Col("tenant_id").Eq(c.tenant)
+}
+
+// tenantResourceIDs selects the IDs of the resources of a table that belong to the client's tenant, for scoping
+// queries of the tables that refer to them.
+func (c *Client) tenantResourceIDs(t tableDescriptor) *goqu.SelectDataset {
+	return c.db.From(t.Name()).Select("id").Where(c.inTenant(t))
+}
+
+// forTenant gives the seed data of a tenant other than the default one IDs of its own, so that the resources of
+// different tenants never share an ID. The IDs are derived from the tenant and the seed ID, so seeding a tenant again
+// finds the resources seeded before.
+func (db *database) forTenant(tenant string) {
+	if tenant == DefaultTenant {
+		return
+	}
+
+	id := func(seedID string) string {
+		sum := sha256.Sum256([]byte(tenant + "/" + seedID))
+		k, err := ksuid.FromBytes(sum[:20])
+		if err != nil {
+			// A KSUID is always 20 bytes
+			panic(err)
+		}
+		return k.String()
+	}
+	ids := func(seedIDs []string) []string {
+		ret := make([]string, len(seedIDs))
+		for i, seedID := range seedIDs {
+			ret[i] = id(seedID)
+		}
+		return ret
+	}
+
+	for _, u := range db.Users {
+		u.Id = id(u.Id)
+	}
+
+	passwords := make(map[string]string, len(db.Passwords))
+	for userID, password := range db.Passwords {
+		passwords[id(userID)] = password
+	}
+	db.Passwords = passwords
+
+	for _, g := range db.Groups {
+		g.Id = id(g.Id)
+		g.Admins = ids(g.Admins)
+		g.Members = ids(g.Members)
+		g.MemberGroups = ids(g.MemberGroups)
+	}
+
+	for _, r := range db.Roles {
+		r.Id = id(r.Id)
+		r.DirectAssignments = ids(r.DirectAssignments)
+		r.GroupAssignments = ids(r.GroupAssignments)
+		r.Admins = ids(r.Admins)
+		r.Delegates = ids(r.Delegates)
+		r.Permissions = ids(r.Permissions)
+	}
+
+	for _, p := range db.Permissions {
+		p.Id = id(p.Id)
+	}
+
+	for _, p := range db.Projects {
+		p.Id = id(p.Id)
+		p.Owner = id(p.Owner)
+		p.GroupAssignments = ids(p.GroupAssignments)
+	}
+
+	for _, s := range db.AccountStates {
+		s.UserID = id(s.UserID)
+	}
+
+	for _, s := range db.Sessions {
+		s.Id = id(s.Id)
+		s.UserID = id(s.UserID)
+	}
+
+	for _, sa := range db.ServiceAccounts {
+		sa.Id = id(sa.Id)
+		sa.Owner = id(sa.Owner)
+	}
+
+	for _, k := range db.APIKeys {
+		k.Id = id(k.Id)
+		k.ServiceAccountID = id(k.ServiceAccountID)
+	}
+
+	for _, a := range db.Attributes {
+		a.ResourceID = id(a.ResourceID)
+	}
+}
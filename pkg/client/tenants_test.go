@@ -0,0 +1,184 @@
+package client_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// TestTenantIsolation checks that two tenants sharing a database see neither each other's resources nor each other's
+// changes and revisions.
+func TestTenantIsolation(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "baton-demo.db")
+
+	def, err := client.NewClient(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = def.Close() })
+
+	acme, err := client.NewClient(path, true, client.WithTenant("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = acme.Close() })
+
+	_, defCursor, err := def.ListChanges(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, acmeCursor, err := acme.ListChanges(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defUsers, _, err := def.ListUsers(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	acmeUsers, _, err := acme.ListUsers(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defUsers) != len(acmeUsers) {
+		t.Errorf("default tenant has %d users, acme %d, want both seeded alike", len(defUsers), len(acmeUsers))
+	}
+	for _, u := range acmeUsers {
+		_, err = def.GetUser(ctx, u.Id)
+		if err == nil {
+			t.Errorf("default tenant sees user %s of acme", u.Id)
+		}
+	}
+
+	first, err := def.CreateUser(ctx, "default-only", "default-only@example.org", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := acme.CreateUser(ctx, "acme-only", "acme-only@example.org", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := def.CreateUser(ctx, "default-too", "default-too@example.org", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Revision != first.Revision+1 {
+		t.Errorf("revision %d after %d, acme's writes must not bump the default tenant's revisions", second.Revision, first.Revision)
+	}
+	if other.Revision != 1 {
+		t.Errorf("revision %d of acme's first user, want 1", other.Revision)
+	}
+
+	defChanges, _, err := def.ListChanges(ctx, defCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acmeChanges, _, err := acme.ListChanges(ctx, acmeCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := changedIDs(defChanges); len(got) != 2 || got[0] != first.Id || got[1] != second.Id {
+		t.Errorf("default tenant lists changes to %v, want %v", got, []string{first.Id, second.Id})
+	}
+	if got := changedIDs(acmeChanges); len(got) != 1 || got[0] != other.Id {
+		t.Errorf("acme lists changes to %v, want %v", got, []string{other.Id})
+	}
+}
+
+// TestTenantsFirstReleaseUpgrade checks that upgrading a database of the first release, which had no tenants, moves its
+// objects into the default tenant, even when it is first opened by another tenant.
+func TestTenantsFirstReleaseUpgrade(t *testing.T) {
+	ctx := context.Background()
+	path := newFirstReleaseDB(t)
+
+	acme, err := client.NewClient(path, true, client.WithTenant("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = acme.Close() })
+
+	def, err := client.NewClient(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = def.Close() })
+
+	defUsers, _, err := def.ListUsers(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defUsers) != 2 {
+		t.Errorf("default tenant has %d users, want the 2 of the first release", len(defUsers))
+	}
+
+	for _, id := range []string{"u1", "u2"} {
+		_, err = acme.GetUser(ctx, id)
+		if err == nil {
+			t.Errorf("acme sees user %s of the first release", id)
+		}
+	}
+	_, err = acme.Authenticate(ctx, "victor", "hunter2")
+	if err == nil {
+		t.Error("logged in to acme with a password of the default tenant")
+	}
+	_, err = def.Authenticate(ctx, "victor", "hunter2")
+	if err != nil {
+		t.Errorf("logging in to the default tenant: %v", err)
+	}
+
+	acmeUsers, _, err := acme.ListUsers(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acmeUsers) == 0 {
+		t.Error("acme was not seeded")
+	}
+
+	_, defCursor, err := def.ListChanges(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, acmeCursor, err := acme.ListChanges(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = def.GrantGroupMember(ctx, "g2", "u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := def.GetGroup(ctx, "g2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Revision != 1 {
+		t.Errorf("revision %d of a group of the first release after its first change, want 1", group.Revision)
+	}
+
+	defChanges, _, err := def.ListChanges(ctx, defCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changedIDs(defChanges); len(got) != 1 || got[0] != "g2" {
+		t.Errorf("default tenant lists changes to %v, want [g2]", got)
+	}
+	acmeChanges, _, err := acme.ListChanges(ctx, acmeCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acmeChanges) != 0 {
+		t.Errorf("acme lists changes to %v, want none", changedIDs(acmeChanges))
+	}
+}
+
+func changedIDs(changes []*client.Change) []string {
+	ids := make([]string, 0, len(changes))
+	for _, c := range changes {
+		ids = append(ids, c.ResourceID)
+	}
+	return ids
+}
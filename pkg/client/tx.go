@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// dbHandle is satisfied by both goqu.Database and goqu.TxDatabase, so the queries of a Client run the same in and
+// outside of a transaction.
+type dbHandle interface {
+	From(from ...interface{}) *goqu.SelectDataset
+	Insert(table interface{}) *goqu.InsertDataset
+	Update(table interface{}) *goqu.UpdateDataset
+	Delete(table interface{}) *goqu.DeleteDataset
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// commitErr is returned by a mutator whose writes must be committed even though it fails, such as the record of a
+// failed login.
+type commitErr struct {
+	error
+}
+
+func (e commitErr) Unwrap() error {
+	return e.error
+}
+
+// withTx runs fn with a copy of the client whose queries run in a transaction, which is committed if fn succeeds and
+// rolled back otherwise. Mutators read the objects they change in their transaction, and transactions take the write
+// lock of the database as they begin, so concurrent writes to the same object can't overwrite each other. Calls made
+// by fn run in the transaction already open.
+func (c *Client) withTx(ctx context.Context, fn func(c *Client) error) error {
+	err := c.validateDB()
+	if err != nil {
+		return err
+	}
+
+	if c.tx {
+		return fn(c)
+	}
+
+	tx, err := c.rootDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var failure error
+	err = tx.Wrap(func() error {
+		if c.dialect.lockTx != "" {
+			_, err := tx.ExecContext(ctx, c.dialect.lockTx)
+			if err != nil {
+				return err
+			}
+		}
+
+		txc := *c
+		txc.db = tx
+		txc.tx = true
+
+		err := fn(&txc)
+		var ce commitErr
+		if errors.As(err, &ce) {
+			failure = ce.error
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return failure
+}
+
+// inTx is withTx for mutators that return a value.
+func inTx[T any](ctx context.Context, c *Client, fn func(c *Client) (T, error)) (T, error) {
+	var ret T
+	err := c.withTx(ctx, func(c *Client) error {
+		var err error
+		ret, err = fn(c)
+		return err
+	})
+	return ret, err
+}
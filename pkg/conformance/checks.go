@@ -0,0 +1,424 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/crypto/providers/jwk"
+)
+
+// passwordLength is the length of the random passwords the checks ask for.
+const passwordLength = 20
+
+// checkSync lists every resource of a resource type along with its entitlements and grants.
+func (s *session) checkSync(ctx context.Context, rt *v2.ResourceType) error {
+	rs, err := s.resourcesOfType(ctx, rt.GetId())
+	if err != nil {
+		return fmt.Errorf("listing resources: %w", err)
+	}
+
+	for _, r := range rs {
+		_, err = s.entitlements(ctx, r)
+		if err != nil {
+			return fmt.Errorf("listing the entitlements of %s: %w", r.GetId().GetResource(), err)
+		}
+		_, err = s.grants(ctx, r)
+		if err != nil {
+			return fmt.Errorf("listing the grants of %s: %w", r.GetId().GetResource(), err)
+		}
+	}
+
+	return nil
+}
+
+// checkProvision grants every entitlement of every resource of a resource type that isn't immutable to a principal
+// of each type it is grantable to, checks that the grant is listed, revokes the listed grant and checks that it is
+// gone.
+func (s *session) checkProvision(ctx context.Context, rt *v2.ResourceType) error {
+	rs, err := s.resourcesOfType(ctx, rt.GetId())
+	if err != nil {
+		return fmt.Errorf("listing resources: %w", err)
+	}
+
+	all, err := s.resources(ctx)
+	if err != nil {
+		return fmt.Errorf("listing resources: %w", err)
+	}
+
+	resourceTypes, err := s.resourceTypes(ctx)
+	if err != nil {
+		return fmt.Errorf("listing resource types: %w", err)
+	}
+
+	var errs []error
+	exercised := 0
+	for _, r := range rs {
+		entitlements, err := s.entitlements(ctx, r)
+		if err != nil {
+			return fmt.Errorf("listing the entitlements of %s: %w", r.GetId().GetResource(), err)
+		}
+
+		grants, err := s.grants(ctx, r)
+		if err != nil {
+			return fmt.Errorf("listing the grants of %s: %w", r.GetId().GetResource(), err)
+		}
+
+		for _, e := range entitlements {
+			annos := annotations.Annotations(e.GetAnnotations())
+			if annos.Contains(&v2.EntitlementImmutable{}) {
+				continue
+			}
+
+			for _, principalType := range grantableTo(e, resourceTypes) {
+				principals := candidates(e, principalType, all, grants)
+				if len(principals) == 0 {
+					// Every principal of the type already holds the entitlement
+					continue
+				}
+
+				err := s.grantRoundTrip(ctx, e, principals)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s to a %s: %w", e.GetId(), principalType, err))
+					continue
+				}
+				exercised++
+			}
+		}
+	}
+
+	if exercised == 0 && len(errs) == 0 {
+		return errors.New("provisioning is advertised but there is no entitlement that can be granted")
+	}
+
+	return errors.Join(errs...)
+}
+
+// grantRoundTrip grants an entitlement to the first candidate the connector accepts, then revokes it. Connectors may
+// refuse some principals for good reason, such as a group that would end up a member of itself, so the error of the
+// first candidate is only returned if every candidate is refused.
+func (s *session) grantRoundTrip(ctx context.Context, e *v2.Entitlement, candidates []*v2.Resource) error {
+	var grantErr error
+	for _, principal := range candidates {
+		_, err := s.srv.Grant(ctx, &v2.GrantManagerServiceGrantRequest{Entitlement: e, Principal: principal})
+		if err != nil {
+			if grantErr == nil {
+				grantErr = fmt.Errorf("granting to %s: %w", principal.GetId().GetResource(), err)
+			}
+			continue
+		}
+
+		g, err := s.findGrant(ctx, e, principal.GetId())
+		if err != nil {
+			return fmt.Errorf("listing grants: %w", err)
+		}
+		if g == nil {
+			return fmt.Errorf("granted to %s, but the grant is not listed", principal.GetId().GetResource())
+		}
+
+		_, err = s.srv.Revoke(ctx, &v2.GrantManagerServiceRevokeRequest{Grant: g})
+		if err != nil {
+			return fmt.Errorf("revoking from %s: %w", principal.GetId().GetResource(), err)
+		}
+
+		g, err = s.findGrant(ctx, e, principal.GetId())
+		if err != nil {
+			return fmt.Errorf("listing grants: %w", err)
+		}
+		if g != nil {
+			return fmt.Errorf("revoked from %s, but the grant is still listed", principal.GetId().GetResource())
+		}
+
+		return nil
+	}
+
+	return grantErr
+}
+
+// grantableTo returns the resource types an entitlement can be granted to. Entitlements that don't say are grantable
+// to every resource type with the user trait.
+func grantableTo(e *v2.Entitlement, resourceTypes []*v2.ResourceType) []string {
+	var ret []string
+	for _, rt := range e.GetGrantableTo() {
+		ret = append(ret, rt.GetId())
+	}
+	if len(ret) > 0 {
+		return ret
+	}
+
+	for _, rt := range resourceTypes {
+		for _, trait := range rt.GetTraits() {
+			if trait == v2.ResourceType_TRAIT_USER {
+				ret = append(ret, rt.GetId())
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// candidates returns the principals of a resource type that an entitlement could be granted to, leaving out the
+// resource of the entitlement itself and the principals already holding it, whose grants would outlive the revocation.
+func candidates(e *v2.Entitlement, principalType string, all []*v2.Resource, grants []*v2.Grant) []*v2.Resource {
+	var ret []*v2.Resource
+	for _, r := range all {
+		if r.GetId().GetResourceType() != principalType || sameResource(r.GetId(), e.GetResource().GetId()) {
+			continue
+		}
+		if holds(r, e, grants) {
+			continue
+		}
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+func holds(principal *v2.Resource, e *v2.Entitlement, grants []*v2.Grant) bool {
+	for _, g := range grants {
+		if g.GetEntitlement().GetId() == e.GetId() && sameResource(g.GetPrincipal().GetId(), principal.GetId()) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCreateAccount creates an account with a credential option and checks the account is listed and that it was
+// given a credential only if one was asked for.
+func (s *session) checkCreateAccount(ctx context.Context, rt *v2.ResourceType, option v2.CapabilityDetailCredentialOption) error {
+	if s.cfg.AccountInfo == nil {
+		return errors.New("account provisioning is advertised but the config has no AccountInfo function")
+	}
+
+	opts, err := credentialOptions(option)
+	if err != nil {
+		return err
+	}
+
+	key, err := newKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.srv.CreateAccount(ctx, &v2.CreateAccountRequest{
+		AccountInfo:       s.cfg.AccountInfo(next()),
+		CredentialOptions: opts,
+		EncryptionConfigs: []*v2.EncryptionConfig{key.config},
+	})
+	if err != nil {
+		return fmt.Errorf("creating an account: %w", err)
+	}
+
+	created := resp.GetSuccess().GetResource()
+	if created == nil {
+		created = resp.GetActionRequired().GetResource()
+	}
+	if created == nil {
+		return errors.New("creating an account returned no resource")
+	}
+	if created.GetId().GetResourceType() != rt.GetId() {
+		return fmt.Errorf("creating an account returned a %s rather than a %s", created.GetId().GetResourceType(), rt.GetId())
+	}
+
+	listed, err := s.findResource(ctx, created.GetId())
+	if err != nil {
+		return fmt.Errorf("listing resources: %w", err)
+	}
+	if listed == nil {
+		return fmt.Errorf("created account %s is not listed", created.GetId().GetResource())
+	}
+
+	return s.checkCredentials(ctx, created.GetId(), option, key, resp.GetEncryptedData())
+}
+
+// checkRotate rotates the credentials of a resource with a credential option and checks that it was given a
+// credential only if one was asked for.
+func (s *session) checkRotate(ctx context.Context, rt *v2.ResourceType, option v2.CapabilityDetailCredentialOption) error {
+	rs, err := s.resourcesOfType(ctx, rt.GetId())
+	if err != nil {
+		return fmt.Errorf("listing resources: %w", err)
+	}
+	if len(rs) == 0 {
+		return errors.New("credential rotation is advertised but there is no resource to rotate the credentials of")
+	}
+	target := rs[0].GetId()
+
+	opts, err := credentialOptions(option)
+	if err != nil {
+		return err
+	}
+
+	key, err := newKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.srv.RotateCredential(ctx, &v2.RotateCredentialRequest{
+		ResourceId:        target,
+		CredentialOptions: opts,
+		EncryptionConfigs: []*v2.EncryptionConfig{key.config},
+	})
+	if err != nil {
+		return fmt.Errorf("rotating the credentials of %s: %w", target.GetResource(), err)
+	}
+
+	return s.checkCredentials(ctx, target, option, key, resp.GetEncryptedData())
+}
+
+// checkCredentials checks the credentials returned for a resource against the credential option that was asked for.
+// Generated credentials must decrypt with the key they were encrypted for, and work if the instance can verify them.
+func (s *session) checkCredentials(
+	ctx context.Context,
+	resourceID *v2.ResourceId,
+	option v2.CapabilityDetailCredentialOption,
+	key *key,
+	encrypted []*v2.EncryptedData,
+) error {
+	if option != v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD {
+		if len(encrypted) != 0 {
+			return fmt.Errorf("%d credentials were returned although none was asked for", len(encrypted))
+		}
+		return nil
+	}
+
+	if len(encrypted) == 0 {
+		return errors.New("no credential was returned although a random one was asked for")
+	}
+
+	for _, ed := range encrypted {
+		plaintext, err := key.provider.Decrypt(ctx, ed, key.private)
+		if err != nil {
+			return fmt.Errorf("decrypting credential %q: %w", ed.GetName(), err)
+		}
+		if len(plaintext.GetBytes()) == 0 {
+			return fmt.Errorf("credential %q is empty", ed.GetName())
+		}
+
+		if s.inst.VerifyCredential != nil {
+			err = s.inst.VerifyCredential(ctx, resourceID, plaintext)
+			if err != nil {
+				return fmt.Errorf("credential %q doesn't work: %w", ed.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkCreate creates a resource and checks that it is listed.
+func (s *session) checkCreate(ctx context.Context, rt *v2.ResourceType) error {
+	created, err := s.create(ctx, rt)
+	if err != nil {
+		return err
+	}
+
+	listed, err := s.findResource(ctx, created.GetId())
+	if err != nil {
+		return fmt.Errorf("listing resources: %w", err)
+	}
+	if listed == nil {
+		return fmt.Errorf("created resource %s is not listed", created.GetId().GetResource())
+	}
+
+	return nil
+}
+
+// checkDelete deletes a resource and checks that it is no longer listed. The resource is created first if the
+// resource type advertises creation, so that deleting it doesn't depend on the data the instance starts with.
+func (s *session) checkDelete(ctx context.Context, rt *v2.ResourceType, capabilities []v2.Capability) error {
+	var target *v2.Resource
+	for _, c := range capabilities {
+		if c == v2.Capability_CAPABILITY_RESOURCE_CREATE {
+			created, err := s.create(ctx, rt)
+			if err != nil {
+				return err
+			}
+			target = created
+		}
+	}
+
+	if target == nil {
+		rs, err := s.resourcesOfType(ctx, rt.GetId())
+		if err != nil {
+			return fmt.Errorf("listing resources: %w", err)
+		}
+		if len(rs) == 0 {
+			return errors.New("deletion is advertised but there is no resource to delete")
+		}
+		target = rs[0]
+	}
+
+	_, err := s.srv.DeleteResource(ctx, &v2.DeleteResourceRequest{ResourceId: target.GetId()})
+	if err != nil {
+		return fmt.Errorf("deleting %s: %w", target.GetId().GetResource(), err)
+	}
+
+	listed, err := s.findResource(ctx, target.GetId())
+	if err != nil {
+		return fmt.Errorf("listing resources: %w", err)
+	}
+	if listed != nil {
+		return fmt.Errorf("deleted resource %s is still listed", target.GetId().GetResource())
+	}
+
+	return nil
+}
+
+func (s *session) create(ctx context.Context, rt *v2.ResourceType) (*v2.Resource, error) {
+	if s.cfg.NewResource == nil {
+		return nil, errors.New("resource creation is advertised but the config has no NewResource function")
+	}
+
+	resp, err := s.srv.CreateResource(ctx, &v2.CreateResourceRequest{Resource: s.cfg.NewResource(rt, next())})
+	if err != nil {
+		return nil, fmt.Errorf("creating a resource: %w", err)
+	}
+
+	created := resp.GetCreated()
+	if created == nil {
+		return nil, errors.New("creating a resource returned no resource")
+	}
+	if created.GetId().GetResourceType() != rt.GetId() {
+		return nil, fmt.Errorf("creating a resource returned a %s rather than a %s", created.GetId().GetResourceType(), rt.GetId())
+	}
+
+	return created, nil
+}
+
+// credentialOptions returns the options asking for a credential option.
+func credentialOptions(option v2.CapabilityDetailCredentialOption) (*v2.CredentialOptions, error) {
+	switch option {
+	case v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD:
+		return &v2.CredentialOptions{Options: &v2.CredentialOptions_RandomPassword_{
+			RandomPassword: &v2.CredentialOptions_RandomPassword{Length: passwordLength},
+		}}, nil
+	case v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD:
+		return &v2.CredentialOptions{Options: &v2.CredentialOptions_NoPassword_{
+			NoPassword: &v2.CredentialOptions_NoPassword{},
+		}}, nil
+	case v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_SSO:
+		return &v2.CredentialOptions{Options: &v2.CredentialOptions_Sso{
+			Sso: &v2.CredentialOptions_SSO{SsoProvider: "conformance"},
+		}}, nil
+	default:
+		return nil, fmt.Errorf("credential option %s is not supported by the conformance checks", option)
+	}
+}
+
+// key is a key pair generated credentials are encrypted for.
+type key struct {
+	provider *jwk.JWKEncryptionProvider
+	config   *v2.EncryptionConfig
+	private  []byte
+}
+
+func newKey(ctx context.Context) (*key, error) {
+	provider := &jwk.JWKEncryptionProvider{}
+	config, private, err := provider.GenerateKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("generating an encryption key: %w", err)
+	}
+
+	return &key{provider: provider, config: config, private: private}, nil
+}
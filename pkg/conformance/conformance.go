@@ -0,0 +1,251 @@
+// Package conformance checks that a connector implements every capability it advertises. It is written against the
+// connector server built by the SDK, so it can be run against any connector, not only the demo.
+//
+// Every advertised capability of every resource type is exercised on a fresh instance of the connector: entitlements
+// are granted and revoked, accounts are created with each supported credential option, credentials are rotated, and
+// resources are created and deleted. Each change is checked by reading it back through the connector, the way a sync
+// would.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Instance is a connector under test, over data of its own that the checks are free to change.
+type Instance struct {
+	Server types.ConnectorServer
+	// VerifyCredential optionally checks that a credential the connector returned for a resource works, for example by
+	// logging in with it.
+	VerifyCredential func(ctx context.Context, resourceID *v2.ResourceId, credential *v2.PlaintextData) error
+	// Close releases the instance and its data. It may be nil.
+	Close func() error
+}
+
+// Config describes the connector under test and what it advertises.
+type Config struct {
+	// Capabilities are the capabilities the connector advertises, usually read from its baton_capabilities.json with
+	// LoadCapabilities.
+	Capabilities *v2.ConnectorCapabilities
+	// New returns a fresh instance of the connector. Each check runs on its own instance, so checks never see each
+	// other's changes.
+	New func(ctx context.Context) (*Instance, error)
+	// AccountInfo returns the account to create, n being unique within a run. It is required when a resource type
+	// advertises CAPABILITY_ACCOUNT_PROVISIONING.
+	AccountInfo func(n int) *v2.AccountInfo
+	// NewResource returns a resource of the given type to create, n being unique within a run. It is required when a
+	// resource type advertises CAPABILITY_RESOURCE_CREATE.
+	NewResource func(resourceType *v2.ResourceType, n int) *v2.Resource
+	// CredentialRotation overrides the credential rotation details of a resource type. The capabilities only hold one
+	// set of details for the whole connector, which may not apply to every resource type that can be rotated.
+	CredentialRotation map[string]*v2.CredentialDetailsCredentialRotation
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	// Name identifies the check, such as "group/provision" or "user/account_provisioning/random_password".
+	Name         string
+	ResourceType string
+	Capability   v2.Capability
+	// Skipped is why the check was not run, if it wasn't.
+	Skipped string
+	Err     error
+}
+
+// Passed reports whether the check ran and succeeded.
+func (r *Result) Passed() bool {
+	return r.Skipped == "" && r.Err == nil
+}
+
+// LoadCapabilities reads the capabilities a connector advertises from a file written by its capabilities command.
+func LoadCapabilities(path string) (*v2.ConnectorCapabilities, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &anypb.Any{}
+	err = protojson.Unmarshal(b, a)
+	if err != nil {
+		return nil, fmt.Errorf("parsing capabilities %s: %w", path, err)
+	}
+
+	caps := &v2.ConnectorCapabilities{}
+	err = a.UnmarshalTo(caps)
+	if err != nil {
+		return nil, fmt.Errorf("parsing capabilities %s: %w", path, err)
+	}
+
+	return caps, nil
+}
+
+// Run runs every check and returns their results, in the order the capabilities list them. It only returns an error
+// if the config is unusable.
+func Run(ctx context.Context, cfg Config) ([]*Result, error) {
+	checks, err := cfg.checks()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*Result, 0, len(checks))
+	for _, c := range checks {
+		ret = append(ret, c.run(ctx, cfg))
+	}
+
+	return ret, nil
+}
+
+// check exercises one capability of one resource type.
+type check struct {
+	name         string
+	resourceType *v2.ResourceType
+	capability   v2.Capability
+	skipped      string
+	fn           func(ctx context.Context, s *session) error
+}
+
+func (c *check) run(ctx context.Context, cfg Config) (result *Result) {
+	result = &Result{
+		Name:         c.name,
+		ResourceType: c.resourceType.GetId(),
+		Capability:   c.capability,
+		Skipped:      c.skipped,
+	}
+	if c.skipped != "" {
+		return result
+	}
+
+	inst, err := cfg.New(ctx)
+	if err != nil {
+		result.Err = fmt.Errorf("creating an instance of the connector: %w", err)
+		return result
+	}
+
+	defer func() {
+		// A connector that panics fails the check rather than the whole run
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("panic: %v", r)
+		}
+
+		if inst.Close != nil {
+			err := inst.Close()
+			if err != nil && result.Err == nil {
+				result.Err = fmt.Errorf("closing the instance of the connector: %w", err)
+			}
+		}
+	}()
+
+	result.Err = c.fn(ctx, &session{cfg: cfg, inst: inst, srv: inst.Server})
+
+	return result
+}
+
+// counter makes the accounts and resources created within a process unique.
+var counter atomic.Int64
+
+func next() int {
+	return int(counter.Add(1))
+}
+
+// checks returns a check for every capability of every resource type in the capabilities.
+func (cfg Config) checks() ([]*check, error) {
+	if cfg.Capabilities == nil {
+		return nil, errors.New("conformance config has no capabilities")
+	}
+	if cfg.New == nil {
+		return nil, errors.New("conformance config has no New function")
+	}
+
+	var ret []*check
+	for _, rtc := range cfg.Capabilities.GetResourceTypeCapabilities() {
+		rt := rtc.GetResourceType()
+		for _, capability := range rtc.GetCapabilities() {
+			name := rt.GetId() + "/" + capabilityName(capability)
+			c := &check{name: name, resourceType: rt, capability: capability}
+
+			switch capability {
+			case v2.Capability_CAPABILITY_SYNC:
+				c.fn = func(ctx context.Context, s *session) error { return s.checkSync(ctx, rt) }
+				ret = append(ret, c)
+
+			case v2.Capability_CAPABILITY_PROVISION:
+				c.fn = func(ctx context.Context, s *session) error { return s.checkProvision(ctx, rt) }
+				ret = append(ret, c)
+
+			case v2.Capability_CAPABILITY_ACCOUNT_PROVISIONING:
+				options := cfg.Capabilities.GetCredentialDetails().GetCapabilityAccountProvisioning().GetSupportedCredentialOptions()
+				if len(options) == 0 {
+					c.fn = func(ctx context.Context, s *session) error {
+						return errors.New("account provisioning is advertised without any supported credential options")
+					}
+					ret = append(ret, c)
+				}
+				for _, option := range options {
+					option := option
+					ret = append(ret, &check{
+						name:         name + "/" + optionName(option),
+						resourceType: rt,
+						capability:   capability,
+						fn:           func(ctx context.Context, s *session) error { return s.checkCreateAccount(ctx, rt, option) },
+					})
+				}
+
+			case v2.Capability_CAPABILITY_CREDENTIAL_ROTATION:
+				details := cfg.Capabilities.GetCredentialDetails().GetCapabilityCredentialRotation()
+				if override, ok := cfg.CredentialRotation[rt.GetId()]; ok {
+					details = override
+				}
+				options := details.GetSupportedCredentialOptions()
+				if len(options) == 0 {
+					c.fn = func(ctx context.Context, s *session) error {
+						return errors.New("credential rotation is advertised without any supported credential options")
+					}
+					ret = append(ret, c)
+				}
+				for _, option := range options {
+					option := option
+					ret = append(ret, &check{
+						name:         name + "/" + optionName(option),
+						resourceType: rt,
+						capability:   capability,
+						fn:           func(ctx context.Context, s *session) error { return s.checkRotate(ctx, rt, option) },
+					})
+				}
+
+			case v2.Capability_CAPABILITY_RESOURCE_CREATE:
+				c.fn = func(ctx context.Context, s *session) error { return s.checkCreate(ctx, rt) }
+				ret = append(ret, c)
+
+			case v2.Capability_CAPABILITY_RESOURCE_DELETE:
+				c.fn = func(ctx context.Context, s *session) error { return s.checkDelete(ctx, rt, rtc.GetCapabilities()) }
+				ret = append(ret, c)
+
+			default:
+				c.skipped = fmt.Sprintf("%s is not checked", capability)
+				ret = append(ret, c)
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// capabilityName returns the short name of a capability used in check names, "provision" for CAPABILITY_PROVISION.
+func capabilityName(c v2.Capability) string {
+	return strings.ToLower(strings.TrimPrefix(c.String(), "CAPABILITY_"))
+}
+
+// optionName returns the short name of a credential option used in check names, "random_password" for
+// CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD.
+func optionName(o v2.CapabilityDetailCredentialOption) string {
+	return strings.ToLower(strings.TrimPrefix(o.String(), "CAPABILITY_DETAIL_CREDENTIAL_OPTION_"))
+}
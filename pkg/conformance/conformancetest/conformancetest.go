@@ -0,0 +1,31 @@
+// Package conformancetest runs the conformance checks of package conformance as Go tests, keeping the testing package
+// out of the binaries that run them otherwise.
+package conformancetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-demo/pkg/conformance"
+)
+
+// Test runs every check and reports each as a subtest of t, named after the check.
+func Test(t *testing.T, cfg conformance.Config) {
+	t.Helper()
+
+	results, err := conformance.Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range results {
+		t.Run(result.Name, func(t *testing.T) {
+			if result.Skipped != "" {
+				t.Skip(result.Skipped)
+			}
+			if result.Err != nil {
+				t.Error(result.Err)
+			}
+		})
+	}
+}
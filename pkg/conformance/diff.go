@@ -0,0 +1,111 @@
+package conformance
+
+import (
+	"fmt"
+	"sort"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Diff describes how the capabilities a connector actually has differ from those it advertises, one line per
+// difference. It returns nothing if they are the same.
+func Diff(advertised, actual *v2.ConnectorCapabilities) []string {
+	var ret []string
+
+	for _, c := range missing(advertised.GetConnectorCapabilities(), actual.GetConnectorCapabilities()) {
+		ret = append(ret, fmt.Sprintf("connector: advertises %s but doesn't have it", c))
+	}
+	for _, c := range missing(actual.GetConnectorCapabilities(), advertised.GetConnectorCapabilities()) {
+		ret = append(ret, fmt.Sprintf("connector: has %s but doesn't advertise it", c))
+	}
+
+	advertisedTypes := resourceTypeCapabilities(advertised)
+	actualTypes := resourceTypeCapabilities(actual)
+
+	ids := make([]string, 0, len(advertisedTypes)+len(actualTypes))
+	for id := range advertisedTypes {
+		ids = append(ids, id)
+	}
+	for id := range actualTypes {
+		if _, ok := advertisedTypes[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		want, got := advertisedTypes[id], actualTypes[id]
+		switch {
+		case got == nil:
+			ret = append(ret, fmt.Sprintf("%s: advertised but the connector has no such resource type", id))
+			continue
+		case want == nil:
+			ret = append(ret, fmt.Sprintf("%s: the connector has this resource type but doesn't advertise it", id))
+			continue
+		}
+
+		if !proto.Equal(want.GetResourceType(), got.GetResourceType()) {
+			ret = append(ret, fmt.Sprintf("%s: advertises resource type %s but has %s",
+				id, compact(want.GetResourceType()), compact(got.GetResourceType())))
+		}
+		for _, c := range missing(want.GetCapabilities(), got.GetCapabilities()) {
+			ret = append(ret, fmt.Sprintf("%s: advertises %s but doesn't have it", id, c))
+		}
+		for _, c := range missing(got.GetCapabilities(), want.GetCapabilities()) {
+			ret = append(ret, fmt.Sprintf("%s: has %s but doesn't advertise it", id, c))
+		}
+	}
+
+	wantAccounts := advertised.GetCredentialDetails().GetCapabilityAccountProvisioning()
+	gotAccounts := actual.GetCredentialDetails().GetCapabilityAccountProvisioning()
+	if !proto.Equal(wantAccounts, gotAccounts) {
+		ret = append(ret, fmt.Sprintf("credential details: advertises account provisioning %s but has %s",
+			compact(wantAccounts), compact(gotAccounts)))
+	}
+
+	wantRotation := advertised.GetCredentialDetails().GetCapabilityCredentialRotation()
+	gotRotation := actual.GetCredentialDetails().GetCapabilityCredentialRotation()
+	if !proto.Equal(wantRotation, gotRotation) {
+		ret = append(ret, fmt.Sprintf("credential details: advertises credential rotation %s but has %s",
+			compact(wantRotation), compact(gotRotation)))
+	}
+
+	return ret
+}
+
+func resourceTypeCapabilities(caps *v2.ConnectorCapabilities) map[string]*v2.ResourceTypeCapability {
+	ret := make(map[string]*v2.ResourceTypeCapability)
+	for _, rtc := range caps.GetResourceTypeCapabilities() {
+		ret[rtc.GetResourceType().GetId()] = rtc
+	}
+	return ret
+}
+
+// missing returns the capabilities in a that aren't in b.
+func missing(a, b []v2.Capability) []v2.Capability {
+	in := make(map[v2.Capability]bool, len(b))
+	for _, c := range b {
+		in[c] = true
+	}
+
+	var ret []v2.Capability
+	for _, c := range a {
+		if !in[c] {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func compact(m proto.Message) string {
+	if m == nil {
+		return "{}"
+	}
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return fmt.Sprintf("%v", m)
+	}
+	return string(b)
+}
@@ -0,0 +1,193 @@
+package conformance
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types"
+)
+
+// session is a check running against an instance of the connector.
+type session struct {
+	cfg  Config
+	inst *Instance
+	srv  types.ConnectorServer
+}
+
+// resourceTypes returns every resource type the connector syncs.
+func (s *session) resourceTypes(ctx context.Context) ([]*v2.ResourceType, error) {
+	var ret []*v2.ResourceType
+	pageToken := ""
+	for {
+		resp, err := s.srv.ListResourceTypes(ctx, &v2.ResourceTypesServiceListResourceTypesRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, resp.GetList()...)
+
+		pageToken = resp.GetNextPageToken()
+		if pageToken == "" {
+			return ret, nil
+		}
+	}
+}
+
+// resources returns every resource of the connector, walking down from the top level resources to their children
+// the way a sync does.
+func (s *session) resources(ctx context.Context) ([]*v2.Resource, error) {
+	resourceTypes, err := s.resourceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*v2.Resource
+	for _, rt := range resourceTypes {
+		rs, err := s.listResources(ctx, rt.GetId(), nil)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, rs...)
+	}
+
+	// Children are appended as they are found, so that their own children are walked too
+	for i := 0; i < len(ret); i++ {
+		for _, a := range ret[i].GetAnnotations() {
+			child := &v2.ChildResourceType{}
+			if !a.MessageIs(child) {
+				continue
+			}
+			err := a.UnmarshalTo(child)
+			if err != nil {
+				return nil, err
+			}
+
+			rs, err := s.listResources(ctx, child.GetResourceTypeId(), ret[i].GetId())
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, rs...)
+		}
+	}
+
+	return dedupe(ret), nil
+}
+
+// resourcesOfType returns every resource of a resource type.
+func (s *session) resourcesOfType(ctx context.Context, resourceTypeID string) ([]*v2.Resource, error) {
+	all, err := s.resources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*v2.Resource
+	for _, r := range all {
+		if r.GetId().GetResourceType() == resourceTypeID {
+			ret = append(ret, r)
+		}
+	}
+	return ret, nil
+}
+
+func (s *session) listResources(ctx context.Context, resourceTypeID string, parent *v2.ResourceId) ([]*v2.Resource, error) {
+	var ret []*v2.Resource
+	pageToken := ""
+	for {
+		resp, err := s.srv.ListResources(ctx, &v2.ResourcesServiceListResourcesRequest{
+			ResourceTypeId:   resourceTypeID,
+			ParentResourceId: parent,
+			PageToken:        pageToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, resp.GetList()...)
+
+		pageToken = resp.GetNextPageToken()
+		if pageToken == "" {
+			return ret, nil
+		}
+	}
+}
+
+func (s *session) entitlements(ctx context.Context, r *v2.Resource) ([]*v2.Entitlement, error) {
+	var ret []*v2.Entitlement
+	pageToken := ""
+	for {
+		resp, err := s.srv.ListEntitlements(ctx, &v2.EntitlementsServiceListEntitlementsRequest{Resource: r, PageToken: pageToken})
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, resp.GetList()...)
+
+		pageToken = resp.GetNextPageToken()
+		if pageToken == "" {
+			return ret, nil
+		}
+	}
+}
+
+func (s *session) grants(ctx context.Context, r *v2.Resource) ([]*v2.Grant, error) {
+	var ret []*v2.Grant
+	pageToken := ""
+	for {
+		resp, err := s.srv.ListGrants(ctx, &v2.GrantsServiceListGrantsRequest{Resource: r, PageToken: pageToken})
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, resp.GetList()...)
+
+		pageToken = resp.GetNextPageToken()
+		if pageToken == "" {
+			return ret, nil
+		}
+	}
+}
+
+// findGrant returns the grant of an entitlement to a principal, or nil if there is none.
+func (s *session) findGrant(ctx context.Context, e *v2.Entitlement, principal *v2.ResourceId) (*v2.Grant, error) {
+	grants, err := s.grants(ctx, e.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range grants {
+		if g.GetEntitlement().GetId() == e.GetId() && sameResource(g.GetPrincipal().GetId(), principal) {
+			return g, nil
+		}
+	}
+	return nil, nil
+}
+
+// findResource returns the resource with the given ID, or nil if the connector doesn't list it.
+func (s *session) findResource(ctx context.Context, id *v2.ResourceId) (*v2.Resource, error) {
+	rs, err := s.resourcesOfType(ctx, id.GetResourceType())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rs {
+		if sameResource(r.GetId(), id) {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func sameResource(a, b *v2.ResourceId) bool {
+	return a.GetResourceType() == b.GetResourceType() && a.GetResource() == b.GetResource()
+}
+
+// dedupe drops resources listed more than once, such as a child that is also listed at the top level.
+func dedupe(rs []*v2.Resource) []*v2.Resource {
+	seen := make(map[string]bool, len(rs))
+	ret := rs[:0]
+	for _, r := range rs {
+		key := r.GetId().GetResourceType() + ":" + r.GetId().GetResource()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ret = append(ret, r)
+	}
+	return ret
+}
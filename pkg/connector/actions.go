@@ -0,0 +1,274 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ActionArgumentType is the type of value an action argument accepts.
+type ActionArgumentType string
+
+const (
+	ActionArgumentString ActionArgumentType = "string"
+	ActionArgumentBool   ActionArgumentType = "bool"
+	ActionArgumentInt    ActionArgumentType = "int"
+)
+
+// ActionArgument describes a single argument of an action.
+type ActionArgument struct {
+	Name        string             `json:"name"`
+	Type        ActionArgumentType `json:"type"`
+	Description string             `json:"description"`
+	Required    bool               `json:"required"`
+}
+
+// ActionSchema describes an action and the arguments it accepts.
+type ActionSchema struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Arguments   []ActionArgument `json:"arguments"`
+}
+
+// ActionArgs holds the arguments of an action invocation, converted to the types declared by the action's schema.
+// Optional arguments that were not given hold the zero value of their type.
+type ActionArgs map[string]interface{}
+
+func (a ActionArgs) String(name string) string {
+	v, _ := a[name].(string)
+	return v
+}
+
+func (a ActionArgs) Bool(name string) bool {
+	v, _ := a[name].(bool)
+	return v
+}
+
+func (a ActionArgs) Int(name string) int {
+	v, _ := a[name].(int)
+	return v
+}
+
+// ActionResult is the outcome of an action, keyed by field name.
+type ActionResult map[string]interface{}
+
+type actionHandler func(ctx context.Context, args ActionArgs) (ActionResult, error)
+
+type action struct {
+	schema  *ActionSchema
+	handler actionHandler
+}
+
+var userIDArgument = ActionArgument{
+	Name:        "user_id",
+	Type:        ActionArgumentString,
+	Description: "The ID of the user to act on",
+	Required:    true,
+}
+
+// actions returns every action the connector can perform, keyed by name.
+func (d *Demo) actions() map[string]*action {
+	return map[string]*action{
+		"unlock_account": {
+			schema: &ActionSchema{
+				Name:        "unlock_account",
+				Description: "Unlock a user's account after too many failed logins and reset the failed login counter",
+				Arguments:   []ActionArgument{userIDArgument},
+			},
+			handler: d.unlockAccount,
+		},
+		"force_password_reset": {
+			schema: &ActionSchema{
+				Name:        "force_password_reset",
+				Description: "Require a user to choose a new password the next time they log in",
+				Arguments: []ActionArgument{
+					userIDArgument,
+					{
+						Name:        "revoke_sessions",
+						Type:        ActionArgumentBool,
+						Description: "Also revoke the user's sessions so that they have to log in again right away",
+					},
+				},
+			},
+			handler: d.forcePasswordReset,
+		},
+		"revoke_sessions": {
+			schema: &ActionSchema{
+				Name:        "revoke_sessions",
+				Description: "Revoke every active session of a user",
+				Arguments:   []ActionArgument{userIDArgument},
+			},
+			handler: d.revokeSessions,
+		},
+		"reset_mfa": {
+			schema: &ActionSchema{
+				Name:        "reset_mfa",
+				Description: "Remove a user's MFA enrollment so that they have to enroll again",
+				Arguments:   []ActionArgument{userIDArgument},
+			},
+			handler: d.resetMFA,
+		},
+	}
+}
+
+// ListActions returns the schema of every action the connector can perform, ordered by name.
+func (d *Demo) ListActions() []*ActionSchema {
+	var ret []*ActionSchema
+	for _, a := range d.actions() {
+		ret = append(ret, a.schema)
+	}
+	slices.SortFunc(ret, func(a, b *ActionSchema) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return ret
+}
+
+// InvokeAction performs the named action. The raw arguments are checked and converted against the action's schema
+// before the action runs.
+func (d *Demo) InvokeAction(ctx context.Context, name string, rawArgs map[string]string) (ActionResult, error) {
+	a, ok := d.actions()[name]
+	if !ok {
+		return nil, fmt.Errorf("baton-demo: unknown action %q", name)
+	}
+
+	args, err := parseActionArgs(a.schema, rawArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.handler(ctx, args)
+}
+
+// parseActionArgs converts the raw arguments to the types declared by the schema, rejecting unknown arguments and
+// missing required ones.
+func parseActionArgs(schema *ActionSchema, rawArgs map[string]string) (ActionArgs, error) {
+	for name := range rawArgs {
+		if !slices.ContainsFunc(schema.Arguments, func(arg ActionArgument) bool {
+			return arg.Name == name
+		}) {
+			return nil, fmt.Errorf("baton-demo: action %s has no argument %q", schema.Name, name)
+		}
+	}
+
+	args := ActionArgs{}
+	for _, arg := range schema.Arguments {
+		raw, ok := rawArgs[arg.Name]
+		if !ok || raw == "" {
+			if arg.Required {
+				return nil, fmt.Errorf("baton-demo: action %s requires argument %q", schema.Name, arg.Name)
+			}
+			continue
+		}
+
+		switch arg.Type {
+		case ActionArgumentString:
+			args[arg.Name] = raw
+		case ActionArgumentBool:
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("baton-demo: argument %q of action %s must be a bool: %w", arg.Name, schema.Name, err)
+			}
+			args[arg.Name] = v
+		case ActionArgumentInt:
+			v, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, fmt.Errorf("baton-demo: argument %q of action %s must be an int: %w", arg.Name, schema.Name, err)
+			}
+			args[arg.Name] = v
+		default:
+			return nil, fmt.Errorf("baton-demo: argument %q of action %s has unknown type %q", arg.Name, schema.Name, arg.Type)
+		}
+	}
+
+	return args, nil
+}
+
+func (d *Demo) unlockAccount(ctx context.Context, args ActionArgs) (ActionResult, error) {
+	userID := args.String("user_id")
+
+	err := d.client.UnlockAccount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.accountStateResult(ctx, userID)
+}
+
+func (d *Demo) forcePasswordReset(ctx context.Context, args ActionArgs) (ActionResult, error) {
+	userID := args.String("user_id")
+
+	err := d.client.RequirePasswordReset(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := 0
+	if args.Bool("revoke_sessions") {
+		revoked, err = d.client.RevokeSessions(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ret, err := d.accountStateResult(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ret["revoked_sessions"] = revoked
+
+	return ret, nil
+}
+
+func (d *Demo) revokeSessions(ctx context.Context, args ActionArgs) (ActionResult, error) {
+	userID := args.String("user_id")
+
+	revoked, err := d.client.RevokeSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := d.accountStateResult(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ret["revoked_sessions"] = revoked
+
+	return ret, nil
+}
+
+func (d *Demo) resetMFA(ctx context.Context, args ActionArgs) (ActionResult, error) {
+	userID := args.String("user_id")
+
+	err := d.client.ResetMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.accountStateResult(ctx, userID)
+}
+
+// accountStateResult reports the state of the user's account after an action.
+func (d *Demo) accountStateResult(ctx context.Context, userID string) (ActionResult, error) {
+	state, err := d.client.GetAccountState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := ActionResult{
+		"user_id":                 state.UserID,
+		"failed_logins":           state.FailedLogins,
+		"locked":                  state.Locked(),
+		"password_reset_required": state.PasswordResetRequired,
+		"mfa_enrolled":            state.MFAEnrolled,
+		"active_sessions":         state.ActiveSessions,
+	}
+	if state.LockedAt != nil {
+		ret["locked_at"] = state.LockedAt.Format(time.RFC3339)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,152 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type apiKeyBuilder struct {
+	client   client.Backend
+	pageSize int
+	changes  changeSet
+	console  consoleURL
+}
+
+func (o *apiKeyBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return apiKeyResourceType
+}
+
+// List returns the API keys of a service account as resource objects. API keys only exist as children of service
+// accounts, so nothing is returned without a parent.
+// API keys don't match the 'shape' of any well known types, so the key's timestamps are attached as a profile
+// annotation, and summarized in its description.
+// Given a sync token, only the API keys that changed since the sync that reported it are returned.
+func (o *apiKeyBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != serviceAccountResourceType.Id {
+		return nil, "", nil, nil
+	}
+
+	keys, nextPageToken, err := o.client.ListAPIKeys(ctx, parentResourceID.Resource, pageOptions(pToken, o.pageSize))
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	var ret []*v2.Resource
+	for _, k := range keys {
+		if !o.changes.changed(client.ResourceTypeAPIKey, k.Id) {
+			continue
+		}
+
+		profile, err := apiKeyProfile(k)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		key, err := sdkResource.NewResource(k.Name, apiKeyResourceType, k.Id,
+			sdkResource.WithParentResourceID(parentResourceID),
+			sdkResource.WithDescription(apiKeyDescription(k)),
+			sdkResource.WithAnnotation(profile),
+			o.console.link(apiKeyResourceType, k.Id),
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		ret = append(ret, key)
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+// Entitlements always returns an empty slice for API keys.
+func (o *apiKeyBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for API keys since they don't have any entitlements.
+func (o *apiKeyBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// RotateCapabilityDetails advertises random secrets only, API key secrets are always generated by the service.
+func (o *apiKeyBuilder) RotateCapabilityDetails(ctx context.Context) (*v2.CredentialDetailsCredentialRotation, annotations.Annotations, error) {
+	return credentialRotationDetails(), nil, nil
+}
+
+// Rotate replaces the secret of an API key and returns the new secret.
+func (o *apiKeyBuilder) Rotate(ctx context.Context, resourceId *v2.ResourceId, credentialOptions *v2.CredentialOptions) ([]*v2.PlaintextData, annotations.Annotations, error) {
+	if resourceId.ResourceType != apiKeyResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-demo: non-api-key resource passed to rotate credentials")
+	}
+
+	if credentialOptions.GetRandomPassword() == nil {
+		return nil, nil, fmt.Errorf("baton-demo: api keys can only be rotated to a random secret")
+	}
+
+	_, secret, err := o.client.RotateAPIKey(ctx, resourceId.Resource)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, annos, err
+	}
+
+	return []*v2.PlaintextData{
+		{
+			Name:        "api_key",
+			Description: "The new secret of the API key",
+			Bytes:       []byte(secret),
+		},
+	}, nil, nil
+}
+
+// apiKeyProfile holds the timestamps of an API key as RFC 3339 strings: created_at, and expires_at and last_used_at
+// unless the key never expires or was never used.
+func apiKeyProfile(key *client.APIKey) (*structpb.Struct, error) {
+	profile := map[string]interface{}{
+		"created_at": key.CreatedAt.Format(time.RFC3339),
+	}
+	if key.ExpiresAt != nil {
+		profile["expires_at"] = key.ExpiresAt.Format(time.RFC3339)
+	}
+	if key.LastUsedAt != nil {
+		profile["last_used_at"] = key.LastUsedAt.Format(time.RFC3339)
+	}
+
+	return structpb.NewStruct(profile)
+}
+
+// apiKeyDescription describes when an API key was created, when it expires and when it was last used.
+func apiKeyDescription(key *client.APIKey) string {
+	parts := []string{"Created " + key.CreatedAt.Format(time.RFC3339)}
+
+	if key.ExpiresAt != nil {
+		parts = append(parts, "expires "+key.ExpiresAt.Format(time.RFC3339))
+	} else {
+		parts = append(parts, "never expires")
+	}
+
+	if key.LastUsedAt != nil {
+		parts = append(parts, "last used "+key.LastUsedAt.Format(time.RFC3339))
+	} else {
+		parts = append(parts, "never used")
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func newAPIKeyBuilder(client client.Backend, pageSize int, changes changeSet, console consoleURL) *apiKeyBuilder {
+	return &apiKeyBuilder{
+		client:   client,
+		pageSize: pageSize,
+		changes:  changes,
+		console:  console,
+	}
+}
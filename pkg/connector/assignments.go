@@ -0,0 +1,125 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	sdkGrant "github.com/conductorone/baton-sdk/pkg/types/grant"
+)
+
+// Grant metadata keys. Grants carry the provenance and the expiry of their assignments under these keys, and the
+// same keys can be set on a grant request to record them.
+const (
+	// grantDurationKey makes a new grant time-bound. Its value is a duration such as "72h", counted from the time of
+	// the grant. It is only read from grant requests.
+	grantDurationKey = "duration"
+	// grantExpiresAtKey holds the time a grant expires at, formatted as RFC 3339.
+	grantExpiresAtKey = "expires_at"
+	grantedByKey      = "granted_by"
+	grantedAtKey      = "granted_at"
+	grantReasonKey    = "reason"
+	grantSourceKey    = "source"
+)
+
+// assignments indexes the assignments of a resource by principal.
+type assignments map[string]*client.Assignment
+
+func listAssignments(ctx context.Context, b client.Backend, resourceType, resourceID string) (assignments, error) {
+	list, err := b.ListAssignments(ctx, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(assignments, len(list))
+	for _, a := range list {
+		ret[a.PrincipalType+":"+a.PrincipalID] = a
+	}
+
+	return ret, nil
+}
+
+// grantOptions returns the options of the grant to a principal, which carry the metadata of its assignment.
+func (a assignments) grantOptions(principalType, principalID string) []sdkGrant.GrantOption {
+	assignment, ok := a[principalType+":"+principalID]
+	if !ok {
+		return nil
+	}
+
+	metadata := map[string]interface{}{
+		grantedAtKey:   assignment.GrantedAt.UTC().Format(time.RFC3339),
+		grantSourceKey: string(assignment.Source),
+	}
+	if assignment.GrantedBy != "" {
+		metadata[grantedByKey] = assignment.GrantedBy
+	}
+	if assignment.Reason != "" {
+		metadata[grantReasonKey] = assignment.Reason
+	}
+	if assignment.ExpiresAt != nil {
+		metadata[grantExpiresAtKey] = assignment.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	return []sdkGrant.GrantOption{sdkGrant.WithGrantMetadata(metadata)}
+}
+
+// grantRequestMetadata returns the grant metadata of a grant request, read from the grant metadata annotations of the
+// entitlement and the principal being granted, the entitlement taking precedence.
+func grantRequestMetadata(entitlement *v2.Entitlement, principal *v2.Resource) (map[string]string, error) {
+	fields := map[string]string{}
+	for _, annos := range []annotations.Annotations{principal.GetAnnotations(), entitlement.GetAnnotations()} {
+		md := &v2.GrantMetadata{}
+		ok, err := annos.Pick(md)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		for k, v := range md.GetMetadata().GetFields() {
+			fields[k] = v.GetStringValue()
+		}
+	}
+
+	return fields, nil
+}
+
+// timeBound reports whether grant request metadata asks for the grant to expire.
+func timeBound(metadata map[string]string) bool {
+	_, duration := metadata[grantDurationKey]
+	_, expiresAt := metadata[grantExpiresAtKey]
+	return duration || expiresAt
+}
+
+// assignmentOptions returns the options of an assignment made by provisioning: who asked for it, why, and for how
+// long, as given by the grant request metadata. A grant is time-bound if the metadata holds either a duration or the
+// time the grant expires at.
+func assignmentOptions(metadata map[string]string) ([]client.GrantOption, error) {
+	opts := []client.GrantOption{client.WithSource(client.SourceProvisioning)}
+	if v, ok := metadata[grantedByKey]; ok {
+		opts = append(opts, client.WithGrantedBy(v))
+	}
+	if v, ok := metadata[grantReasonKey]; ok {
+		opts = append(opts, client.WithReason(v))
+	}
+
+	if v, ok := metadata[grantDurationKey]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("baton-demo: invalid grant duration %q", v)
+		}
+		opts = append(opts, client.WithExpiry(time.Now().Add(d)))
+	} else if v, ok := metadata[grantExpiresAtKey]; ok {
+		expiresAt, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("baton-demo: invalid grant expiry %q: %w", v, err)
+		}
+		opts = append(opts, client.WithExpiry(expiresAt))
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,22 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// resourceProfile returns the profile of a resource, which is made up of its custom attributes.
+func resourceProfile(ctx context.Context, b client.Backend, resourceType, resourceID string) (map[string]interface{}, error) {
+	attrs, err := b.GetAttributes(ctx, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := make(map[string]interface{}, len(attrs))
+	for k, v := range attrs {
+		profile[k] = v
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,91 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// changeSet holds the IDs of the resources that changed since a previous sync, keyed by client resource type.
+// A nil changeSet means every resource is synced.
+type changeSet map[string]map[string]bool
+
+// loadChangeSet reads the change log after the cursor in syncToken, returning the changed resources along with the
+// cursor of the last change.
+func loadChangeSet(ctx context.Context, backend client.Backend, syncToken string) (changeSet, int64, error) {
+	since, err := parseSyncToken(syncToken)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changes, cursor, err := backend.ListChanges(ctx, since)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ret := changeSet{}
+	for _, change := range changes {
+		if ret[change.ResourceType] == nil {
+			ret[change.ResourceType] = make(map[string]bool)
+		}
+		ret[change.ResourceType][change.ResourceID] = true
+	}
+
+	return ret, cursor, nil
+}
+
+// parseSyncToken returns the change log cursor held by a sync token. An empty token is the start of the change log.
+func parseSyncToken(syncToken string) (int64, error) {
+	if syncToken == "" {
+		return 0, nil
+	}
+
+	since, err := strconv.ParseInt(syncToken, 10, 64)
+	if err != nil || since < 0 {
+		return 0, fmt.Errorf("baton-demo: invalid sync token %q", syncToken)
+	}
+
+	return since, nil
+}
+
+// changed reports whether the resource, or any of the groups it is assigned to, changed since the previous sync.
+func (c changeSet) changed(resourceType, resourceID string, groupIDs ...string) bool {
+	if c == nil {
+		return true
+	}
+
+	if c[resourceType][resourceID] {
+		return true
+	}
+
+	for _, groupID := range groupIDs {
+		if c[client.ResourceTypeGroup][groupID] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// changedThroughGroups is changed for resources granted to groups, whose grants also go to the members of the groups
+// nested in them. It reports whether the resource, the groups it is assigned to or any group nested in them changed
+// since the previous sync.
+func (c changeSet) changedThroughGroups(ctx context.Context, b client.Backend, resourceType, resourceID string, groupIDs []string) (bool, error) {
+	if c.changed(resourceType, resourceID, groupIDs...) {
+		return true, nil
+	}
+
+	grps, err := nestedGroups(ctx, b, groupIDs)
+	if err != nil {
+		return false, err
+	}
+	for _, grp := range grps {
+		if c[client.ResourceTypeGroup][grp.Id] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,114 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	"github.com/conductorone/baton-demo/pkg/conformance"
+)
+
+// Conformance returns the config that runs the conformance checks against the demo connector for the given
+// capabilities. Every check gets a freshly seeded database in a temporary directory, which is removed afterwards.
+func Conformance(ctx context.Context, caps *v2.ConnectorCapabilities) conformance.Config {
+	return conformance.Config{
+		Capabilities: caps,
+		New:          NewConformanceInstance,
+		AccountInfo: func(n int) *v2.AccountInfo {
+			return &v2.AccountInfo{
+				Login:  fmt.Sprintf("conformance-%d", n),
+				Emails: []*v2.AccountInfo_Email{{Address: fmt.Sprintf("conformance-%d@example.com", n), IsPrimary: true}},
+			}
+		},
+		NewResource: func(resourceType *v2.ResourceType, n int) *v2.Resource {
+			// Users are the only resources the connector creates
+			r, err := sdkResource.NewUserResource(fmt.Sprintf("conformance-%d", n), resourceType, "",
+				[]sdkResource.UserTraitOption{sdkResource.WithEmail(fmt.Sprintf("conformance-%d@example.com", n), true)})
+			if err != nil {
+				return &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceType.GetId()}}
+			}
+			return r
+		},
+		CredentialRotation: credentialRotation(ctx),
+	}
+}
+
+// NewConformanceInstance returns an instance of the demo connector over a freshly seeded database in a temporary
+// directory.
+func NewConformanceInstance(ctx context.Context) (*conformance.Instance, error) {
+	dir, err := os.MkdirTemp("", "baton-demo-conformance-")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := client.NewClient(filepath.Join(dir, "baton-demo.db"), true)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	closeFn := func() error {
+		return errors.Join(c.Close(), os.RemoveAll(dir))
+	}
+
+	demo, err := New(ctx, c)
+	if err != nil {
+		_ = closeFn()
+		return nil, err
+	}
+
+	srv, err := connectorbuilder.NewConnector(ctx, demo)
+	if err != nil {
+		_ = closeFn()
+		return nil, err
+	}
+
+	return &conformance.Instance{
+		Server:           srv,
+		VerifyCredential: verifyCredential(c),
+		Close:            closeFn,
+	}, nil
+}
+
+// credentialRotation returns the credential rotation details of each resource type that can be rotated. They are all
+// the same, see credentialRotationDetails, but the checks rotate each resource type with its own details.
+func credentialRotation(ctx context.Context) map[string]*v2.CredentialDetailsCredentialRotation {
+	ret := make(map[string]*v2.CredentialDetailsCredentialRotation)
+	for _, rs := range (&Demo{}).ResourceSyncers(ctx) {
+		cm, ok := rs.(connectorbuilder.CredentialManager)
+		if !ok {
+			continue
+		}
+
+		details, _, err := cm.RotateCapabilityDetails(ctx)
+		if err != nil {
+			continue
+		}
+		ret[rs.ResourceType(ctx).Id] = details
+	}
+	return ret
+}
+
+// verifyCredential checks generated passwords by logging in with them. API key secrets can't be checked this way.
+func verifyCredential(b client.Backend) func(ctx context.Context, resourceID *v2.ResourceId, credential *v2.PlaintextData) error {
+	return func(ctx context.Context, resourceID *v2.ResourceId, credential *v2.PlaintextData) error {
+		if resourceID.GetResourceType() != userResourceType.Id {
+			return nil
+		}
+
+		user, err := b.GetUser(ctx, resourceID.GetResource())
+		if err != nil {
+			return err
+		}
+
+		_, err = b.Authenticate(ctx, user.Name, string(credential.GetBytes()))
+		return err
+	}
+}
@@ -0,0 +1,50 @@
+package connector_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/conductorone/baton-demo/pkg/conformance"
+	"github.com/conductorone/baton-demo/pkg/conformance/conformancetest"
+	"github.com/conductorone/baton-demo/pkg/connector"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// TestConformance checks that the connector implements every capability baton_capabilities.json advertises.
+func TestConformance(t *testing.T) {
+	caps, err := conformance.LoadCapabilities(filepath.Join("..", "..", "baton_capabilities.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conformancetest.Test(t, connector.Conformance(context.Background(), caps))
+}
+
+// TestCapabilitiesMatch checks that the capabilities the connector computes are those baton_capabilities.json
+// advertises, every time. The SDK visits the builders in map order, so a mismatch may only show up in some runs.
+func TestCapabilitiesMatch(t *testing.T) {
+	ctx := context.Background()
+
+	caps, err := conformance.LoadCapabilities(filepath.Join("..", "..", "baton_capabilities.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inst, err := connector.NewConformanceInstance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inst.Close()
+
+	for i := 0; i < 20; i++ {
+		md, err := inst.Server.GetMetadata(ctx, &v2.ConnectorServiceGetMetadataRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, d := range conformance.Diff(caps, md.GetMetadata().GetCapabilities()) {
+			t.Errorf("run %d: %s", i, d)
+		}
+	}
+}
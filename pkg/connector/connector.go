@@ -3,25 +3,98 @@ package connector
 import (
 	"context"
 	"io"
+	"strconv"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/conductorone/baton-demo/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
 )
 
+// defaultPageSize is the number of objects requested per page of a list call when the SDK does not ask for a size.
+const defaultPageSize = 50
+
 type Demo struct {
-	client *client.Client
+	client    client.Backend
+	pageSize  int
+	syncToken string
+	changes   changeSet
+	console   consoleURL
+	tenant    tenantScope
+}
+
+// credentialRotationDetails returns the credential rotation details of every resource type that can be rotated. The
+// capabilities of a connector carry a single set of rotation details, which the SDK takes from whichever credential
+// manager it happens to visit last, so every credential manager must return the same details: the options all of
+// them support.
+func credentialRotationDetails() *v2.CredentialDetailsCredentialRotation {
+	return &v2.CredentialDetailsCredentialRotation{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+	}
+}
+
+// Option configures optional behavior of the Demo connector.
+type Option func(d *Demo)
+
+// WithPageSize sets the number of objects requested from the backend per page of a list call.
+func WithPageSize(size int) Option {
+	return func(d *Demo) {
+		if size > 0 {
+			d.pageSize = size
+		}
+	}
+}
+
+// WithSyncToken limits the sync to the resources, and their grants, that changed since the sync that reported the
+// given token. An empty token syncs everything.
+func WithSyncToken(token string) Option {
+	return func(d *Demo) {
+		d.syncToken = token
+	}
+}
+
+// WithConsoleURL makes every resource link to its page in the web console of the demo application at the given base
+// URL. An empty URL emits no links.
+func WithConsoleURL(url string) Option {
+	return func(d *Demo) {
+		d.console = consoleURL(url)
+	}
+}
+
+// WithTenantResource syncs the tenant of the backend as a top-level resource, with the users, groups, roles, projects,
+// permissions and service accounts of the tenant as its child resources rather than at the top level.
+func WithTenantResource(enabled bool) Option {
+	return func(d *Demo) {
+		d.tenant = tenantScope(enabled)
+	}
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Demo) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
-	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(d.client),
-		newGroupBuilder(d.client),
-		newRoleBuilder(d.client),
-		newProjectBuilder(d.client),
+	syncers := []connectorbuilder.ResourceSyncer{
+		newUserBuilder(d.client, d.pageSize, d.changes, d.console, d.tenant),
+		newGroupBuilder(d.client, d.pageSize, d.changes, d.console, d.tenant),
+		newRoleBuilder(d.client, d.pageSize, d.changes, d.console, d.tenant),
+		newProjectBuilder(d.client, d.pageSize, d.changes, d.console, d.tenant),
+		newPermissionBuilder(d.client, d.pageSize, d.changes, d.console, d.tenant),
+		newServiceAccountBuilder(d.client, d.pageSize, d.changes, d.console, d.tenant),
+		newAPIKeyBuilder(d.client, d.pageSize, d.changes, d.console),
 	}
+
+	// The tenant resource type is only advertised in tenant mode
+	if d.tenant {
+		syncers = append(syncers, newTenantBuilder(d.client))
+	}
+
+	return syncers
 }
 
 // Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
@@ -31,10 +104,29 @@ func (d *Demo) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.ReadCl
 }
 
 // Metadata returns metadata about the connector.
+// The profile carries the sync token to pass to the next sync so that it only includes what changed after this one.
 func (d *Demo) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
+	since, err := parseSyncToken(d.syncToken)
+	if err != nil {
+		return nil, err
+	}
+
+	_, cursor, err := d.client.ListChanges(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		"sync_token": strconv.FormatInt(cursor, 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &v2.ConnectorMetadata{
 		DisplayName: "Demo",
 		Description: "A demo connector",
+		Profile:     profile,
 	}, nil
 }
 
@@ -48,15 +140,50 @@ func (d *Demo) Close() error {
 	return d.client.Close()
 }
 
-// New returns a new instance of the Demo connector.
-func New(ctx context.Context, dbFileName string, initDB bool) (*Demo, error) {
-	cli, err := client.NewClient(dbFileName, initDB)
+// New returns a new instance of the Demo connector backed by the given storage backend.
+func New(ctx context.Context, backend client.Backend, opts ...Option) (*Demo, error) {
+	demo := &Demo{
+		client:   backend,
+		pageSize: defaultPageSize,
+	}
+
+	for _, opt := range opts {
+		opt(demo)
+	}
+
+	err := demo.console.validate()
 	if err != nil {
 		return nil, err
 	}
-	demo := &Demo{
-		client: cli,
+
+	if demo.syncToken != "" {
+		changes, cursor, err := loadChangeSet(ctx, demo.client, demo.syncToken)
+		if err != nil {
+			return nil, err
+		}
+		demo.changes = changes
+
+		ctxzap.Extract(ctx).Info("syncing changes only",
+			zap.String("sync_token", demo.syncToken),
+			zap.Int64("cursor", cursor),
+		)
 	}
 
 	return demo, nil
 }
+
+// pageOptions returns the backend page requested by the SDK, falling back to pageSize when the SDK did not ask for a size.
+func pageOptions(pToken *pagination.Token, pageSize int) client.PageOptions {
+	page := client.PageOptions{
+		Size: pageSize,
+	}
+
+	if pToken != nil {
+		page.Token = pToken.Token
+		if pToken.Size > 0 {
+			page.Size = pToken.Size
+		}
+	}
+
+	return page
+}
@@ -0,0 +1,47 @@
+package connector
+
+import (
+	"errors"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// wrapError converts a backend error into an error the SDK knows how to handle.
+// Rate limits become codes.Unavailable errors carrying a RateLimitDescription, which is also returned as an annotation,
+// so that the syncer waits for the limit to reset before retrying. Injected faults are reported as codes.Unavailable
+// because they simulate a transient upstream failure. Membership cycles and changes to immutable grants are rejected as
+// codes.FailedPrecondition.
+func wrapError(err error) (annotations.Annotations, error) {
+	var rlErr *client.RateLimitError
+	var immutableErr *client.ImmutableError
+	switch {
+	case errors.As(err, &rlErr):
+		desc := &v2.RateLimitDescription{
+			Status:    v2.RateLimitDescription_STATUS_OVERLIMIT,
+			Limit:     rlErr.Limit,
+			Remaining: rlErr.Remaining,
+			ResetAt:   timestamppb.New(rlErr.ResetAt),
+		}
+
+		var annos annotations.Annotations
+		annos.Update(desc)
+
+		st, stErr := status.New(codes.Unavailable, err.Error()).WithDetails(desc)
+		if stErr != nil {
+			return annos, status.Error(codes.Unavailable, err.Error())
+		}
+
+		return annos, st.Err()
+	case errors.Is(err, client.ErrInjectedFault):
+		return nil, status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, client.ErrMembershipCycle), errors.As(err, &immutableErr):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return nil, err
+	}
+}
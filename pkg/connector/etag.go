@@ -0,0 +1,51 @@
+package connector
+
+import (
+	"strconv"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	sdkEntitlement "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+)
+
+// grantsETag compares the current ETag of a resource's grants with the ETag the syncer attached to the resource from
+// the previous sync. The SDK only reuses previous grants for a single entitlement, so etagEntitlement should be the
+// entitlement holding the bulk of the resource's grants.
+//
+// If the ETags match, the returned annotations carry an ETagMatch and the caller must omit the grants for
+// etagEntitlement, which the syncer copies from the previous sync. Otherwise they carry the new ETag, which the syncer
+// stores on the resource for the next sync.
+func grantsETag(resource *v2.Resource, etagEntitlement string, value string) (bool, annotations.Annotations, error) {
+	entitlementID := sdkEntitlement.NewEntitlementID(resource, etagEntitlement)
+
+	var annos annotations.Annotations
+
+	resourceAnnos := annotations.Annotations(resource.GetAnnotations())
+	prev := &v2.ETag{}
+	ok, err := resourceAnnos.Pick(prev)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if ok && prev.GetValue() != "" && prev.GetValue() == value && prev.GetEntitlementId() == entitlementID {
+		annos.Update(&v2.ETagMatch{EntitlementId: entitlementID})
+		return true, annos, nil
+	}
+
+	annos.Update(&v2.ETag{
+		Value:         value,
+		EntitlementId: entitlementID,
+	})
+
+	return false, annos, nil
+}
+
+// revisionETag combines the revisions of every object that a resource's grants are derived from into an ETag value.
+func revisionETag(revisions ...int64) string {
+	parts := make([]string, 0, len(revisions))
+	for _, rev := range revisions {
+		parts = append(parts, strconv.FormatInt(rev, 10))
+	}
+	return strings.Join(parts, ".")
+}
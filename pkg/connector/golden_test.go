@@ -0,0 +1,254 @@
+package connector_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/dotc1z"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	"github.com/conductorone/baton-demo/pkg/connector"
+	"github.com/conductorone/baton-demo/pkg/scenario"
+)
+
+var update = flag.Bool("update", false, "Regenerate the golden files in testdata/golden from the current connector")
+
+// goldenTimestamp replaces every timestamp in the golden files, since the demo data is seeded relative to the time
+// the database is created.
+const goldenTimestamp = "<timestamp>"
+
+// TestGoldenSync syncs the seeded demo data of each backend to a c1z and compares what was synced to the golden files.
+// Both backends must sync exactly the same data.
+func TestGoldenSync(t *testing.T) {
+	for name, newBackend := range map[string]func(t *testing.T) client.Backend{
+		"sqlite": func(t *testing.T) client.Backend {
+			c, err := client.NewClient(filepath.Join(t.TempDir(), "baton-demo.db"), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return c
+		},
+		"memory": func(t *testing.T) client.Backend {
+			return client.NewMemoryBackend(true)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if *update && name != "sqlite" {
+				t.Skip("golden files are regenerated from the sqlite backend")
+			}
+
+			ctx := context.Background()
+			b := newBackend(t)
+			defer b.Close()
+
+			c1z := syncToC1Z(ctx, t, b)
+			defer c1z.Close()
+
+			compareGolden(t, "resources.json", listResources(ctx, t, c1z))
+			compareGolden(t, "entitlements.json", listEntitlements(ctx, t, c1z))
+			compareGolden(t, "grants.json", listGrants(ctx, t, c1z))
+		})
+	}
+}
+
+// syncToC1Z runs a full sync of the connector over the backend and opens the c1z it wrote.
+func syncToC1Z(ctx context.Context, t *testing.T, b client.Backend) *dotc1z.C1File {
+	t.Helper()
+
+	demo, err := connector.New(ctx, b, connector.WithConsoleURL("https://demo.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := connectorbuilder.NewConnector(ctx, demo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sync.c1z")
+	err = scenario.Sync(ctx, srv, path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1z, err := dotc1z.NewC1ZFile(ctx, path, dotc1z.WithTmpDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c1z
+}
+
+func listResources(ctx context.Context, t *testing.T, c1z *dotc1z.C1File) []proto.Message {
+	var ret []proto.Message
+	pageToken := ""
+	for {
+		resp, err := c1z.ListResources(ctx, &v2.ResourcesServiceListResourcesRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range resp.GetList() {
+			ret = append(ret, r)
+		}
+		pageToken = resp.GetNextPageToken()
+		if pageToken == "" {
+			return ret
+		}
+	}
+}
+
+func listEntitlements(ctx context.Context, t *testing.T, c1z *dotc1z.C1File) []proto.Message {
+	var ret []proto.Message
+	pageToken := ""
+	for {
+		resp, err := c1z.ListEntitlements(ctx, &v2.EntitlementsServiceListEntitlementsRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range resp.GetList() {
+			ret = append(ret, e)
+		}
+		pageToken = resp.GetNextPageToken()
+		if pageToken == "" {
+			return ret
+		}
+	}
+}
+
+func listGrants(ctx context.Context, t *testing.T, c1z *dotc1z.C1File) []proto.Message {
+	var ret []proto.Message
+	pageToken := ""
+	for {
+		resp, err := c1z.ListGrants(ctx, &v2.GrantsServiceListGrantsRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, g := range resp.GetList() {
+			ret = append(ret, g)
+		}
+		pageToken = resp.GetNextPageToken()
+		if pageToken == "" {
+			return ret
+		}
+	}
+}
+
+// compareGolden compares messages to a golden file, or writes them to it with -update. The messages are written as
+// stable JSON: protojson output is reparsed so that its formatting is deterministic, timestamps are normalized and the
+// messages are sorted.
+func compareGolden(t *testing.T, name string, msgs []proto.Message) {
+	t.Helper()
+
+	normalized := make([]interface{}, 0, len(msgs))
+	for _, m := range msgs {
+		b, err := protojson.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var v interface{}
+		err = json.Unmarshal(b, &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		normalized = append(normalized, normalizeTimestamps(v))
+	}
+
+	keys := make([]string, len(normalized))
+	for i, v := range normalized {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = string(b)
+	}
+	sort.Sort(byKey{keys: keys, values: normalized})
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(normalized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.Bytes()
+
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, got, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file, run the tests with -update to create it: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		f, err := os.CreateTemp("", "golden-*-"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		_, err = f.Write(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Errorf("synced data differs from %s, the synced data is in %s. Run the tests with -update if the change is expected", path, f.Name())
+	}
+}
+
+// timestampPattern matches RFC 3339 timestamps, including those within longer strings such as descriptions.
+var timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+
+// normalizeTimestamps replaces every RFC 3339 timestamp in a decoded JSON value with goldenTimestamp.
+func normalizeTimestamps(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeTimestamps(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeTimestamps(e)
+		}
+		return v
+	case string:
+		return timestampPattern.ReplaceAllString(v, goldenTimestamp)
+	default:
+		return v
+	}
+}
+
+// byKey sorts values by their keys.
+type byKey struct {
+	keys   []string
+	values []interface{}
+}
+
+func (b byKey) Len() int           { return len(b.keys) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+}
@@ -0,0 +1,40 @@
+package connector
+
+import (
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// grantSet collects the grants of a resource so that each principal receives a single grant of an entitlement, however
+// many ways it holds it. The syncer stores grants by ID, so a second grant to the same principal would replace the
+// first along with its annotations. Instead the annotations of later grants are merged into the first one, keeping
+// the first of each type.
+type grantSet struct {
+	grants []*v2.Grant
+	byID   map[string]*v2.Grant
+}
+
+func (s *grantSet) add(grant *v2.Grant) {
+	if s.byID == nil {
+		s.byID = make(map[string]*v2.Grant)
+	}
+
+	existing, ok := s.byID[grant.Id]
+	if !ok {
+		s.byID[grant.Id] = grant
+		s.grants = append(s.grants, grant)
+		return
+	}
+
+	for _, anno := range grant.Annotations {
+		present := false
+		for _, a := range existing.Annotations {
+			if a.GetTypeUrl() == anno.GetTypeUrl() {
+				present = true
+				break
+			}
+		}
+		if !present {
+			existing.Annotations = append(existing.Annotations, anno)
+		}
+	}
+}
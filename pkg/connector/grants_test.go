@@ -0,0 +1,211 @@
+package connector_test
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/dotc1z"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	"github.com/conductorone/baton-demo/pkg/connector"
+	"github.com/conductorone/baton-demo/pkg/scenario"
+)
+
+// syncedGrants syncs the backend and returns the grants it synced by ID.
+func syncedGrants(ctx context.Context, t *testing.T, b client.Backend) map[string]*v2.Grant {
+	t.Helper()
+
+	c1z := syncToC1Z(ctx, t, b)
+	defer c1z.Close()
+
+	return grantsByID(ctx, t, c1z)
+}
+
+// grantsByID returns the grants of the last sync in the c1z by ID.
+func grantsByID(ctx context.Context, t *testing.T, c1z *dotc1z.C1File) map[string]*v2.Grant {
+	ret := make(map[string]*v2.Grant)
+	for _, m := range listGrants(ctx, t, c1z) {
+		g := m.(*v2.Grant)
+		ret[g.GetId()] = g
+	}
+	return ret
+}
+
+// TestProjectOwnerAccessImmutable checks that the access grant of a project's owner stays immutable when the owner
+// also has access through one of the project's groups.
+func TestProjectOwnerAccessImmutable(t *testing.T) {
+	ctx := context.Background()
+	b := client.NewMemoryBackend(true)
+	defer b.Close()
+
+	projects, _, err := b.ListProjects(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grants := syncedGrants(ctx, t, b)
+
+	for _, p := range projects {
+		if p.Owner == "" {
+			continue
+		}
+
+		id := "project:" + p.Id + ":access:user:" + p.Owner
+		grant, ok := grants[id]
+		if !ok {
+			t.Errorf("no grant %s", id)
+			continue
+		}
+		annos := annotations.Annotations(grant.GetAnnotations())
+		if !annos.Contains(&v2.GrantImmutable{}) {
+			t.Errorf("grant %s is not immutable", id)
+		}
+	}
+}
+
+// TestRoleGrantKeepsDirectAssignment checks that a user assigned a role both directly and through one of its groups
+// keeps the expiry and provenance of their direct assignment.
+func TestRoleGrantKeepsDirectAssignment(t *testing.T) {
+	ctx := context.Background()
+	b := client.NewMemoryBackend(true)
+	defer b.Close()
+
+	roles, _, err := b.ListRoles(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := slices.IndexFunc(roles, func(r *client.Role) bool { return len(r.GroupAssignments) > 0 })
+	if idx < 0 {
+		t.Fatal("no role assigned to a group")
+	}
+	role := roles[idx]
+
+	user, err := b.CreateUser(ctx, "both", "both@example.org", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	err = b.GrantRole(ctx, user.Id, role.Id, client.WithExpiry(expiresAt), client.WithReason("on call"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.GrantGroupMember(ctx, role.GroupAssignments[0], user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grants := syncedGrants(ctx, t, b)
+
+	id := "role:" + role.Id + ":assignment:user:" + user.Id
+	grant, ok := grants[id]
+	if !ok {
+		t.Fatalf("no grant %s", id)
+	}
+
+	annos := annotations.Annotations(grant.GetAnnotations())
+	md := &v2.GrantMetadata{}
+	ok, err = annos.Pick(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("grant %s has no metadata", id)
+	}
+
+	fields := md.GetMetadata().GetFields()
+	if got := fields["reason"].GetStringValue(); got != "on call" {
+		t.Errorf("reason %q, want %q", got, "on call")
+	}
+	if got, want := fields["expires_at"].GetStringValue(), expiresAt.UTC().Format(time.RFC3339); got != want {
+		t.Errorf("expires at %q, want %q", got, want)
+	}
+}
+
+// TestNestedGroupMemberGrants checks that the members of a group nested in a group assigned a role or a project
+// receive its grants, including when the syncer would otherwise reuse the grants of a previous sync.
+func TestNestedGroupMemberGrants(t *testing.T) {
+	ctx := context.Background()
+	b := client.NewMemoryBackend(true)
+	defer b.Close()
+
+	// Engineers contains Platform, which contains SRE
+	const (
+		engineersGroupID = "2IC0WmAPkihbFdZhEPsch5N5WNO"
+		sreGroupID       = "3Kscz2XAPWCGapNN6R8LICQeZ2P"
+	)
+
+	demo, err := connector.New(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := connectorbuilder.NewConnector(ctx, demo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sync.c1z")
+	err = scenario.Sync(ctx, srv, path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := b.CreateUser(ctx, "nested", "nested@example.org", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.GrantGroupMember(ctx, sreGroupID, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second sync sees the ETags of the first
+	err = scenario.Sync(ctx, srv, path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1z, err := dotc1z.NewC1ZFile(ctx, path, dotc1z.WithTmpDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1z.Close()
+
+	grants := grantsByID(ctx, t, c1z)
+
+	var want []string
+	roles, _, err := b.ListRoles(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range roles {
+		if slices.Contains(r.GroupAssignments, engineersGroupID) {
+			want = append(want, "role:"+r.Id+":assignment:user:"+user.Id)
+		}
+	}
+	projects, _, err := b.ListProjects(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range projects {
+		if slices.Contains(p.GroupAssignments, engineersGroupID) {
+			want = append(want, "project:"+p.Id+":access:user:"+user.Id)
+		}
+	}
+	if len(want) == 0 {
+		t.Fatal("nothing is assigned to the Engineers group")
+	}
+
+	for _, id := range want {
+		if _, ok := grants[id]; !ok {
+			t.Errorf("no grant %s", id)
+		}
+	}
+}
@@ -20,7 +20,11 @@ var (
 )
 
 type groupBuilder struct {
-	client *client.Client
+	client   client.Backend
+	pageSize int
+	changes  changeSet
+	console  consoleURL
+	tenant   tenantScope
 }
 
 func (o *groupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -29,17 +33,30 @@ func (o *groupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 
 // List returns all the groups from the database as resource objects.
 // Groups include the GroupTrait because they have the 'shape' of the well known Group type.
+// Given a sync token, only the groups that changed since the sync that reported it are returned.
 func (o *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	groups, err := o.client.ListGroups(ctx)
+	if o.tenant.skip(parentResourceID) {
+		return nil, "", nil, nil
+	}
+
+	groups, nextPageToken, err := o.client.ListGroups(ctx, pageOptions(pToken, o.pageSize))
 	if err != nil {
-		return nil, "", nil, err
+		annos, err := wrapError(err)
+		return nil, "", annos, err
 	}
 
 	var ret []*v2.Resource
 	for _, g := range groups {
+		if !o.changes.changed(client.ResourceTypeGroup, g.Id) {
+			continue
+		}
+
 		// Group traits can contain arbitrary profile data
-		profile := make(map[string]interface{})
-		profile["group_color"] = "green"
+		profile, err := resourceProfile(ctx, o.client, client.ResourceTypeGroup, g.Id)
+		if err != nil {
+			annos, err := wrapError(err)
+			return nil, "", annos, err
+		}
 
 		group, err := sdkResource.NewGroupResource(
 			g.Name,
@@ -47,6 +64,7 @@ func (o *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 			g.Id,
 			[]sdkResource.GroupTraitOption{sdkResource.WithGroupProfile(profile)},
 			sdkResource.WithParentResourceID(parentResourceID),
+			o.console.link(groupResourceType, g.Id),
 		)
 		if err != nil {
 			return nil, "", nil, err
@@ -54,13 +72,13 @@ func (o *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 		ret = append(ret, group)
 	}
 
-	return ret, "", nil, nil
+	return ret, nextPageToken, nil, nil
 }
 
 // Entitlements returns a membership and admin entitlement.
 func (o *groupBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	// This entitlement represents being a member of the group, and it can be granted to Users.
-	member := sdkEntitlement.NewAssignmentEntitlement(resource, groupMemberEntitlement, sdkEntitlement.WithGrantableTo(userResourceType))
+	// This entitlement represents being a member of the group, and it can be granted to Users and other Groups.
+	member := sdkEntitlement.NewAssignmentEntitlement(resource, groupMemberEntitlement, sdkEntitlement.WithGrantableTo(userResourceType, groupResourceType))
 	member.Description = fmt.Sprintf("Is a member of the %s group", resource.DisplayName)
 
 	admin := sdkEntitlement.NewPermissionEntitlement(resource, groupAdminEntitlement, sdkEntitlement.WithGrantableTo(userResourceType))
@@ -70,8 +88,17 @@ func (o *groupBuilder) Entitlements(ctx context.Context, resource *v2.Resource,
 }
 
 // Grants returns grant information for group administrators and members.
+// Member groups are granted the member entitlement as expandable grants, so the syncer makes the members of nested
+// groups members of this group too.
+// Member grants are only returned when the group changed since the previous sync, otherwise the syncer reuses them.
 func (o *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	grp, err := o.client.GetGroup(ctx, resource.Id.Resource)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	unchanged, annos, err := grantsETag(resource, groupMemberEntitlement, revisionETag(grp.Revision))
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -86,7 +113,19 @@ func (o *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 
 		// Each admin gets the admin entitlement in addition to the member entitlement
 		ret = append(ret, sdkGrant.NewGrant(resource, groupAdminEntitlement, pID))
-		ret = append(ret, sdkGrant.NewGrant(resource, groupMemberEntitlement, pID))
+		if !unchanged {
+			ret = append(ret, sdkGrant.NewGrant(resource, groupMemberEntitlement, pID))
+		}
+	}
+
+	if unchanged {
+		return ret, "", annos, nil
+	}
+
+	members, err := listAssignments(ctx, o.client, client.ResourceTypeGroup, grp.Id)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
 	}
 
 	for _, memberID := range grp.Members {
@@ -95,10 +134,23 @@ func (o *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 			return nil, "", nil, err
 		}
 
-		ret = append(ret, sdkGrant.NewGrant(resource, groupMemberEntitlement, pID))
+		ret = append(ret, sdkGrant.NewGrant(resource, groupMemberEntitlement, pID, members.grantOptions(client.ResourceTypeUser, memberID)...))
 	}
 
-	return ret, "", nil, nil
+	for _, memberGroupID := range grp.MemberGroups {
+		pID, err := sdkResource.NewResourceID(groupResourceType, memberGroupID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		opts := append(members.grantOptions(client.ResourceTypeGroup, memberGroupID), sdkGrant.WithAnnotation(&v2.GrantExpandable{
+			EntitlementIds:  []string{sdkEntitlement.NewEntitlementID(&v2.Resource{Id: pID}, groupMemberEntitlement)},
+			ResourceTypeIds: []string{userResourceType.Id},
+		}))
+		ret = append(ret, sdkGrant.NewGrant(resource, groupMemberEntitlement, pID, opts...))
+	}
+
+	return ret, "", annos, nil
 }
 
 func parseGroupID(groupID string) (string, string, error) {
@@ -111,56 +163,118 @@ func parseGroupID(groupID string) (string, string, error) {
 }
 
 func (o *groupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
-	if principal.Id.ResourceType != userResourceType.Id {
-		return nil, nil, fmt.Errorf("baton-postgres: only users can have group memberships granted")
-	}
-
 	if entitlement.Resource.Id.ResourceType != groupResourceType.Id {
-		return nil, nil, fmt.Errorf("baton-postgres: only groups can have memberships granted")
+		return nil, nil, fmt.Errorf("baton-demo: only groups can have memberships granted")
 	}
 
 	groupId, grantType, err := parseGroupID(entitlement.Id)
 	if err != nil {
 		return nil, nil, err
 	}
-	userID := principal.Id.Resource
 
-	switch grantType {
-	case "member":
-		err := o.client.GrantGroupMember(ctx, groupId, userID)
-		if err != nil {
-			return nil, nil, err
-		}
-	case "admin":
-		err := o.client.GrantGroupAdmin(ctx, groupId, userID)
-		if err != nil {
-			return nil, nil, err
-		}
-	default:
-		return nil, nil, fmt.Errorf("baton-demo: unknown resource type")
+	metadata, err := grantRequestMetadata(entitlement, principal)
+	if err != nil {
+		return nil, nil, err
+	}
+	if grantType == groupAdminEntitlement && timeBound(metadata) {
+		return nil, nil, fmt.Errorf("baton-demo: group admin grants cannot be time-bound")
+	}
+
+	opts, err := assignmentOptions(metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = o.updateMembership(ctx, groupId, grantType, principal.Id, true, opts...)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, annos, err
 	}
 
 	return nil, nil, nil
 }
 
 func (o *groupBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
-	role := grant.Entitlement.Resource.Id.Resource
-	userID := grant.Principal.Id.Resource
+	if err := checkRevocable(grant); err != nil {
+		return wrapError(err)
+	}
 
-	switch grant.Entitlement.Resource.Id.ResourceType {
-	case roleResourceType.Id:
-		err := o.client.RevokeRole(ctx, userID, role)
-		if err != nil {
-			return nil, err
+	if grant.Entitlement.Resource.Id.ResourceType != groupResourceType.Id {
+		return nil, fmt.Errorf("baton-demo: only groups can have memberships revoked")
+	}
+
+	groupId, grantType, err := parseGroupID(grant.Entitlement.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.updateMembership(ctx, groupId, grantType, grant.Principal.Id, false)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return nil, nil
+}
+
+// updateMembership grants or revokes the member or admin entitlement of a group. Groups can only be members, and only
+// memberships are made with the grant options.
+func (o *groupBuilder) updateMembership(ctx context.Context, groupID, grantType string, principal *v2.ResourceId, grant bool, opts ...client.GrantOption) error {
+	switch {
+	case grantType == groupMemberEntitlement && principal.ResourceType == userResourceType.Id:
+		if grant {
+			return o.client.GrantGroupMember(ctx, groupID, principal.Resource, opts...)
 		}
-		return nil, nil
+		return o.client.RevokeGroupMember(ctx, groupID, principal.Resource)
+	case grantType == groupMemberEntitlement && principal.ResourceType == groupResourceType.Id:
+		if grant {
+			return o.client.GrantGroupMemberGroup(ctx, groupID, principal.Resource, opts...)
+		}
+		return o.client.RevokeGroupMemberGroup(ctx, groupID, principal.Resource)
+	case grantType == groupAdminEntitlement && principal.ResourceType == userResourceType.Id:
+		if grant {
+			return o.client.GrantGroupAdmin(ctx, groupID, principal.Resource)
+		}
+		return o.client.RevokeGroupAdmin(ctx, groupID, principal.Resource)
+	case grantType == groupAdminEntitlement:
+		return fmt.Errorf("baton-demo: only users can be group admins")
 	default:
-		return nil, fmt.Errorf("baton-demo: unknown resource type")
+		return fmt.Errorf("baton-demo: unknown group entitlement %s for %s", grantType, principal.ResourceType)
 	}
 }
 
-func newGroupBuilder(client *client.Client) *groupBuilder {
+func newGroupBuilder(client client.Backend, pageSize int, changes changeSet, console consoleURL, tenant tenantScope) *groupBuilder {
 	return &groupBuilder{
-		client: client,
+		client:   client,
+		pageSize: pageSize,
+		changes:  changes,
+		console:  console,
+		tenant:   tenant,
+	}
+}
+
+// nestedGroups returns the groups with the given IDs followed by every group nested in them, at any depth, each of
+// them once. Their members are all members of the given groups. Groups nested in a loop, as data written before the
+// cycle guard may be, don't make it walk forever.
+func nestedGroups(ctx context.Context, b client.Backend, groupIDs []string) ([]*client.Group, error) {
+	var ret []*client.Group
+	seen := make(map[string]bool)
+
+	queue := append([]string(nil), groupIDs...)
+	for len(queue) > 0 {
+		groupID := queue[0]
+		queue = queue[1:]
+		if seen[groupID] {
+			continue
+		}
+		seen[groupID] = true
+
+		grp, err := b.GetGroup(ctx, groupID)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, grp)
+		queue = append(queue, grp.MemberGroups...)
 	}
+
+	return ret, nil
 }
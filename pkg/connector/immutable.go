@@ -0,0 +1,31 @@
+package connector
+
+import (
+	"github.com/conductorone/baton-demo/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	sdkGrant "github.com/conductorone/baton-sdk/pkg/types/grant"
+)
+
+// immutableGrant returns the options marking a grant as immutable if it is, so that it is not offered for revocation.
+func immutableGrant(immutable bool) []sdkGrant.GrantOption {
+	if !immutable {
+		return nil
+	}
+	return []sdkGrant.GrantOption{sdkGrant.WithAnnotation(&v2.GrantImmutable{})}
+}
+
+// checkRevocable returns an ImmutableError for a grant that carries a GrantImmutable annotation, which cannot be
+// revoked.
+func checkRevocable(grant *v2.Grant) error {
+	annos := annotations.Annotations(grant.GetAnnotations())
+	if !annos.Contains(&v2.GrantImmutable{}) {
+		return nil
+	}
+
+	return &client.ImmutableError{
+		ResourceType: grant.GetEntitlement().GetResource().GetId().GetResourceType(),
+		ResourceID:   grant.GetEntitlement().GetResource().GetId().GetResource(),
+		Reason:       "the grant is managed by the demo application",
+	}
+}
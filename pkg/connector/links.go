@@ -0,0 +1,65 @@
+package connector
+
+import (
+	"fmt"
+	"net/url"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// consolePaths maps resource type IDs to the path of their pages in the web console of the demo application.
+var consolePaths = map[string]string{
+	userResourceType.Id:           "users",
+	groupResourceType.Id:          "groups",
+	roleResourceType.Id:           "roles",
+	projectResourceType.Id:        "projects",
+	permissionResourceType.Id:     "permissions",
+	serviceAccountResourceType.Id: "service-accounts",
+	apiKeyResourceType.Id:         "api-keys",
+}
+
+// consoleURL is the base URL of the web console of the demo application. Resources link to their page in the console
+// when it is set.
+type consoleURL string
+
+// validate checks that the console URL is an absolute HTTP URL. An empty URL disables links.
+func (c consoleURL) validate() error {
+	if c == "" {
+		return nil
+	}
+
+	u, err := url.Parse(string(c))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("baton-demo: invalid console URL %q", string(c))
+	}
+
+	return nil
+}
+
+// link returns a resource option adding an ExternalLink to the page of a resource in the console, such as
+// /users/{id}. It does nothing if no console URL is set.
+func (c consoleURL) link(resourceType *v2.ResourceType, resourceID string) sdkResource.ResourceOption {
+	return func(r *v2.Resource) error {
+		if c == "" {
+			return nil
+		}
+
+		path, ok := consolePaths[resourceType.Id]
+		if !ok {
+			return fmt.Errorf("baton-demo: no console page for resource type %s", resourceType.Id)
+		}
+
+		link, err := url.JoinPath(string(c), path, resourceID)
+		if err != nil {
+			return err
+		}
+
+		annos := annotations.Annotations(r.Annotations)
+		annos.Update(&v2.ExternalLink{Url: link})
+		r.Annotations = annos
+
+		return nil
+	}
+}
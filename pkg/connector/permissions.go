@@ -0,0 +1,155 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	sdkEntitlement "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	sdkGrant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+var (
+	permissionAssignedEntitlement = "assigned"
+)
+
+type permissionBuilder struct {
+	client   client.Backend
+	pageSize int
+	changes  changeSet
+	console  consoleURL
+	tenant   tenantScope
+}
+
+func (o *permissionBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return permissionResourceType
+}
+
+// List returns the permission catalog from the database as resource objects.
+// Permissions don't include any traits because they don't match the 'shape' of any well known types.
+// Given a sync token, only the permissions that changed, or whose roles changed, since the sync that reported it are returned.
+func (o *permissionBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if o.tenant.skip(parentResourceID) {
+		return nil, "", nil, nil
+	}
+
+	permissions, nextPageToken, err := o.client.ListPermissions(ctx, pageOptions(pToken, o.pageSize))
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	var ret []*v2.Resource
+	for _, p := range permissions {
+		if !o.changes.changed(client.ResourceTypePermission, p.Id) {
+			continue
+		}
+
+		permission, err := sdkResource.NewResource(p.Name, permissionResourceType, p.Id,
+			sdkResource.WithParentResourceID(parentResourceID),
+			sdkResource.WithDescription(p.Description),
+			o.console.link(permissionResourceType, p.Id),
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		ret = append(ret, permission)
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+// Entitlements returns an entitlement representing the permission being granted to a role.
+func (o *permissionBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	assigned := sdkEntitlement.NewPermissionEntitlement(resource, permissionAssignedEntitlement, sdkEntitlement.WithGrantableTo(roleResourceType))
+	assigned.Description = fmt.Sprintf("Is granted the %s permission", resource.DisplayName)
+
+	return []*v2.Entitlement{assigned}, "", nil, nil
+}
+
+// Grants returns a grant for each role that is granted the permission. The grants expand to the users assigned each
+// role, which is what answers what a user can actually do. The permissions of system roles are immutable.
+// No grants are returned when the permission did not change since the previous sync, the syncer reuses them instead.
+func (o *permissionBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	permission, err := o.client.GetPermission(ctx, resource.Id.Resource)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	unchanged, annos, err := grantsETag(resource, permissionAssignedEntitlement, revisionETag(permission.Revision))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if unchanged {
+		return nil, "", annos, nil
+	}
+
+	var ret []*v2.Grant
+	for _, roleID := range permission.Roles {
+		role, err := o.client.GetRole(ctx, roleID)
+		if err != nil {
+			annos, err := wrapError(err)
+			return nil, "", annos, err
+		}
+
+		pID, err := sdkResource.NewResourceID(roleResourceType, role.Id)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		opts := append(immutableGrant(role.System), sdkGrant.WithAnnotation(&v2.GrantExpandable{
+			EntitlementIds:  []string{sdkEntitlement.NewEntitlementID(&v2.Resource{Id: pID}, roleAssignmentEntitlement)},
+			ResourceTypeIds: []string{userResourceType.Id},
+		}))
+		ret = append(ret, sdkGrant.NewGrant(resource, permissionAssignedEntitlement, pID, opts...))
+	}
+
+	return ret, "", annos, nil
+}
+
+// Grant gives a permission to a role. Permissions can only be granted to roles.
+func (o *permissionBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	if principal.Id.ResourceType != roleResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-demo: only roles can be granted permissions")
+	}
+
+	err := o.client.GrantRolePermission(ctx, principal.Id.Resource, entitlement.Resource.Id.Resource)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, annos, err
+	}
+
+	return nil, nil, nil
+}
+
+func (o *permissionBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	if err := checkRevocable(grant); err != nil {
+		return wrapError(err)
+	}
+
+	if grant.Principal.Id.ResourceType != roleResourceType.Id {
+		return nil, fmt.Errorf("baton-demo: only roles can have permissions revoked")
+	}
+
+	err := o.client.RevokeRolePermission(ctx, grant.Principal.Id.Resource, grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return nil, nil
+}
+
+func newPermissionBuilder(client client.Backend, pageSize int, changes changeSet, console consoleURL, tenant tenantScope) *permissionBuilder {
+	return &permissionBuilder{
+		client:   client,
+		pageSize: pageSize,
+		changes:  changes,
+		console:  console,
+		tenant:   tenant,
+	}
+}
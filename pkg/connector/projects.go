@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/conductorone/baton-demo/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -19,7 +20,11 @@ var (
 )
 
 type projectBuilder struct {
-	client *client.Client
+	client   client.Backend
+	pageSize int
+	changes  changeSet
+	console  consoleURL
+	tenant   tenantScope
 }
 
 func (o *projectBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -28,69 +33,122 @@ func (o *projectBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 
 // List returns all the projects from the database as resource objects
 // Projects don't include any traits because they don't match the 'shape' of any well known types.
+// Given a sync token, only the projects that changed, or whose assigned groups or the groups nested in them changed, since the sync that reported it are returned.
 func (o *projectBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	projects, err := o.client.ListProjects(ctx)
+	if o.tenant.skip(parentResourceID) {
+		return nil, "", nil, nil
+	}
+
+	projects, nextPageToken, err := o.client.ListProjects(ctx, pageOptions(pToken, o.pageSize))
 	if err != nil {
-		return nil, "", nil, err
+		annos, err := wrapError(err)
+		return nil, "", annos, err
 	}
 
 	var ret []*v2.Resource
 	for _, p := range projects {
-		project, err := sdkResource.NewResource(p.Name, projectResourceType, p.Id, sdkResource.WithParentResourceID(parentResourceID))
+		changed, err := o.changes.changedThroughGroups(ctx, o.client, client.ResourceTypeProject, p.Id, p.GroupAssignments)
+		if err != nil {
+			annos, err := wrapError(err)
+			return nil, "", annos, err
+		}
+		if !changed {
+			continue
+		}
+
+		project, err := sdkResource.NewResource(p.Name, projectResourceType, p.Id,
+			sdkResource.WithParentResourceID(parentResourceID),
+			o.console.link(projectResourceType, p.Id),
+		)
 		if err != nil {
 			return nil, "", nil, err
 		}
 		ret = append(ret, project)
 	}
 
-	return ret, "", nil, nil
+	return ret, nextPageToken, nil, nil
 }
 
 // Entitlements returns two entitlements:
-//   - Ownership of the project, grantable to a user
+//   - Ownership of the project, grantable to a user. Ownership is immutable, it cannot be granted or revoked
 //   - Access to the project, grantable to groups
 func (o *projectBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	access := sdkEntitlement.NewAssignmentEntitlement(resource, projectAccessEntitlement, sdkEntitlement.WithGrantableTo(groupResourceType, userResourceType))
+	access := sdkEntitlement.NewAssignmentEntitlement(resource, projectAccessEntitlement, sdkEntitlement.WithGrantableTo(groupResourceType))
 	access.Description = fmt.Sprintf("Has access to the %s project", resource.DisplayName)
 
-	owner := sdkEntitlement.NewPermissionEntitlement(resource, projectOwnerEntitlement, sdkEntitlement.WithGrantableTo(userResourceType))
+	owner := sdkEntitlement.NewPermissionEntitlement(resource, projectOwnerEntitlement,
+		sdkEntitlement.WithGrantableTo(userResourceType),
+		sdkEntitlement.WithAnnotation(&v2.EntitlementImmutable{}),
+	)
 	owner.Description = fmt.Sprintf("Is the owner of the %s project", resource.DisplayName)
 
 	return []*v2.Entitlement{access, owner}, "", nil, nil
 }
 
-// Grants returns grants for the access and owner entitlements. Only groups can be assigned to projects, but we will materialize group members as having access to the project.
+// Grants returns grants for the access and owner entitlements. Only groups can be assigned to projects, but we will materialize group members, including the members of nested groups, as having access to the project.
+// Access grants are only returned when the project or its groups changed since the previous sync, otherwise the syncer reuses them.
 func (o *projectBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	project, err := o.client.GetProject(ctx, resource.Id.Resource)
 	if err != nil {
-		return nil, "", nil, err
+		annos, err := wrapError(err)
+		return nil, "", annos, err
 	}
 
-	var ret []*v2.Grant
+	// Look up the assigned groups and the groups nested in them first, their members are part of the project's grants
+	grps, err := nestedGroups(ctx, o.client, project.GroupAssignments)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+	revisions := []int64{project.Revision}
+	for _, grp := range grps {
+		revisions = append(revisions, grp.Revision)
+	}
 
-	// Grant the owner entitlement to the project owner
-	ownerID, err := sdkResource.NewResourceID(userResourceType, project.Owner)
+	unchanged, annos, err := grantsETag(resource, projectAccessEntitlement, revisionETag(revisions...))
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	ret = append(ret, sdkGrant.NewGrant(resource, projectOwnerEntitlement, ownerID))
-	// Owners also receive the access entitlement
-	ret = append(ret, sdkGrant.NewGrant(resource, projectAccessEntitlement, ownerID))
+	var ret []*v2.Grant
 
-	// Iterate group assignments
-	for _, grpID := range project.GroupAssignments {
-		pID, err := sdkResource.NewResourceID(groupResourceType, grpID)
+	// Grant the owner entitlement to the project owner, projects whose owner was deleted have none
+	var ownerID *v2.ResourceId
+	if project.Owner != "" {
+		ownerID, err = sdkResource.NewResourceID(userResourceType, project.Owner)
 		if err != nil {
 			return nil, "", nil, err
 		}
 
-		ret = append(ret, sdkGrant.NewGrant(resource, projectAccessEntitlement, pID))
+		ret = append(ret, sdkGrant.NewGrant(resource, projectOwnerEntitlement, ownerID, immutableGrant(true)...))
+	}
+	if unchanged {
+		return ret, "", annos, nil
+	}
+
+	assigned, err := listAssignments(ctx, o.client, client.ResourceTypeProject, project.Id)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
 
-		// Look up group and iterate its members
-		grp, err := o.client.GetGroup(ctx, grpID)
-		if err != nil {
-			return nil, "", nil, err
+	// Users may receive access in several ways, each of them gets a single grant
+	access := &grantSet{}
+
+	// Owners also receive the access entitlement, which cannot be revoked from them
+	if ownerID != nil {
+		access.add(sdkGrant.NewGrant(resource, projectAccessEntitlement, ownerID, immutableGrant(true)...))
+	}
+
+	// Iterate group assignments, along with the groups nested in them whose members hold the project too
+	for _, grp := range grps {
+		if slices.Contains(project.GroupAssignments, grp.Id) {
+			pID, err := sdkResource.NewResourceID(groupResourceType, grp.Id)
+			if err != nil {
+				return nil, "", nil, err
+			}
+
+			access.add(sdkGrant.NewGrant(resource, projectAccessEntitlement, pID, assigned.grantOptions(client.ResourceTypeGroup, grp.Id)...))
 		}
 
 		for _, userID := range append(grp.Admins, grp.Members...) {
@@ -99,15 +157,80 @@ func (o *projectBuilder) Grants(ctx context.Context, resource *v2.Resource, pTok
 				return nil, "", nil, err
 			}
 
-			ret = append(ret, sdkGrant.NewGrant(resource, projectAccessEntitlement, pID))
+			access.add(sdkGrant.NewGrant(resource, projectAccessEntitlement, pID))
 		}
 	}
 
-	return ret, "", nil, nil
+	return append(ret, access.grants...), "", annos, nil
+}
+
+// Grant gives a group access to the project. Access can only be granted to groups, ownership cannot be granted.
+func (o *projectBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	if entitlement.Id != sdkEntitlement.NewEntitlementID(entitlement.Resource, projectAccessEntitlement) {
+		return nil, nil, fmt.Errorf("baton-demo: only project access can be granted")
+	}
+	if principal.Id.ResourceType != groupResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-demo: only groups can be granted project access")
+	}
+
+	metadata, err := grantRequestMetadata(entitlement, principal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts, err := assignmentOptions(metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = o.client.GrantProjectAccess(ctx, entitlement.Resource.Id.Resource, principal.Id.Resource, opts...)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, annos, err
+	}
+
+	return nil, nil, nil
+}
+
+func (o *projectBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	if err := checkRevocable(grant); err != nil {
+		return wrapError(err)
+	}
+
+	if grant.Entitlement.Id != sdkEntitlement.NewEntitlementID(grant.Entitlement.Resource, projectAccessEntitlement) {
+		return nil, fmt.Errorf("baton-demo: only project access can be revoked")
+	}
+	if grant.Principal.Id.ResourceType == userResourceType.Id {
+		project, err := o.client.GetProject(ctx, grant.Entitlement.Resource.Id.Resource)
+		if err != nil {
+			return wrapError(err)
+		}
+		if project.Owner == grant.Principal.Id.Resource {
+			return wrapError(&client.ImmutableError{
+				ResourceType: client.ResourceTypeProject,
+				ResourceID:   project.Id,
+				Reason:       "the owner of a project always has access to it",
+			})
+		}
+	}
+	if grant.Principal.Id.ResourceType != groupResourceType.Id {
+		return nil, fmt.Errorf("baton-demo: only groups can have project access revoked")
+	}
+
+	err := o.client.RevokeProjectAccess(ctx, grant.Entitlement.Resource.Id.Resource, grant.Principal.Id.Resource)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return nil, nil
 }
 
-func newProjectBuilder(client *client.Client) *projectBuilder {
+func newProjectBuilder(client client.Backend, pageSize int, changes changeSet, console consoleURL, tenant tenantScope) *projectBuilder {
 	return &projectBuilder{
-		client: client,
+		client:   client,
+		pageSize: pageSize,
+		changes:  changes,
+		console:  console,
+		tenant:   tenant,
 	}
 }
@@ -31,3 +31,31 @@ var projectResourceType = &v2.ResourceType{
 	Id:          "project",
 	DisplayName: "Project",
 }
+
+// The service account resource type is for all non-human identities from the database.
+// Service accounts have the 'shape' of a user, with a service account type.
+var serviceAccountResourceType = &v2.ResourceType{
+	Id:          "service_account",
+	DisplayName: "Service Account",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+}
+
+// The API key resource type is for the credentials of service accounts, which are always their parent resource.
+var apiKeyResourceType = &v2.ResourceType{
+	Id:          "api_key",
+	DisplayName: "API Key",
+}
+
+// The permission resource type is for the catalog of permissions that can be granted to roles.
+// Permissions don't match any of the well-known resource traits.
+var permissionResourceType = &v2.ResourceType{
+	Id:          "permission",
+	DisplayName: "Permission",
+}
+
+// The tenant resource type is for the tenant the connector is scoped to, synced as the parent of every other resource
+// in tenant mode. Tenants don't match any of the well-known resource traits.
+var tenantResourceType = &v2.ResourceType{
+	Id:          "tenant",
+	DisplayName: "Tenant",
+}
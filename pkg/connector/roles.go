@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/conductorone/baton-demo/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -15,10 +16,16 @@ import (
 
 var (
 	roleAssignmentEntitlement = "assignment"
+	roleAdminEntitlement      = "admin"
+	roleDelegateEntitlement   = "delegate"
 )
 
 type roleBuilder struct {
-	client *client.Client
+	client   client.Backend
+	pageSize int
+	changes  changeSet
+	console  consoleURL
+	tenant   tenantScope
 }
 
 func (o *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -27,43 +34,142 @@ func (o *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 
 // List returns all the roles from the database as resource objects
 // Roles include the role trait because they have the 'shape' of the well known Role type.
+// Given a sync token, only the roles that changed, or whose assigned groups or the groups nested in them changed, since the sync that reported it are returned.
 func (o *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	roles, err := o.client.ListRoles(ctx)
+	if o.tenant.skip(parentResourceID) {
+		return nil, "", nil, nil
+	}
+
+	roles, nextPageToken, err := o.client.ListRoles(ctx, pageOptions(pToken, o.pageSize))
 	if err != nil {
-		return nil, "", nil, err
+		annos, err := wrapError(err)
+		return nil, "", annos, err
 	}
 
 	var ret []*v2.Resource
 	for _, r := range roles {
-		role, err := sdkResource.NewRoleResource(r.Name, roleResourceType, r.Id, nil, sdkResource.WithParentResourceID(parentResourceID))
+		changed, err := o.changes.changedThroughGroups(ctx, o.client, client.ResourceTypeRole, r.Id, r.GroupAssignments)
+		if err != nil {
+			annos, err := wrapError(err)
+			return nil, "", annos, err
+		}
+		if !changed {
+			continue
+		}
+
+		profile, err := resourceProfile(ctx, o.client, client.ResourceTypeRole, r.Id)
+		if err != nil {
+			annos, err := wrapError(err)
+			return nil, "", annos, err
+		}
+
+		role, err := sdkResource.NewRoleResource(
+			r.Name,
+			roleResourceType,
+			r.Id,
+			[]sdkResource.RoleTraitOption{sdkResource.WithRoleProfile(profile)},
+			sdkResource.WithParentResourceID(parentResourceID),
+			o.console.link(roleResourceType, r.Id),
+		)
 		if err != nil {
 			return nil, "", nil, err
 		}
 		ret = append(ret, role)
 	}
 
-	return ret, "", nil, nil
+	return ret, nextPageToken, nil, nil
 }
 
-// Entitlements returns an assignment entitlement.
-func (o *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+// Entitlements returns an assignment entitlement, along with admin and delegate entitlements for the users who manage
+// who holds the role. The entitlements of system roles are immutable, because the demo application manages them.
+func (o *roleBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	role, err := o.client.GetRole(ctx, resource.Id.Resource)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	var opts []sdkEntitlement.EntitlementOption
+	if role.System {
+		opts = append(opts, sdkEntitlement.WithAnnotation(&v2.EntitlementImmutable{}))
+	}
+
 	// This entitlement represents a User or Group being assigned the role
-	assignment := sdkEntitlement.NewAssignmentEntitlement(resource, roleAssignmentEntitlement, sdkEntitlement.WithGrantableTo(userResourceType, groupResourceType))
+	assignment := sdkEntitlement.NewAssignmentEntitlement(resource, roleAssignmentEntitlement,
+		append(opts, sdkEntitlement.WithGrantableTo(userResourceType, groupResourceType))...)
 	assignment.Description = fmt.Sprintf("Is assigned the %s role", resource.DisplayName)
 
-	return []*v2.Entitlement{assignment}, "", nil, nil
+	admin := sdkEntitlement.NewPermissionEntitlement(resource, roleAdminEntitlement, append(opts, sdkEntitlement.WithGrantableTo(userResourceType))...)
+	admin.Description = fmt.Sprintf("Manages who holds the %s role, including its admins and delegates", resource.DisplayName)
+
+	delegate := sdkEntitlement.NewPermissionEntitlement(resource, roleDelegateEntitlement, append(opts, sdkEntitlement.WithGrantableTo(userResourceType))...)
+	delegate.Description = fmt.Sprintf("Can assign the %s role to others", resource.DisplayName)
+
+	return []*v2.Entitlement{assignment, admin, delegate}, "", nil, nil
 }
 
-// Grants returns grants for the assigned entitlement. We will return a grant for each group that is assigned the role, in addition to a grant for every member of the group/
+// Grants returns grants for the admin, delegate and assigned entitlements. We will return a grant for each group that is assigned the role, in addition to a grant for every member of the group and of the groups nested in it.
 // Users can also be directly assigned to a role to receive a grant.
+// No assignment grants are returned when neither the role nor its groups changed since the previous sync, the syncer reuses them instead.
+// The grants of system roles are immutable.
 func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	role, err := o.client.GetRole(ctx, resource.Id.Resource)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	// Look up the assigned groups and the groups nested in them first, their members are part of the role's grants
+	grps, err := nestedGroups(ctx, o.client, role.GroupAssignments)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+	revisions := []int64{role.Revision}
+	for _, grp := range grps {
+		revisions = append(revisions, grp.Revision)
+	}
+
+	unchanged, annos, err := grantsETag(resource, roleAssignmentEntitlement, revisionETag(revisions...))
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	immutable := immutableGrant(role.System)
+
 	var ret []*v2.Grant
 
+	// Grant the admin and delegate entitlements to the users managing the role
+	for _, managers := range []struct {
+		entitlement string
+		userIDs     []string
+	}{
+		{roleAdminEntitlement, role.Admins},
+		{roleDelegateEntitlement, role.Delegates},
+	} {
+		for _, userID := range managers.userIDs {
+			pID, err := sdkResource.NewResourceID(userResourceType, userID)
+			if err != nil {
+				return nil, "", nil, err
+			}
+
+			ret = append(ret, sdkGrant.NewGrant(resource, managers.entitlement, pID, immutable...))
+		}
+	}
+	if unchanged {
+		return ret, "", annos, nil
+	}
+
+	assigned, err := listAssignments(ctx, o.client, client.ResourceTypeRole, role.Id)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	// Users may hold the role both directly and through groups, each of them gets a single grant carrying the metadata
+	// of their direct assignment
+	holders := &grantSet{}
+
 	// Iterate direct assignments
 	for _, userID := range role.DirectAssignments {
 		pID, err := sdkResource.NewResourceID(userResourceType, userID)
@@ -71,22 +177,20 @@ func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 			return nil, "", nil, err
 		}
 
-		ret = append(ret, sdkGrant.NewGrant(resource, roleAssignmentEntitlement, pID))
+		opts := append(assigned.grantOptions(client.ResourceTypeUser, userID), immutable...)
+		holders.add(sdkGrant.NewGrant(resource, roleAssignmentEntitlement, pID, opts...))
 	}
 
-	// Iterate group assignments
-	for _, grpID := range role.GroupAssignments {
-		pID, err := sdkResource.NewResourceID(groupResourceType, grpID)
-		if err != nil {
-			return nil, "", nil, err
-		}
-
-		ret = append(ret, sdkGrant.NewGrant(resource, roleAssignmentEntitlement, pID))
+	// Iterate group assignments, along with the groups nested in them whose members hold the role too
+	for _, grp := range grps {
+		if slices.Contains(role.GroupAssignments, grp.Id) {
+			pID, err := sdkResource.NewResourceID(groupResourceType, grp.Id)
+			if err != nil {
+				return nil, "", nil, err
+			}
 
-		// Look up group and iterate its members
-		grp, err := o.client.GetGroup(ctx, grpID)
-		if err != nil {
-			return nil, "", nil, err
+			opts := append(assigned.grantOptions(client.ResourceTypeGroup, grp.Id), immutable...)
+			holders.add(sdkGrant.NewGrant(resource, roleAssignmentEntitlement, pID, opts...))
 		}
 
 		// Grant all admins and members the assignment entitlement
@@ -96,51 +200,117 @@ func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 				return nil, "", nil, err
 			}
 
-			ret = append(ret, sdkGrant.NewGrant(resource, roleAssignmentEntitlement, pID))
+			holders.add(sdkGrant.NewGrant(resource, roleAssignmentEntitlement, pID, immutable...))
 		}
 	}
 
-	return ret, "", nil, nil
+	return append(ret, holders.grants...), "", annos, nil
 }
 
 func (o *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
-	if principal.Id.ResourceType != userResourceType.Id {
-		return nil, nil, fmt.Errorf("baton-postgres: only users can have roles granted")
+	if entitlement.Resource.Id.ResourceType != roleResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-demo: unknown resource type")
+	}
+
+	metadata, err := grantRequestMetadata(entitlement, principal)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	role := entitlement.Resource.Id.Resource
-	userID := principal.Id.Resource
+	principalID := principal.Id.Resource
+
+	switch entitlement.Id {
+	case sdkEntitlement.NewEntitlementID(entitlement.Resource, roleAdminEntitlement),
+		sdkEntitlement.NewEntitlementID(entitlement.Resource, roleDelegateEntitlement):
+		if timeBound(metadata) {
+			return nil, nil, fmt.Errorf("baton-demo: role admin and delegate grants cannot be time-bound")
+		}
 
-	switch entitlement.Resource.Id.ResourceType {
-	case roleResourceType.Id:
-		err := o.client.GrantRole(ctx, userID, role)
+		err = o.updateManagers(ctx, entitlement, principal.Id, true)
+	default:
+		var opts []client.GrantOption
+		opts, err = assignmentOptions(metadata)
 		if err != nil {
 			return nil, nil, err
 		}
-		return nil, nil, nil
-	default:
-		return nil, nil, fmt.Errorf("baton-demo: unknown resource type")
+
+		switch principal.Id.ResourceType {
+		case userResourceType.Id:
+			err = o.client.GrantRole(ctx, principalID, role, opts...)
+		case groupResourceType.Id:
+			err = o.client.GrantRoleGroup(ctx, principalID, role, opts...)
+		default:
+			return nil, nil, fmt.Errorf("baton-demo: only users and groups can have roles granted")
+		}
 	}
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, annos, err
+	}
+
+	return nil, nil, nil
 }
 
 func (o *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	if err := checkRevocable(grant); err != nil {
+		return wrapError(err)
+	}
+
+	if grant.Entitlement.Resource.Id.ResourceType != roleResourceType.Id {
+		return nil, fmt.Errorf("baton-demo: unknown resource type")
+	}
+
 	role := grant.Entitlement.Resource.Id.Resource
-	userID := grant.Principal.Id.Resource
+	principalID := grant.Principal.Id.Resource
 
-	switch grant.Entitlement.Resource.Id.ResourceType {
-	case roleResourceType.Id:
-		err := o.client.RevokeRole(ctx, userID, role)
-		if err != nil {
-			return nil, err
+	var err error
+	switch grant.Entitlement.Id {
+	case sdkEntitlement.NewEntitlementID(grant.Entitlement.Resource, roleAdminEntitlement),
+		sdkEntitlement.NewEntitlementID(grant.Entitlement.Resource, roleDelegateEntitlement):
+		err = o.updateManagers(ctx, grant.Entitlement, grant.Principal.Id, false)
+	default:
+		switch grant.Principal.Id.ResourceType {
+		case userResourceType.Id:
+			err = o.client.RevokeRole(ctx, principalID, role)
+		case groupResourceType.Id:
+			err = o.client.RevokeRoleGroup(ctx, principalID, role)
+		default:
+			return nil, fmt.Errorf("baton-demo: only users and groups can have roles revoked")
 		}
-		return nil, nil
+	}
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return nil, nil
+}
+
+// updateManagers grants or revokes the admin or delegate entitlement of a role, which only users can hold.
+func (o *roleBuilder) updateManagers(ctx context.Context, entitlement *v2.Entitlement, principal *v2.ResourceId, grant bool) error {
+	if principal.ResourceType != userResourceType.Id {
+		return fmt.Errorf("baton-demo: only users can be role admins or delegates")
+	}
+
+	roleID := entitlement.Resource.Id.Resource
+	switch {
+	case entitlement.Id == sdkEntitlement.NewEntitlementID(entitlement.Resource, roleAdminEntitlement) && grant:
+		return o.client.GrantRoleAdmin(ctx, roleID, principal.Resource)
+	case entitlement.Id == sdkEntitlement.NewEntitlementID(entitlement.Resource, roleAdminEntitlement):
+		return o.client.RevokeRoleAdmin(ctx, roleID, principal.Resource)
+	case grant:
+		return o.client.GrantRoleDelegate(ctx, roleID, principal.Resource)
 	default:
-		return nil, fmt.Errorf("baton-demo: unknown resource type")
+		return o.client.RevokeRoleDelegate(ctx, roleID, principal.Resource)
 	}
 }
 
-func newRoleBuilder(client *client.Client) *roleBuilder {
+func newRoleBuilder(client client.Backend, pageSize int, changes changeSet, console consoleURL, tenant tenantScope) *roleBuilder {
 	return &roleBuilder{
-		client: client,
+		client:   client,
+		pageSize: pageSize,
+		changes:  changes,
+		console:  console,
+		tenant:   tenant,
 	}
 }
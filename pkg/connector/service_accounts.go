@@ -0,0 +1,116 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	sdkEntitlement "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	sdkGrant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+var (
+	serviceAccountOwnerEntitlement = "owner"
+)
+
+type serviceAccountBuilder struct {
+	client   client.Backend
+	pageSize int
+	changes  changeSet
+	console  consoleURL
+	tenant   tenantScope
+}
+
+func (o *serviceAccountBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return serviceAccountResourceType
+}
+
+// List returns all the service accounts from the database as resource objects.
+// Service accounts include a UserTrait with the service account type, and have their API keys as child resources.
+// Given a sync token, only the service accounts that changed since the sync that reported it are returned.
+func (o *serviceAccountBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if o.tenant.skip(parentResourceID) {
+		return nil, "", nil, nil
+	}
+
+	serviceAccounts, nextPageToken, err := o.client.ListServiceAccounts(ctx, pageOptions(pToken, o.pageSize))
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	var ret []*v2.Resource
+	for _, sa := range serviceAccounts {
+		if !o.changes.changed(client.ResourceTypeServiceAccount, sa.Id) {
+			continue
+		}
+
+		profile, err := resourceProfile(ctx, o.client, client.ResourceTypeServiceAccount, sa.Id)
+		if err != nil {
+			annos, err := wrapError(err)
+			return nil, "", annos, err
+		}
+		// Service accounts whose owner was deleted have none
+		if sa.Owner != "" {
+			profile["owner_id"] = sa.Owner
+		}
+
+		serviceAccount, err := sdkResource.NewUserResource(sa.Name, serviceAccountResourceType, sa.Id, []sdkResource.UserTraitOption{
+			sdkResource.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_SERVICE),
+			sdkResource.WithUserProfile(profile),
+		},
+			sdkResource.WithParentResourceID(parentResourceID),
+			sdkResource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: apiKeyResourceType.Id}),
+			o.console.link(serviceAccountResourceType, sa.Id),
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		ret = append(ret, serviceAccount)
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+// Entitlements returns an owner entitlement, grantable to users.
+func (o *serviceAccountBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	owner := sdkEntitlement.NewPermissionEntitlement(resource, serviceAccountOwnerEntitlement, sdkEntitlement.WithGrantableTo(userResourceType))
+	owner.Description = fmt.Sprintf("Is the owner of the %s service account", resource.DisplayName)
+
+	return []*v2.Entitlement{owner}, "", nil, nil
+}
+
+// Grants returns a grant of the owner entitlement to the user that owns the service account, if any.
+func (o *serviceAccountBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	sa, err := o.client.GetServiceAccount(ctx, resource.Id.Resource)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	// Service accounts whose owner was deleted have none
+	if sa.Owner == "" {
+		return nil, "", nil, nil
+	}
+
+	ownerID, err := sdkResource.NewResourceID(userResourceType, sa.Owner)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return []*v2.Grant{sdkGrant.NewGrant(resource, serviceAccountOwnerEntitlement, ownerID)}, "", nil, nil
+}
+
+func newServiceAccountBuilder(client client.Backend, pageSize int, changes changeSet, console consoleURL, tenant tenantScope) *serviceAccountBuilder {
+	return &serviceAccountBuilder{
+		client:   client,
+		pageSize: pageSize,
+		changes:  changes,
+		console:  console,
+		tenant:   tenant,
+	}
+}
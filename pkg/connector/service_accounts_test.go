@@ -0,0 +1,75 @@
+package connector_test
+
+import (
+	"context"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+// TestServiceAccountOwnerProfile checks that only service accounts with an owner have an owner_id in their profile.
+func TestServiceAccountOwnerProfile(t *testing.T) {
+	ctx := context.Background()
+	b := client.NewMemoryBackend(true)
+	defer b.Close()
+
+	serviceAccounts, _, err := b.ListServiceAccounts(ctx, client.PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(serviceAccounts) < 2 {
+		t.Fatalf("%d seeded service accounts, want at least 2", len(serviceAccounts))
+	}
+
+	// Deleting the owner of the first service account leaves it without one
+	orphan := serviceAccounts[0]
+	err = b.DeleteUser(ctx, orphan.Owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owners := make(map[string]string, len(serviceAccounts))
+	for _, sa := range serviceAccounts {
+		sa, err = b.GetServiceAccount(ctx, sa.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[sa.Id] = sa.Owner
+	}
+	if owners[orphan.Id] != "" {
+		t.Fatalf("service account %s still owned by %s after its owner was deleted", orphan.Id, owners[orphan.Id])
+	}
+
+	c1z := syncToC1Z(ctx, t, b)
+	defer c1z.Close()
+
+	synced := 0
+	for _, m := range listResources(ctx, t, c1z) {
+		r := m.(*v2.Resource)
+		owner, ok := owners[r.GetId().GetResource()]
+		if !ok {
+			continue
+		}
+		synced++
+
+		trait := &v2.UserTrait{}
+		annos := annotations.Annotations(r.GetAnnotations())
+		_, err = annos.Pick(trait)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ownerID, ok := trait.GetProfile().GetFields()["owner_id"]
+		switch {
+		case owner == "" && ok:
+			t.Errorf("service account %s without an owner has owner_id %q", r.GetId().GetResource(), ownerID.GetStringValue())
+		case owner != "" && ownerID.GetStringValue() != owner:
+			t.Errorf("service account %s has owner_id %q, want %s", r.GetId().GetResource(), ownerID.GetStringValue(), owner)
+		}
+	}
+	if synced != len(owners) {
+		t.Errorf("synced %d service accounts, want %d", synced, len(owners))
+	}
+}
@@ -0,0 +1,98 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// tenantChildResourceTypes are the resource types synced as children of the tenant in tenant mode. API keys stay
+// children of their service account.
+var tenantChildResourceTypes = []*v2.ResourceType{
+	userResourceType,
+	groupResourceType,
+	roleResourceType,
+	projectResourceType,
+	permissionResourceType,
+	serviceAccountResourceType,
+}
+
+// tenantScope is whether the connector runs in tenant mode, syncing the tenant of its backend as the only top-level
+// resource with every other resource below it.
+type tenantScope bool
+
+// skip reports whether a List call of one of the tenantChildResourceTypes must return nothing. In tenant mode, their
+// resources are only listed under the tenant resource.
+func (t tenantScope) skip(parentResourceID *v2.ResourceId) bool {
+	return bool(t) && parentResourceID.GetResourceType() != tenantResourceType.Id
+}
+
+// parent returns the ID of the tenant resource that resources created by the connector are children of, nil outside
+// of tenant mode.
+func (t tenantScope) parent(ctx context.Context, backend client.Backend) (*v2.ResourceId, error) {
+	if !t {
+		return nil, nil
+	}
+
+	tenant, err := backend.GetTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdkResource.NewResourceID(tenantResourceType, tenant.Id)
+}
+
+type tenantBuilder struct {
+	client client.Backend
+}
+
+func (o *tenantBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return tenantResourceType
+}
+
+// List returns the tenant of the backend as a resource object, with the users, groups, roles, projects, permissions
+// and service accounts of the tenant as its child resources.
+// The tenant is always returned, even given a sync token, so that the resources that changed are listed below it.
+func (o *tenantBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID != nil {
+		return nil, "", nil, nil
+	}
+
+	tenant, err := o.client.GetTenant(ctx)
+	if err != nil {
+		annos, err := wrapError(err)
+		return nil, "", annos, err
+	}
+
+	opts := make([]sdkResource.ResourceOption, 0, len(tenantChildResourceTypes))
+	for _, rt := range tenantChildResourceTypes {
+		opts = append(opts, sdkResource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: rt.Id}))
+	}
+
+	resource, err := sdkResource.NewResource(tenant.Id, tenantResourceType, tenant.Id, opts...)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return []*v2.Resource{resource}, "", nil, nil
+}
+
+// Entitlements always returns an empty slice, access is granted to the resources of a tenant rather than the tenant.
+func (o *tenantBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice, tenants have no entitlements.
+func (o *tenantBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newTenantBuilder(client client.Backend) *tenantBuilder {
+	return &tenantBuilder{
+		client: client,
+	}
+}
@@ -16,7 +16,11 @@ import (
 )
 
 type userBuilder struct {
-	client *client.Client
+	client   client.Backend
+	pageSize int
+	changes  changeSet
+	console  consoleURL
+	tenant   tenantScope
 }
 
 func (o *userBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -24,18 +28,52 @@ func (o *userBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 }
 
 // List returns all the users from the database as resource objects.
-// Users include a UserTrait because they are the 'shape' of a standard user.
+// Users include a UserTrait because they are the 'shape' of a standard user. The trait carries the user's last login
+// and MFA status.
+// Given a sync token, only the users that changed since the sync that reported it are returned.
 func (o *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	users, err := o.client.ListUsers(ctx)
+	if o.tenant.skip(parentResourceID) {
+		return nil, "", nil, nil
+	}
+
+	users, nextPageToken, err := o.client.ListUsers(ctx, pageOptions(pToken, o.pageSize))
 	if err != nil {
-		return nil, "", nil, err
+		annos, err := wrapError(err)
+		return nil, "", annos, err
 	}
 
 	var ret []*v2.Resource
 	for _, u := range users {
-		userResource, err := sdkResource.NewUserResource(u.Name, userResourceType, u.Id, []sdkResource.UserTraitOption{
+		if !o.changes.changed(client.ResourceTypeUser, u.Id) {
+			continue
+		}
+
+		// The account state carries the login activity of the user
+		state, err := o.client.GetAccountState(ctx, u.Id)
+		if err != nil {
+			annos, err := wrapError(err)
+			return nil, "", annos, err
+		}
+
+		profile, err := resourceProfile(ctx, o.client, client.ResourceTypeUser, u.Id)
+		if err != nil {
+			annos, err := wrapError(err)
+			return nil, "", annos, err
+		}
+
+		traitOpts := []sdkResource.UserTraitOption{
 			sdkResource.WithEmail(u.Email, true),
-		}, sdkResource.WithParentResourceID(parentResourceID))
+			sdkResource.WithUserProfile(profile),
+			sdkResource.WithMFAStatus(&v2.UserTrait_MFAStatus{MfaEnabled: state.MFAEnrolled}),
+		}
+		if state.LastLoginAt != nil {
+			traitOpts = append(traitOpts, sdkResource.WithLastLogin(*state.LastLoginAt))
+		}
+
+		userResource, err := sdkResource.NewUserResource(u.Name, userResourceType, u.Id, traitOpts,
+			sdkResource.WithParentResourceID(parentResourceID),
+			o.console.link(userResourceType, u.Id),
+		)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -43,7 +81,7 @@ func (o *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		ret = append(ret, userResource)
 	}
 
-	return ret, "", nil, nil
+	return ret, nextPageToken, nil, nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -56,49 +94,55 @@ func (o *userBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 	return nil, "", nil, nil
 }
 
+// RotateCapabilityDetails advertises random passwords. Rotate also clears the password when asked for no password, but
+// that isn't advertised since API keys can't do it.
 func (r *userBuilder) RotateCapabilityDetails(ctx context.Context) (*v2.CredentialDetailsCredentialRotation, annotations.Annotations, error) {
-	return &v2.CredentialDetailsCredentialRotation{
-		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
-			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
-			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
-		},
-		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
-	}, nil, nil
+	return credentialRotationDetails(), nil, nil
 }
 
 func (o *userBuilder) Rotate(ctx context.Context, resourceId *v2.ResourceId, credentialOptions *v2.CredentialOptions) ([]*v2.PlaintextData, annotations.Annotations, error) {
-	if resourceId.ResourceType != roleResourceType.Id {
-		return nil, nil, fmt.Errorf("baton-postgres: non-role/user resource passed to rotate credentials")
+	if resourceId.ResourceType != userResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-demo: non-user resource passed to rotate credentials")
 	}
 
 	user, err := o.client.GetUser(ctx, resourceId.Resource)
 	if err != nil {
-		return nil, nil, err
+		annos, err := wrapError(err)
+		return nil, annos, err
 	}
 
 	var plainTextPassword string
-	var ptd *v2.PlaintextData
+	var plaintexts []*v2.PlaintextData
 	if credentialOptions.GetRandomPassword() != nil {
 		plainTextPassword, err = crypto.GeneratePassword(credentialOptions)
 		if err != nil {
 			return nil, nil, err
 		}
-		ptd = &v2.PlaintextData{
+		plaintexts = append(plaintexts, &v2.PlaintextData{
 			Name:  "password",
 			Bytes: []byte(plainTextPassword),
-		}
+		})
 	}
 
 	err = o.client.ChangePassword(ctx, user.Id, plainTextPassword)
 	if err != nil {
-		return nil, nil, err
+		annos, err := wrapError(err)
+		return nil, annos, err
 	}
 
-	return []*v2.PlaintextData{ptd}, nil, nil
+	return plaintexts, nil, nil
 }
 
 func (o *userBuilder) makeResource(ctx context.Context, user *client.User) (*v2.Resource, error) {
-	return sdkResource.NewUserResource(user.Name, userResourceType, user.Id, nil)
+	parentResourceID, err := o.tenant.parent(ctx, o.client)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdkResource.NewUserResource(user.Name, userResourceType, user.Id, nil,
+		sdkResource.WithParentResourceID(parentResourceID),
+		o.console.link(userResourceType, user.Id),
+	)
 }
 
 func (o *userBuilder) CreateAccountCapabilityDetails(ctx context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
@@ -119,24 +163,25 @@ func (o *userBuilder) CreateAccount(
 	l := ctxzap.Extract(ctx)
 	var plainTextPassword string
 	var err error
-	var ptd *v2.PlaintextData
+	var plaintexts []*v2.PlaintextData
 	if credentialOptions.GetRandomPassword() != nil {
 		l.Info("Generating random password")
 		plainTextPassword, err = crypto.GeneratePassword(credentialOptions)
 		if err != nil {
 			return nil, nil, nil, err
 		}
-		ptd = &v2.PlaintextData{
+		plaintexts = append(plaintexts, &v2.PlaintextData{
 			Name:  "password",
 			Bytes: []byte(plainTextPassword),
-		}
+		})
 	} else {
 		l.Info("No password generated")
 	}
 
-	createdUser, err := o.client.CreateUser(ctx, accountInfo.Login, accountInfo.Emails[0].String(), plainTextPassword)
+	createdUser, err := o.client.CreateUser(ctx, accountInfo.Login, primaryEmail(accountInfo), plainTextPassword)
 	if err != nil {
-		return nil, nil, nil, err
+		annos, err := wrapError(err)
+		return nil, nil, annos, err
 	}
 
 	resource, err := o.makeResource(ctx, createdUser)
@@ -146,37 +191,74 @@ func (o *userBuilder) CreateAccount(
 
 	return &v2.CreateAccountResponse_SuccessResult{
 		Resource: resource,
-	}, []*v2.PlaintextData{ptd}, nil, nil
+	}, plaintexts, nil, nil
 }
 
-func (o *userBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
-	return nil, nil, fmt.Errorf("baton-demo: role creation not supported")
+// primaryEmail returns the primary email address of an account, or its first one if none is marked primary.
+func primaryEmail(accountInfo *v2.AccountInfo) string {
+	for _, e := range accountInfo.GetEmails() {
+		if e.GetIsPrimary() {
+			return e.GetAddress()
+		}
+	}
+	if len(accountInfo.GetEmails()) > 0 {
+		return accountInfo.GetEmails()[0].GetAddress()
+	}
+	return ""
 }
 
-func (o *userBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
-	if resourceId.ResourceType != userResourceType.Id {
-		return nil, fmt.Errorf("baton-demo: non-user resource passed to role delete")
+// Create creates a user named after the display name of the resource, with the primary email of its user trait. The
+// user has no password until one is rotated in.
+func (o *userBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	if resource.GetId().GetResourceType() != userResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-demo: non-user resource passed to user create")
+	}
+
+	email := ""
+	trait, err := sdkResource.GetUserTrait(resource)
+	if err == nil {
+		for _, e := range trait.GetEmails() {
+			if e.GetIsPrimary() || email == "" {
+				email = e.GetAddress()
+			}
+		}
 	}
 
-	pgRole, err := o.client.GetUser(ctx, resourceId.Resource)
+	user, err := o.client.CreateUser(ctx, resource.GetDisplayName(), email, "")
 	if err != nil {
-		return nil, err
+		annos, err := wrapError(err)
+		return nil, annos, err
 	}
 
-	err = o.client.DeleteUser(ctx, pgRole.Name)
-	return nil, err
-}
+	created, err := o.makeResource(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
 
-func (o *userBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
-	return nil, nil, nil
+	return created, nil, nil
 }
 
-func (o *userBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
-	return nil, nil
+func (o *userBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if resourceId.ResourceType != userResourceType.Id {
+		return nil, fmt.Errorf("baton-demo: non-user resource passed to user delete")
+	}
+
+	user, err := o.client.GetUser(ctx, resourceId.Resource)
+	if err != nil {
+		annos, err := wrapError(err)
+		return annos, err
+	}
+
+	err = o.client.DeleteUser(ctx, user.Id)
+	return wrapError(err)
 }
 
-func newUserBuilder(client *client.Client) *userBuilder {
+func newUserBuilder(client client.Backend, pageSize int, changes changeSet, console consoleURL, tenant tenantScope) *userBuilder {
 	return &userBuilder{
-		client: client,
+		client:   client,
+		pageSize: pageSize,
+		changes:  changes,
+		console:  console,
+		tenant:   tenant,
 	}
 }
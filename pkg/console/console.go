@@ -0,0 +1,728 @@
+package console
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+// Server is a small web console over the demo data. It browses every resource, edits memberships, role assignments
+// and project access, creates and deletes users, and shows the change log.
+// Pages live under the same paths the connector links resources to, such as /users/{id}.
+type Server struct {
+	backend client.Backend
+	mux     *http.ServeMux
+	pages   map[string]*template.Template
+}
+
+// New returns a console serving the data of the given backend.
+func New(backend client.Backend) (*Server, error) {
+	s := &Server{
+		backend: backend,
+		mux:     http.NewServeMux(),
+		pages:   make(map[string]*template.Template),
+	}
+
+	for _, page := range []string{
+		"users", "user", "groups", "group", "roles", "role", "projects", "project",
+		"permissions", "permission", "service_accounts", "service_account", "api_key", "events", "error",
+	} {
+		t, err := template.New(page).Funcs(funcs).ParseFS(templates, "templates/layout.html", "templates/"+page+".html")
+		if err != nil {
+			return nil, fmt.Errorf("baton-demo: parsing console page %s: %w", page, err)
+		}
+		s.pages[page] = t
+	}
+
+	s.mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/users", http.StatusFound)
+	})
+
+	s.handle("GET /users", s.listUsers)
+	s.handle("POST /users", s.createUser)
+	s.handle("GET /users/{id}", s.getUser)
+	s.handle("POST /users/{id}/delete", s.deleteUser)
+
+	s.handle("GET /groups", s.listGroups)
+	s.handle("GET /groups/{id}", s.getGroup)
+	s.handle("POST /groups/{id}/members", s.addGroupMember)
+	s.handle("POST /groups/{id}/members/{type}/{principal}/delete", s.removeGroupMember)
+	s.handle("POST /groups/{id}/admins", s.addGroupAdmin)
+	s.handle("POST /groups/{id}/admins/{principal}/delete", s.removeGroupAdmin)
+
+	s.handle("GET /roles", s.listRoles)
+	s.handle("GET /roles/{id}", s.getRole)
+	s.handle("POST /roles/{id}/assignments", s.assignRole)
+	s.handle("POST /roles/{id}/assignments/{type}/{principal}/delete", s.unassignRole)
+
+	s.handle("GET /projects", s.listProjects)
+	s.handle("GET /projects/{id}", s.getProject)
+	s.handle("POST /projects/{id}/access", s.grantProjectAccess)
+	s.handle("POST /projects/{id}/access/{principal}/delete", s.revokeProjectAccess)
+
+	s.handle("GET /permissions", s.listPermissions)
+	s.handle("GET /permissions/{id}", s.getPermission)
+	s.handle("GET /service-accounts", s.listServiceAccounts)
+	s.handle("GET /service-accounts/{id}", s.getServiceAccount)
+	s.handle("GET /api-keys/{id}", s.getAPIKey)
+
+	s.handle("GET /events", s.listEvents)
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !sameOrigin(r) {
+		s.renderError(w, http.StatusForbidden, errors.New("cross-origin requests cannot change the demo data"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// sameOrigin reports whether a request comes from a page of the console, so that the forms of other sites cannot
+// change the demo data through the browser of someone using the console. Browsers tell where a request comes from in
+// the Sec-Fetch-Site, Origin or Referer headers; requests without any of them are not sent by a browser's form and are
+// let through.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "":
+	case "same-origin", "none":
+		return true
+	default:
+		return false
+	}
+
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Referer()
+	}
+	if source == "" {
+		return true
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host
+}
+
+var funcs = template.FuncMap{
+	// dict builds the data of a nested template from key and value pairs.
+	"dict": func(pairs ...interface{}) (map[string]interface{}, error) {
+		if len(pairs)%2 != 0 {
+			return nil, errors.New("dict expects key and value pairs")
+		}
+		m := make(map[string]interface{}, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			key, ok := pairs[i].(string)
+			if !ok {
+				return nil, fmt.Errorf("dict key %v is not a string", pairs[i])
+			}
+			m[key] = pairs[i+1]
+		}
+		return m, nil
+	},
+}
+
+// badRequestError is returned by handlers for forms that are missing fields.
+type badRequestError struct {
+	msg string
+}
+
+func (e *badRequestError) Error() string {
+	return e.msg
+}
+
+// handle registers a handler that either writes a response or returns an error, which is rendered as an error page.
+func (s *Server) handle(pattern string, fn func(w http.ResponseWriter, r *http.Request) error) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		err := fn(w, r)
+		if err == nil {
+			return
+		}
+
+		status := http.StatusInternalServerError
+		var badRequest *badRequestError
+		var immutable *client.ImmutableError
+		switch {
+		case errors.Is(err, client.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.As(err, &badRequest):
+			status = http.StatusBadRequest
+		case errors.As(err, &immutable), errors.Is(err, client.ErrMembershipCycle):
+			status = http.StatusConflict
+		}
+
+		s.renderError(w, status, err)
+	})
+}
+
+// renderError writes an error page with the given status.
+func (s *Server) renderError(w http.ResponseWriter, status int, err error) {
+	w.WriteHeader(status)
+	_ = s.pages["error"].ExecuteTemplate(w, "layout", map[string]interface{}{
+		"Title":  http.StatusText(status),
+		"Status": status,
+		"Error":  err.Error(),
+	})
+}
+
+// render writes a page, titled title, with the given data.
+func (s *Server) render(w http.ResponseWriter, page, title string, data map[string]interface{}) error {
+	data["Title"] = title
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return s.pages[page].ExecuteTemplate(w, "layout", data)
+}
+
+// redirect sends the browser back to a page after a form was submitted.
+func redirect(w http.ResponseWriter, r *http.Request, format string, args ...interface{}) error {
+	http.Redirect(w, r, fmt.Sprintf(format, args...), http.StatusSeeOther)
+	return nil
+}
+
+// formValue returns a required form field.
+func formValue(r *http.Request, key string) (string, error) {
+	v := strings.TrimSpace(r.PostFormValue(key))
+	if v == "" {
+		return "", &badRequestError{msg: fmt.Sprintf("%s is required", key)}
+	}
+	return v, nil
+}
+
+// directory holds every user, group and role, which pages use to show names instead of IDs and to offer choices in
+// their forms.
+type directory struct {
+	Users  []*client.User
+	Groups []*client.Group
+	Roles  []*client.Role
+	Names  map[string]string
+}
+
+func (s *Server) loadDirectory(ctx context.Context) (*directory, error) {
+	users, _, err := s.backend.ListUsers(ctx, client.PageOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	groups, _, err := s.backend.ListGroups(ctx, client.PageOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	roles, _, err := s.backend.ListRoles(ctx, client.PageOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(users)+len(groups)+len(roles))
+	for _, u := range users {
+		names[u.Id] = u.Name
+	}
+	for _, g := range groups {
+		names[g.Id] = g.Name
+	}
+	for _, r := range roles {
+		names[r.Id] = r.Name
+	}
+
+	return &directory{
+		Users:  users,
+		Groups: groups,
+		Roles:  roles,
+		Names:  names,
+	}, nil
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) error {
+	users, _, err := s.backend.ListUsers(r.Context(), client.PageOptions{})
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "users", "Users", map[string]interface{}{
+		"Users": users,
+	})
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) error {
+	name, err := formValue(r, "name")
+	if err != nil {
+		return err
+	}
+
+	user, err := s.backend.CreateUser(r.Context(), name, strings.TrimSpace(r.PostFormValue("email")), r.PostFormValue("password"))
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/users/%s", user.Id)
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := s.backend.GetUser(ctx, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	state, err := s.backend.GetAccountState(ctx, user.Id)
+	if err != nil {
+		return err
+	}
+
+	attributes, err := s.backend.GetAttributes(ctx, client.ResourceTypeUser, user.Id)
+	if err != nil {
+		return err
+	}
+
+	dir, err := s.loadDirectory(ctx)
+	if err != nil {
+		return err
+	}
+
+	var memberOf, adminOf []*client.Group
+	for _, g := range dir.Groups {
+		if slices.Contains(g.Members, user.Id) {
+			memberOf = append(memberOf, g)
+		}
+		if slices.Contains(g.Admins, user.Id) {
+			adminOf = append(adminOf, g)
+		}
+	}
+
+	var roles []*client.Role
+	for _, role := range dir.Roles {
+		if slices.Contains(role.DirectAssignments, user.Id) {
+			roles = append(roles, role)
+		}
+	}
+
+	projects, _, err := s.backend.ListProjects(ctx, client.PageOptions{})
+	if err != nil {
+		return err
+	}
+	projects = slices.DeleteFunc(projects, func(p *client.Project) bool {
+		return p.Owner != user.Id
+	})
+
+	return s.render(w, "user", user.Name, map[string]interface{}{
+		"User":       user,
+		"State":      state,
+		"Attributes": attributes,
+		"MemberOf":   memberOf,
+		"AdminOf":    adminOf,
+		"Roles":      roles,
+		"Projects":   projects,
+	})
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) error {
+	err := s.backend.DeleteUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/users")
+}
+
+func (s *Server) listGroups(w http.ResponseWriter, r *http.Request) error {
+	groups, _, err := s.backend.ListGroups(r.Context(), client.PageOptions{})
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "groups", "Groups", map[string]interface{}{
+		"Groups": groups,
+	})
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	group, err := s.backend.GetGroup(ctx, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	members, err := s.backend.ListAssignments(ctx, client.ResourceTypeGroup, group.Id)
+	if err != nil {
+		return err
+	}
+
+	attributes, err := s.backend.GetAttributes(ctx, client.ResourceTypeGroup, group.Id)
+	if err != nil {
+		return err
+	}
+
+	dir, err := s.loadDirectory(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "group", group.Name, map[string]interface{}{
+		"Group":      group,
+		"Members":    members,
+		"Attributes": attributes,
+		"Directory":  dir,
+	})
+}
+
+func (s *Server) addGroupMember(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	groupID := r.PathValue("id")
+
+	principalType, principalID, err := principalValue(r)
+	if err != nil {
+		return err
+	}
+
+	switch principalType {
+	case client.ResourceTypeUser:
+		err = s.backend.GrantGroupMember(ctx, groupID, principalID)
+	default:
+		err = s.backend.GrantGroupMemberGroup(ctx, groupID, principalID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/groups/%s", groupID)
+}
+
+func (s *Server) removeGroupMember(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	groupID := r.PathValue("id")
+
+	var err error
+	switch r.PathValue("type") {
+	case client.ResourceTypeUser:
+		err = s.backend.RevokeGroupMember(ctx, groupID, r.PathValue("principal"))
+	case client.ResourceTypeGroup:
+		err = s.backend.RevokeGroupMemberGroup(ctx, groupID, r.PathValue("principal"))
+	default:
+		err = &badRequestError{msg: fmt.Sprintf("unknown member type %s", r.PathValue("type"))}
+	}
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/groups/%s", groupID)
+}
+
+func (s *Server) addGroupAdmin(w http.ResponseWriter, r *http.Request) error {
+	groupID := r.PathValue("id")
+
+	userID, err := formValue(r, "user_id")
+	if err != nil {
+		return err
+	}
+
+	err = s.backend.GrantGroupAdmin(r.Context(), groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/groups/%s", groupID)
+}
+
+func (s *Server) removeGroupAdmin(w http.ResponseWriter, r *http.Request) error {
+	groupID := r.PathValue("id")
+
+	err := s.backend.RevokeGroupAdmin(r.Context(), groupID, r.PathValue("principal"))
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/groups/%s", groupID)
+}
+
+func (s *Server) listRoles(w http.ResponseWriter, r *http.Request) error {
+	roles, _, err := s.backend.ListRoles(r.Context(), client.PageOptions{})
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "roles", "Roles", map[string]interface{}{
+		"Roles": roles,
+	})
+}
+
+func (s *Server) getRole(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	role, err := s.backend.GetRole(ctx, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	assignments, err := s.backend.ListAssignments(ctx, client.ResourceTypeRole, role.Id)
+	if err != nil {
+		return err
+	}
+
+	permissions := make([]*client.Permission, 0, len(role.Permissions))
+	for _, permissionID := range role.Permissions {
+		p, err := s.backend.GetPermission(ctx, permissionID)
+		if err != nil {
+			return err
+		}
+		permissions = append(permissions, p)
+	}
+
+	dir, err := s.loadDirectory(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "role", role.Name, map[string]interface{}{
+		"Role":        role,
+		"Assignments": assignments,
+		"Permissions": permissions,
+		"Directory":   dir,
+	})
+}
+
+func (s *Server) assignRole(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	roleID := r.PathValue("id")
+
+	principalType, principalID, err := principalValue(r)
+	if err != nil {
+		return err
+	}
+
+	switch principalType {
+	case client.ResourceTypeUser:
+		err = s.backend.GrantRole(ctx, principalID, roleID)
+	default:
+		err = s.backend.GrantRoleGroup(ctx, principalID, roleID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/roles/%s", roleID)
+}
+
+func (s *Server) unassignRole(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	roleID := r.PathValue("id")
+
+	var err error
+	switch r.PathValue("type") {
+	case client.ResourceTypeUser:
+		err = s.backend.RevokeRole(ctx, r.PathValue("principal"), roleID)
+	case client.ResourceTypeGroup:
+		err = s.backend.RevokeRoleGroup(ctx, r.PathValue("principal"), roleID)
+	default:
+		err = &badRequestError{msg: fmt.Sprintf("unknown principal type %s", r.PathValue("type"))}
+	}
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/roles/%s", roleID)
+}
+
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request) error {
+	projects, _, err := s.backend.ListProjects(r.Context(), client.PageOptions{})
+	if err != nil {
+		return err
+	}
+
+	dir, err := s.loadDirectory(r.Context())
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "projects", "Projects", map[string]interface{}{
+		"Projects":  projects,
+		"Directory": dir,
+	})
+}
+
+func (s *Server) getProject(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	project, err := s.backend.GetProject(ctx, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	access, err := s.backend.ListAssignments(ctx, client.ResourceTypeProject, project.Id)
+	if err != nil {
+		return err
+	}
+
+	dir, err := s.loadDirectory(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "project", project.Name, map[string]interface{}{
+		"Project":   project,
+		"Access":    access,
+		"Directory": dir,
+	})
+}
+
+func (s *Server) grantProjectAccess(w http.ResponseWriter, r *http.Request) error {
+	projectID := r.PathValue("id")
+
+	groupID, err := formValue(r, "group_id")
+	if err != nil {
+		return err
+	}
+
+	err = s.backend.GrantProjectAccess(r.Context(), projectID, groupID)
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/projects/%s", projectID)
+}
+
+func (s *Server) revokeProjectAccess(w http.ResponseWriter, r *http.Request) error {
+	projectID := r.PathValue("id")
+
+	err := s.backend.RevokeProjectAccess(r.Context(), projectID, r.PathValue("principal"))
+	if err != nil {
+		return err
+	}
+
+	return redirect(w, r, "/projects/%s", projectID)
+}
+
+func (s *Server) listPermissions(w http.ResponseWriter, r *http.Request) error {
+	permissions, _, err := s.backend.ListPermissions(r.Context(), client.PageOptions{})
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "permissions", "Permissions", map[string]interface{}{
+		"Permissions": permissions,
+	})
+}
+
+func (s *Server) getPermission(w http.ResponseWriter, r *http.Request) error {
+	permission, err := s.backend.GetPermission(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	dir, err := s.loadDirectory(r.Context())
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "permission", permission.Name, map[string]interface{}{
+		"Permission": permission,
+		"Directory":  dir,
+	})
+}
+
+func (s *Server) listServiceAccounts(w http.ResponseWriter, r *http.Request) error {
+	serviceAccounts, _, err := s.backend.ListServiceAccounts(r.Context(), client.PageOptions{})
+	if err != nil {
+		return err
+	}
+
+	dir, err := s.loadDirectory(r.Context())
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "service_accounts", "Service Accounts", map[string]interface{}{
+		"ServiceAccounts": serviceAccounts,
+		"Directory":       dir,
+	})
+}
+
+func (s *Server) getServiceAccount(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	serviceAccount, err := s.backend.GetServiceAccount(ctx, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	keys, _, err := s.backend.ListAPIKeys(ctx, serviceAccount.Id, client.PageOptions{})
+	if err != nil {
+		return err
+	}
+
+	dir, err := s.loadDirectory(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "service_account", serviceAccount.Name, map[string]interface{}{
+		"ServiceAccount": serviceAccount,
+		"Keys":           keys,
+		"Directory":      dir,
+	})
+}
+
+func (s *Server) getAPIKey(w http.ResponseWriter, r *http.Request) error {
+	key, err := s.backend.GetAPIKey(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	return s.render(w, "api_key", key.Name, map[string]interface{}{
+		"Key": key,
+	})
+}
+
+// listEvents shows the change log, most recent change first.
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request) error {
+	changes, _, err := s.backend.ListChanges(r.Context(), 0)
+	if err != nil {
+		return err
+	}
+	slices.Reverse(changes)
+
+	return s.render(w, "events", "Event Log", map[string]interface{}{
+		"Changes": changes,
+		"Paths":   resourcePaths,
+	})
+}
+
+// resourcePaths maps resource types to the path of their pages.
+var resourcePaths = map[string]string{
+	client.ResourceTypeUser:           "users",
+	client.ResourceTypeGroup:          "groups",
+	client.ResourceTypeRole:           "roles",
+	client.ResourceTypeProject:        "projects",
+	client.ResourceTypePermission:     "permissions",
+	client.ResourceTypeServiceAccount: "service-accounts",
+	client.ResourceTypeAPIKey:         "api-keys",
+}
+
+// principalValue reads the principal a form grants something to, which is a "user:{id}" or "group:{id}" value.
+func principalValue(r *http.Request) (string, string, error) {
+	v, err := formValue(r, "principal")
+	if err != nil {
+		return "", "", err
+	}
+
+	principalType, principalID, ok := strings.Cut(v, ":")
+	if !ok || principalID == "" || (principalType != client.ResourceTypeUser && principalType != client.ResourceTypeGroup) {
+		return "", "", &badRequestError{msg: fmt.Sprintf("invalid principal %q", v)}
+	}
+
+	return principalType, principalID, nil
+}
@@ -0,0 +1,348 @@
+package console_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	"github.com/conductorone/baton-demo/pkg/console"
+)
+
+const (
+	alice     = "2IC0Wn5oRQqVVn3COFl1O1zSzV6"
+	bob       = "2IC0WoNfqUPT7mgO4FOaViIxBrR"
+	dan       = "2IC0Wn7DaxV1xqDpdg7jJRiPtCp"
+	engineers = "2IC0WmAPkihbFdZhEPsch5N5WNO"
+	sales     = "2IC0WjepYDBsRp6b7cqrumGsVGt"
+	platform  = "3Kscz1E7u7nTtsV6jSrJGmHvJI6"
+	editor    = "2IC0WmaHecJdzo5jYnQiTh2BVlB"
+	admin     = "3KseziSY8ObLAsq6ix3KRv5i7yz"
+	productX  = "2IC0WqENS0dCRHiJ0YvPAidl0D5"
+)
+
+// origin is where httptest requests are sent to, and where the forms of the console are submitted from.
+const origin = "http://example.com"
+
+func newConsole(t *testing.T) (*console.Server, client.Backend) {
+	t.Helper()
+
+	b := client.NewMemoryBackend(true)
+	s, err := console.New(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, b
+}
+
+func get(t *testing.T, s *console.Server, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// post submits a form as a browser showing a page of the console would, with the given headers on top.
+func post(t *testing.T, s *console.Server, target string, form url.Values, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Origin", origin)
+	for k, v := range header {
+		if v == "" {
+			r.Header.Del(k)
+			continue
+		}
+		r.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestPages(t *testing.T) {
+	s, _ := newConsole(t)
+
+	for _, tc := range []struct {
+		target string
+		want   string
+	}{
+		{"/users", "Alice"},
+		{"/users/" + alice, "Staff Engineer"},
+		{"/groups", "Engineers"},
+		{"/groups/" + engineers, "Platform"},
+		{"/roles", "Editor"},
+		{"/roles/" + editor, "documents:write"},
+		{"/projects", "Product X"},
+		{"/projects/" + productX, "Engineers"},
+		{"/permissions", "documents:read"},
+		{"/permissions/3KseR1G1fb9d1QbA5t3pfX7enWZ", "View documents"},
+		{"/service-accounts", "ci-deployer"},
+		{"/service-accounts/3KscitOHIUhJDGT7ypNvY3q17zI", "github-actions"},
+		{"/api-keys/3KscixK6defCCFa191nTC73ERDe", "github-actions"},
+		{"/events", "Event Log"},
+	} {
+		w := get(t, s, tc.target)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d, want %d", tc.target, w.Code, http.StatusOK)
+			continue
+		}
+		if !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("GET %s: page doesn't show %q", tc.target, tc.want)
+		}
+	}
+
+	w := get(t, s, "/")
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/users" {
+		t.Errorf("GET /: status %d to %q, want a redirect to /users", w.Code, w.Header().Get("Location"))
+	}
+
+	for _, target := range []string{"/users/missing", "/groups/missing", "/roles/missing", "/projects/missing", "/api-keys/missing"} {
+		w := get(t, s, target)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want %d", target, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestForms(t *testing.T) {
+	ctx := context.Background()
+	s, b := newConsole(t)
+
+	// Each form redirects back to the page it was submitted from, and its change is checked there
+	for _, tc := range []struct {
+		name     string
+		target   string
+		form     url.Values
+		location string
+		check    func() bool
+	}{
+		{
+			name:     "add a user to a group",
+			target:   "/groups/" + sales + "/members",
+			form:     url.Values{"principal": {"user:" + alice}},
+			location: "/groups/" + sales,
+			check: func() bool {
+				g, err := b.GetGroup(ctx, sales)
+				return err == nil && slices.Contains(g.Members, alice)
+			},
+		},
+		{
+			name:     "remove a user from a group",
+			target:   "/groups/" + sales + "/members/user/" + alice + "/delete",
+			location: "/groups/" + sales,
+			check: func() bool {
+				g, err := b.GetGroup(ctx, sales)
+				return err == nil && !slices.Contains(g.Members, alice)
+			},
+		},
+		{
+			name:     "add a group to a group",
+			target:   "/groups/" + sales + "/members",
+			form:     url.Values{"principal": {"group:" + platform}},
+			location: "/groups/" + sales,
+			check: func() bool {
+				g, err := b.GetGroup(ctx, sales)
+				return err == nil && slices.Contains(g.MemberGroups, platform)
+			},
+		},
+		{
+			name:     "remove a group from a group",
+			target:   "/groups/" + sales + "/members/group/" + platform + "/delete",
+			location: "/groups/" + sales,
+			check: func() bool {
+				g, err := b.GetGroup(ctx, sales)
+				return err == nil && !slices.Contains(g.MemberGroups, platform)
+			},
+		},
+		{
+			name:     "add a group admin",
+			target:   "/groups/" + sales + "/admins",
+			form:     url.Values{"user_id": {dan}},
+			location: "/groups/" + sales,
+			check: func() bool {
+				g, err := b.GetGroup(ctx, sales)
+				return err == nil && slices.Contains(g.Admins, dan)
+			},
+		},
+		{
+			name:     "remove a group admin",
+			target:   "/groups/" + sales + "/admins/" + dan + "/delete",
+			location: "/groups/" + sales,
+			check: func() bool {
+				g, err := b.GetGroup(ctx, sales)
+				return err == nil && !slices.Contains(g.Admins, dan)
+			},
+		},
+		{
+			name:     "assign a role to a user",
+			target:   "/roles/" + editor + "/assignments",
+			form:     url.Values{"principal": {"user:" + dan}},
+			location: "/roles/" + editor,
+			check: func() bool {
+				r, err := b.GetRole(ctx, editor)
+				return err == nil && slices.Contains(r.DirectAssignments, dan)
+			},
+		},
+		{
+			name:     "unassign a role from a user",
+			target:   "/roles/" + editor + "/assignments/user/" + dan + "/delete",
+			location: "/roles/" + editor,
+			check: func() bool {
+				r, err := b.GetRole(ctx, editor)
+				return err == nil && !slices.Contains(r.DirectAssignments, dan)
+			},
+		},
+		{
+			name:     "assign a role to a group",
+			target:   "/roles/" + editor + "/assignments",
+			form:     url.Values{"principal": {"group:" + sales}},
+			location: "/roles/" + editor,
+			check: func() bool {
+				r, err := b.GetRole(ctx, editor)
+				return err == nil && slices.Contains(r.GroupAssignments, sales)
+			},
+		},
+		{
+			name:     "unassign a role from a group",
+			target:   "/roles/" + editor + "/assignments/group/" + sales + "/delete",
+			location: "/roles/" + editor,
+			check: func() bool {
+				r, err := b.GetRole(ctx, editor)
+				return err == nil && !slices.Contains(r.GroupAssignments, sales)
+			},
+		},
+		{
+			name:     "grant a group access to a project",
+			target:   "/projects/" + productX + "/access",
+			form:     url.Values{"group_id": {sales}},
+			location: "/projects/" + productX,
+			check: func() bool {
+				p, err := b.GetProject(ctx, productX)
+				return err == nil && slices.Contains(p.GroupAssignments, sales)
+			},
+		},
+		{
+			name:     "revoke a group's access to a project",
+			target:   "/projects/" + productX + "/access/" + sales + "/delete",
+			location: "/projects/" + productX,
+			check: func() bool {
+				p, err := b.GetProject(ctx, productX)
+				return err == nil && !slices.Contains(p.GroupAssignments, sales)
+			},
+		},
+		{
+			name:     "delete a user",
+			target:   "/users/" + bob + "/delete",
+			location: "/users",
+			check: func() bool {
+				_, err := b.GetUser(ctx, bob)
+				return err != nil
+			},
+		},
+	} {
+		w := post(t, s, tc.target, tc.form, nil)
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != tc.location {
+			t.Errorf("%s: status %d to %q, want a redirect to %s", tc.name, w.Code, w.Header().Get("Location"), tc.location)
+		}
+		if !tc.check() {
+			t.Errorf("%s: not done", tc.name)
+		}
+	}
+
+	w := post(t, s, "/users", url.Values{"name": {"zoe"}, "email": {"zoe@example.org"}}, nil)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("create a user: status %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	userID := strings.TrimPrefix(w.Header().Get("Location"), "/users/")
+	user, err := b.GetUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("create a user: redirected to %s: %v", w.Header().Get("Location"), err)
+	}
+	if user.Name != "zoe" || user.Email != "zoe@example.org" {
+		t.Errorf("created user %s <%s>, want zoe <zoe@example.org>", user.Name, user.Email)
+	}
+}
+
+func TestFormErrors(t *testing.T) {
+	s, _ := newConsole(t)
+
+	for _, tc := range []struct {
+		target string
+		form   url.Values
+		want   int
+	}{
+		{"/users", url.Values{"email": {"nobody@example.org"}}, http.StatusBadRequest},
+		{"/groups/" + sales + "/members", url.Values{"principal": {"role:" + editor}}, http.StatusBadRequest},
+		{"/groups/" + sales + "/members", url.Values{"principal": {"user:"}}, http.StatusBadRequest},
+		{"/groups/" + sales + "/members/role/" + editor + "/delete", nil, http.StatusBadRequest},
+		{"/groups/" + sales + "/admins", nil, http.StatusBadRequest},
+		{"/roles/" + editor + "/assignments", nil, http.StatusBadRequest},
+		{"/roles/" + editor + "/assignments/role/" + admin + "/delete", nil, http.StatusBadRequest},
+		{"/projects/" + productX + "/access", nil, http.StatusBadRequest},
+		{"/users/missing/delete", nil, http.StatusNotFound},
+		// A group cannot contain itself
+		{"/groups/" + platform + "/members", url.Values{"principal": {"group:" + engineers}}, http.StatusConflict},
+		// The holders of system roles are managed by the demo
+		{"/roles/" + admin + "/assignments", url.Values{"principal": {"user:" + dan}}, http.StatusConflict},
+	} {
+		w := post(t, s, tc.target, tc.form, nil)
+		if w.Code != tc.want {
+			t.Errorf("POST %s %s: status %d, want %d", tc.target, tc.form.Encode(), w.Code, tc.want)
+		}
+	}
+}
+
+func TestCrossOriginForms(t *testing.T) {
+	ctx := context.Background()
+	s, b := newConsole(t)
+
+	for _, header := range []map[string]string{
+		{"Origin": "http://evil.example.org"},
+		{"Origin": "null"},
+		{"Origin": "", "Referer": "http://evil.example.org/attack.html"},
+		{"Origin": "", "Sec-Fetch-Site": "cross-site"},
+		{"Sec-Fetch-Site": "same-site"},
+	} {
+		w := post(t, s, "/groups/"+sales+"/members", url.Values{"principal": {"user:" + alice}}, header)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%v: status %d, want %d", header, w.Code, http.StatusForbidden)
+		}
+	}
+
+	g, err := b.GetGroup(ctx, sales)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(g.Members, alice) {
+		t.Error("a cross-origin form added a group member")
+	}
+
+	// Forms of the console itself, and clients that are not browsers, are let through
+	for _, header := range []map[string]string{
+		{"Sec-Fetch-Site": "same-origin"},
+		{"Origin": "", "Referer": origin + "/groups/" + sales},
+		{"Origin": ""},
+	} {
+		w := post(t, s, "/groups/"+sales+"/admins", url.Values{"user_id": {dan}}, header)
+		if w.Code != http.StatusSeeOther {
+			t.Errorf("%v: status %d, want %d", header, w.Code, http.StatusSeeOther)
+		}
+	}
+
+	// Pages can be linked to from anywhere
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set("Sec-Fetch-Site", "cross-site")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("cross-site link: status %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,537 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/dotc1z"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-demo/pkg/client"
+	"github.com/conductorone/baton-demo/pkg/connector"
+)
+
+// ErrAssertion is returned when an assertion of a scenario does not hold.
+var ErrAssertion = errors.New("assertion failed")
+
+// Report is the outcome of running a scenario.
+type Report struct {
+	Name   string        `json:"name,omitempty"`
+	Passed bool          `json:"passed"`
+	Steps  []*StepResult `json:"steps"`
+}
+
+// StepResult is the outcome of a single step. Steps after the first one that fails are not run.
+type StepResult struct {
+	Step   int    `json:"step"`
+	Action Action `json:"action"`
+	// At is the time of the clock of the scenario when the step ran.
+	At    time.Time `json:"at"`
+	Error string    `json:"error,omitempty"`
+	// C1Z is the path of the c1z written by a sync step, when the c1z files are kept.
+	C1Z string `json:"c1z,omitempty"`
+}
+
+type runner struct {
+	backend client.Backend
+	server  types.ConnectorServer
+	conn    *inProcess
+
+	// now is the clock of the scenario, which only moves when advanced.
+	now time.Time
+
+	workDir  string
+	c1zDir   string
+	syncs    int
+	lastSync string
+}
+
+// Run runs a scenario against a fresh copy of the demo data in a temporary database. The c1z of every sync is written
+// to outDir, or to the temporary directory, and removed with it, if outDir is empty.
+// It returns an error wrapping ErrAssertion if an assertion does not hold, and the report of the steps that ran.
+func Run(ctx context.Context, s *Scenario, outDir string) (*Report, error) {
+	workDir, err := os.MkdirTemp("", "baton-demo-scenario")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	backend, err := client.NewClient(filepath.Join(workDir, "baton-demo.db"), true)
+	if err != nil {
+		return nil, err
+	}
+	defer backend.Close()
+
+	demo, err := connector.New(ctx, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := connectorbuilder.NewConnector(ctx, demo)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &runner{
+		backend: backend,
+		server:  server,
+		now:     time.Now().UTC().Truncate(time.Second),
+		workDir: workDir,
+		c1zDir:  outDir,
+	}
+	if r.c1zDir == "" {
+		r.c1zDir = workDir
+	} else {
+		err = os.MkdirAll(r.c1zDir, 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.conn, err = serve(server, r.withTime)
+	if err != nil {
+		return nil, err
+	}
+	defer r.conn.Close()
+
+	report := &Report{Name: s.Name}
+	for i, step := range s.Steps {
+		result := &StepResult{
+			Step:   i + 1,
+			Action: step.Action,
+			At:     r.now,
+		}
+		report.Steps = append(report.Steps, result)
+
+		err := r.run(r.withTime(ctx), step, result)
+		if err != nil {
+			result.Error = err.Error()
+			return report, fmt.Errorf("baton-demo: step %d (%s): %w", i+1, step.Action, err)
+		}
+
+		ctxzap.Extract(ctx).Debug("ran scenario step", zap.Int("step", i+1), zap.String("action", string(step.Action)))
+	}
+	report.Passed = true
+
+	return report, nil
+}
+
+// withTime makes the backend treat the clock of the scenario as the current time.
+func (r *runner) withTime(ctx context.Context) context.Context {
+	return client.WithTime(ctx, r.now)
+}
+
+func (r *runner) run(ctx context.Context, step *Step, result *StepResult) error {
+	switch step.Action {
+	case ActionCreateUser:
+		_, err := r.backend.CreateUser(ctx, step.Name, step.Email, step.Password)
+		return err
+
+	case ActionDeleteUser:
+		userID, err := r.resolve(ctx, client.ResourceTypeUser, step.User)
+		if err != nil {
+			return err
+		}
+		return r.backend.DeleteUser(ctx, userID)
+
+	case ActionAddToGroup, ActionRemoveFromGroup:
+		return r.updateGroup(ctx, step)
+
+	case ActionAssignRole, ActionUnassignRole:
+		return r.updateRole(ctx, step)
+
+	case ActionGrantProjectAccess, ActionRevokeProjectAccess:
+		return r.updateProject(ctx, step)
+
+	case ActionTransferOwner:
+		projectID, err := r.resolve(ctx, client.ResourceTypeProject, step.Project)
+		if err != nil {
+			return err
+		}
+		userID, err := r.resolve(ctx, client.ResourceTypeUser, step.User)
+		if err != nil {
+			return err
+		}
+		return r.backend.TransferProjectOwnership(ctx, projectID, userID)
+
+	case ActionGrant:
+		return r.grant(ctx, step)
+
+	case ActionRevoke:
+		return r.revoke(ctx, step)
+
+	case ActionSync:
+		r.syncs++
+		name := step.Name
+		if name == "" {
+			name = "sync"
+		}
+		path := filepath.Join(r.c1zDir, fmt.Sprintf("%02d-%s.c1z", r.syncs, name))
+
+		err := r.conn.sync(ctx, path, r.workDir)
+		if err != nil {
+			return err
+		}
+		r.lastSync = path
+		if r.c1zDir != r.workDir {
+			result.C1Z = path
+		}
+		return nil
+
+	case ActionAssertGrant, ActionAssertNoGrant:
+		return r.assert(ctx, step)
+
+	case ActionAdvance:
+		r.now = r.now.Add(step.Duration)
+		return nil
+
+	case ActionReapExpired:
+		_, err := client.ReapExpired(ctx, r.backend)
+		return err
+	}
+
+	return fmt.Errorf("unknown action %q", step.Action)
+}
+
+// assignmentOptions returns the options of an assignment made by a step, which is recorded as simulated.
+func (r *runner) assignmentOptions(step *Step) []client.GrantOption {
+	opts := []client.GrantOption{client.WithSource(client.SourceSimulation)}
+	if step.ExpiresIn > 0 {
+		opts = append(opts, client.WithExpiry(r.now.Add(step.ExpiresIn)))
+	}
+	if step.Reason != "" {
+		opts = append(opts, client.WithReason(step.Reason))
+	}
+	return opts
+}
+
+func (r *runner) updateGroup(ctx context.Context, step *Step) error {
+	groupID, err := r.resolve(ctx, client.ResourceTypeGroup, step.Group)
+	if err != nil {
+		return err
+	}
+	principalType, principalID, err := r.resolvePrincipal(ctx, step.Principal)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case step.Action == ActionAddToGroup && principalType == client.ResourceTypeUser:
+		return r.backend.GrantGroupMember(ctx, groupID, principalID, r.assignmentOptions(step)...)
+	case step.Action == ActionAddToGroup:
+		return r.backend.GrantGroupMemberGroup(ctx, groupID, principalID, r.assignmentOptions(step)...)
+	case principalType == client.ResourceTypeUser:
+		return r.backend.RevokeGroupMember(ctx, groupID, principalID)
+	default:
+		return r.backend.RevokeGroupMemberGroup(ctx, groupID, principalID)
+	}
+}
+
+func (r *runner) updateRole(ctx context.Context, step *Step) error {
+	roleID, err := r.resolve(ctx, client.ResourceTypeRole, step.Role)
+	if err != nil {
+		return err
+	}
+	principalType, principalID, err := r.resolvePrincipal(ctx, step.Principal)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case step.Action == ActionAssignRole && principalType == client.ResourceTypeUser:
+		return r.backend.GrantRole(ctx, principalID, roleID, r.assignmentOptions(step)...)
+	case step.Action == ActionAssignRole:
+		return r.backend.GrantRoleGroup(ctx, principalID, roleID, r.assignmentOptions(step)...)
+	case principalType == client.ResourceTypeUser:
+		return r.backend.RevokeRole(ctx, principalID, roleID)
+	default:
+		return r.backend.RevokeRoleGroup(ctx, principalID, roleID)
+	}
+}
+
+func (r *runner) updateProject(ctx context.Context, step *Step) error {
+	projectID, err := r.resolve(ctx, client.ResourceTypeProject, step.Project)
+	if err != nil {
+		return err
+	}
+	principalType, principalID, err := r.resolvePrincipal(ctx, step.Principal)
+	if err != nil {
+		return err
+	}
+	if principalType != client.ResourceTypeGroup {
+		return fmt.Errorf("project access is granted to groups, not %s", principalType)
+	}
+
+	if step.Action == ActionGrantProjectAccess {
+		return r.backend.GrantProjectAccess(ctx, projectID, principalID, r.assignmentOptions(step)...)
+	}
+	return r.backend.RevokeProjectAccess(ctx, projectID, principalID)
+}
+
+// grant grants an entitlement through the connector.
+func (r *runner) grant(ctx context.Context, step *Step) error {
+	entitlement, err := r.findEntitlement(ctx, step.Entitlement)
+	if err != nil {
+		return err
+	}
+	principal, err := r.principalResource(ctx, step.Principal)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.server.Grant(ctx, &v2.GrantManagerServiceGrantRequest{
+		Entitlement: entitlement,
+		Principal:   principal,
+	})
+	return err
+}
+
+// revoke revokes a grant through the connector. The grant must be one the connector lists itself, grants that are
+// only expanded from others cannot be revoked.
+func (r *runner) revoke(ctx context.Context, step *Step) error {
+	resourceID, entitlementID, err := r.resolveEntitlement(ctx, step.Entitlement)
+	if err != nil {
+		return err
+	}
+	principal, err := r.principalResource(ctx, step.Principal)
+	if err != nil {
+		return err
+	}
+
+	pageToken := ""
+	for {
+		resp, err := r.server.ListGrants(ctx, &v2.GrantsServiceListGrantsRequest{
+			Resource:  &v2.Resource{Id: resourceID},
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, g := range resp.GetList() {
+			if g.GetEntitlement().GetId() == entitlementID && samePrincipal(g, principal.GetId()) {
+				_, err = r.server.Revoke(ctx, &v2.GrantManagerServiceRevokeRequest{Grant: g})
+				return err
+			}
+		}
+
+		pageToken = resp.GetNextPageToken()
+		if pageToken == "" {
+			return fmt.Errorf("%s is not granted %s: %w", step.Principal, step.Entitlement, client.ErrNotFound)
+		}
+	}
+}
+
+// assert checks whether the last sync has a grant of an entitlement to a principal, including grants expanded from
+// other grants.
+func (r *runner) assert(ctx context.Context, step *Step) error {
+	if r.lastSync == "" {
+		return fmt.Errorf("%s needs a sync before it", step.Action)
+	}
+
+	_, entitlementID, err := r.resolveEntitlement(ctx, step.Entitlement)
+	if err != nil {
+		return err
+	}
+	principal, err := r.principalResource(ctx, step.Principal)
+	if err != nil {
+		return err
+	}
+
+	c1z, err := dotc1z.NewC1ZFile(ctx, r.lastSync, dotc1z.WithTmpDir(r.workDir))
+	if err != nil {
+		return err
+	}
+	defer c1z.Close()
+
+	found := false
+	pageToken := ""
+	for {
+		resp, err := c1z.ListGrants(ctx, &v2.GrantsServiceListGrantsRequest{PageToken: pageToken})
+		if err != nil {
+			return err
+		}
+
+		for _, g := range resp.GetList() {
+			if g.GetEntitlement().GetId() == entitlementID && samePrincipal(g, principal.GetId()) {
+				found = true
+			}
+		}
+
+		pageToken = resp.GetNextPageToken()
+		if found || pageToken == "" {
+			break
+		}
+	}
+
+	switch {
+	case step.Action == ActionAssertGrant && !found:
+		return fmt.Errorf("%w: %s is not granted %s", ErrAssertion, step.Principal, step.Entitlement)
+	case step.Action == ActionAssertNoGrant && found:
+		return fmt.Errorf("%w: %s is granted %s", ErrAssertion, step.Principal, step.Entitlement)
+	}
+
+	return nil
+}
+
+func samePrincipal(g *v2.Grant, principalID *v2.ResourceId) bool {
+	id := g.GetPrincipal().GetId()
+	return id.GetResourceType() == principalID.GetResourceType() && id.GetResource() == principalID.GetResource()
+}
+
+// findEntitlement returns the entitlement the connector lists for a reference such as "group:Sales:member".
+func (r *runner) findEntitlement(ctx context.Context, ref string) (*v2.Entitlement, error) {
+	resourceID, entitlementID, err := r.resolveEntitlement(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	pageToken := ""
+	for {
+		resp, err := r.server.ListEntitlements(ctx, &v2.EntitlementsServiceListEntitlementsRequest{
+			Resource:  &v2.Resource{Id: resourceID},
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range resp.GetList() {
+			if e.GetId() == entitlementID {
+				return e, nil
+			}
+		}
+
+		pageToken = resp.GetNextPageToken()
+		if pageToken == "" {
+			return nil, fmt.Errorf("entitlement %s: %w", ref, client.ErrNotFound)
+		}
+	}
+}
+
+// resolveEntitlement returns the resource of an entitlement reference and the ID the connector gives the entitlement.
+func (r *runner) resolveEntitlement(ctx context.Context, ref string) (*v2.ResourceId, string, error) {
+	resourceType, resource, slug, err := parseEntitlement(ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	id, err := r.resolve(ctx, resourceType, resource)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &v2.ResourceId{ResourceType: resourceType, Resource: id}, fmt.Sprintf("%s:%s:%s", resourceType, id, slug), nil
+}
+
+func (r *runner) resolvePrincipal(ctx context.Context, ref string) (string, string, error) {
+	principalType, principal, err := parsePrincipal(ref)
+	if err != nil {
+		return "", "", err
+	}
+
+	id, err := r.resolve(ctx, principalType, principal)
+	if err != nil {
+		return "", "", err
+	}
+
+	return principalType, id, nil
+}
+
+func (r *runner) principalResource(ctx context.Context, ref string) (*v2.Resource, error) {
+	principalType, id, err := r.resolvePrincipal(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v2.Resource{Id: &v2.ResourceId{ResourceType: principalType, Resource: id}}, nil
+}
+
+// named is a resource that can be referred to by name.
+type named struct {
+	id   string
+	name string
+}
+
+// resolve returns the ID of the resource of the given type referred to by ref, which is either its name or its ID.
+func (r *runner) resolve(ctx context.Context, resourceType, ref string) (string, error) {
+	var resources []named
+	switch resourceType {
+	case client.ResourceTypeUser:
+		users, _, err := r.backend.ListUsers(ctx, client.PageOptions{})
+		if err != nil {
+			return "", err
+		}
+		for _, u := range users {
+			resources = append(resources, named{u.Id, u.Name})
+		}
+	case client.ResourceTypeGroup:
+		groups, _, err := r.backend.ListGroups(ctx, client.PageOptions{})
+		if err != nil {
+			return "", err
+		}
+		for _, g := range groups {
+			resources = append(resources, named{g.Id, g.Name})
+		}
+	case client.ResourceTypeRole:
+		roles, _, err := r.backend.ListRoles(ctx, client.PageOptions{})
+		if err != nil {
+			return "", err
+		}
+		for _, role := range roles {
+			resources = append(resources, named{role.Id, role.Name})
+		}
+	case client.ResourceTypeProject:
+		projects, _, err := r.backend.ListProjects(ctx, client.PageOptions{})
+		if err != nil {
+			return "", err
+		}
+		for _, p := range projects {
+			resources = append(resources, named{p.Id, p.Name})
+		}
+	case client.ResourceTypePermission:
+		permissions, _, err := r.backend.ListPermissions(ctx, client.PageOptions{})
+		if err != nil {
+			return "", err
+		}
+		for _, p := range permissions {
+			resources = append(resources, named{p.Id, p.Name})
+		}
+	case client.ResourceTypeServiceAccount:
+		serviceAccounts, _, err := r.backend.ListServiceAccounts(ctx, client.PageOptions{})
+		if err != nil {
+			return "", err
+		}
+		for _, sa := range serviceAccounts {
+			resources = append(resources, named{sa.Id, sa.Name})
+		}
+	default:
+		return "", fmt.Errorf("unknown resource type %q", resourceType)
+	}
+
+	id := ""
+	for _, res := range resources {
+		if res.id == ref {
+			return res.id, nil
+		}
+		if res.name == ref {
+			if id != "" {
+				return "", fmt.Errorf("more than one %s is named %q, refer to it by ID", resourceType, ref)
+			}
+			id = res.id
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("%s %s: %w", resourceType, ref, client.ErrNotFound)
+	}
+
+	return id, nil
+}
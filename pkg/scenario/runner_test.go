@@ -0,0 +1,174 @@
+package scenario_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-demo/pkg/scenario"
+)
+
+func load(t *testing.T, yaml string) *scenario.Scenario {
+	t.Helper()
+
+	s, err := scenario.Load(strings.NewReader(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// TestRun runs a scenario using every kind of step and checks that each of them took effect.
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	outDir := t.TempDir()
+
+	s := load(t, `
+name: every step
+steps:
+  - action: create_user
+    name: Eve
+    email: eve@example.com
+  - action: add_to_group
+    group: Sales
+    principal: user:Eve
+    expires_in: 24h
+  - action: grant
+    entitlement: group:Sales:member
+    principal: user:Bob
+  - action: sync
+    name: before
+  - action: assert_grant
+    entitlement: group:Sales:member
+    principal: user:Eve
+  - action: assert_grant
+    entitlement: group:Sales:member
+    principal: user:Bob
+  - action: assert_no_grant
+    entitlement: group:Engineers:member
+    principal: user:Eve
+  - action: advance
+    duration: 48h
+  - action: reap_expired
+  - action: revoke
+    entitlement: group:Sales:member
+    principal: user:Bob
+  - action: sync
+    name: after
+  - action: assert_no_grant
+    entitlement: group:Sales:member
+    principal: user:Eve
+  - action: assert_no_grant
+    entitlement: group:Sales:member
+    principal: user:Bob
+`)
+
+	report, err := scenario.Run(ctx, s, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Passed || report.Name != "every step" {
+		t.Errorf("report of %q passed %v", report.Name, report.Passed)
+	}
+	if len(report.Steps) != len(s.Steps) {
+		t.Fatalf("%d steps ran, want %d", len(report.Steps), len(s.Steps))
+	}
+	for _, r := range report.Steps {
+		if r.Error != "" {
+			t.Errorf("step %d: %s", r.Step, r.Error)
+		}
+	}
+
+	if d := report.Steps[8].At.Sub(report.Steps[7].At); d != 48*time.Hour {
+		t.Errorf("advancing moved the clock by %s, want 48h", d)
+	}
+
+	for _, sync := range []struct {
+		step int
+		c1z  string
+	}{
+		{4, "01-before.c1z"},
+		{11, "02-after.c1z"},
+	} {
+		want := filepath.Join(outDir, sync.c1z)
+		if got := report.Steps[sync.step-1].C1Z; got != want {
+			t.Errorf("step %d wrote %q, want %q", sync.step, got, want)
+		}
+		_, err = os.Stat(want)
+		if err != nil {
+			t.Errorf("step %d: %v", sync.step, err)
+		}
+	}
+}
+
+// TestRunFailingAssertion checks that a scenario stops at the first assertion that doesn't hold.
+func TestRunFailingAssertion(t *testing.T) {
+	ctx := context.Background()
+
+	s := load(t, `
+steps:
+  - action: sync
+  - action: assert_grant
+    entitlement: group:Sales:member
+    principal: user:Bob
+  - action: add_to_group
+    group: Sales
+    principal: user:Bob
+`)
+
+	report, err := scenario.Run(ctx, s, "")
+	if !errors.Is(err, scenario.ErrAssertion) {
+		t.Fatalf("got %v, want %v", err, scenario.ErrAssertion)
+	}
+
+	if report.Passed {
+		t.Error("report passed")
+	}
+	if len(report.Steps) != 2 {
+		t.Fatalf("%d steps ran, want the steps up to the failing assertion", len(report.Steps))
+	}
+	if !strings.Contains(report.Steps[1].Error, "user:Bob is not granted group:Sales:member") {
+		t.Errorf("failing step reported %q", report.Steps[1].Error)
+	}
+	if report.Steps[0].C1Z != "" {
+		t.Errorf("c1z %s reported, but it was written to a temporary directory", report.Steps[0].C1Z)
+	}
+}
+
+// TestRunFailingStep checks that a step that can't run fails the scenario without being taken for an assertion.
+func TestRunFailingStep(t *testing.T) {
+	ctx := context.Background()
+
+	for name, yaml := range map[string]string{
+		"assertion before a sync": `
+steps:
+  - action: assert_no_grant
+    entitlement: group:Sales:member
+    principal: user:Bob
+`,
+		"unknown group": `
+steps:
+  - action: add_to_group
+    group: Marketing
+    principal: user:Bob
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			report, err := scenario.Run(ctx, load(t, yaml), "")
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if errors.Is(err, scenario.ErrAssertion) {
+				t.Errorf("got an assertion failure: %v", err)
+			}
+			if report.Passed || report.Steps[0].Error == "" {
+				t.Errorf("failing step reported as %+v", report.Steps[0])
+			}
+		})
+	}
+}
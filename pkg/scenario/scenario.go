@@ -0,0 +1,216 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action is what a step of a scenario does.
+type Action string
+
+const (
+	// ActionCreateUser creates a user named Name.
+	ActionCreateUser Action = "create_user"
+	// ActionDeleteUser deletes User.
+	ActionDeleteUser Action = "delete_user"
+	// ActionAddToGroup makes Principal, a user or a group, a member of Group.
+	ActionAddToGroup Action = "add_to_group"
+	// ActionRemoveFromGroup removes Principal from the members of Group.
+	ActionRemoveFromGroup Action = "remove_from_group"
+	// ActionAssignRole assigns Role to Principal, a user or a group.
+	ActionAssignRole Action = "assign_role"
+	// ActionUnassignRole takes Role away from Principal.
+	ActionUnassignRole Action = "unassign_role"
+	// ActionGrantProjectAccess gives Principal, a group, access to Project.
+	ActionGrantProjectAccess Action = "grant_project_access"
+	// ActionRevokeProjectAccess takes the access to Project away from Principal.
+	ActionRevokeProjectAccess Action = "revoke_project_access"
+	// ActionTransferOwner makes User the owner of Project.
+	ActionTransferOwner Action = "transfer_owner"
+	// ActionGrant grants Entitlement to Principal through the connector, the way provisioning does.
+	ActionGrant Action = "grant"
+	// ActionRevoke revokes the grant of Entitlement to Principal through the connector, the way provisioning does.
+	ActionRevoke Action = "revoke"
+	// ActionSync syncs the connector to a new c1z, which later assertions check.
+	ActionSync Action = "sync"
+	// ActionAssertGrant fails the scenario unless the last sync has a grant of Entitlement to Principal.
+	ActionAssertGrant Action = "assert_grant"
+	// ActionAssertNoGrant fails the scenario if the last sync has a grant of Entitlement to Principal.
+	ActionAssertNoGrant Action = "assert_no_grant"
+	// ActionAdvance moves the clock of the scenario forward by Duration.
+	ActionAdvance Action = "advance"
+	// ActionReapExpired revokes every assignment that has expired by the clock of the scenario.
+	ActionReapExpired Action = "reap_expired"
+)
+
+// Scenario is a sequence of steps run against a fresh copy of the demo data. Steps change the data, either directly
+// or through the connector, sync it, and assert on what was synced. For example, this scenario checks that a group
+// membership is synced until it expires:
+//
+//	name: expiring membership
+//	steps:
+//	  - action: add_to_group
+//	    group: Sales
+//	    principal: user:Bob
+//	    expires_in: 24h
+//	  - action: sync
+//	  - action: assert_grant
+//	    entitlement: group:Sales:member
+//	    principal: user:Bob
+//	  - action: advance
+//	    duration: 48h
+//	  - action: reap_expired
+//	  - action: sync
+//	  - action: assert_no_grant
+//	    entitlement: group:Sales:member
+//	    principal: user:Bob
+type Scenario struct {
+	Name  string  `yaml:"name"`
+	Steps []*Step `yaml:"steps"`
+}
+
+// Step is a single step of a scenario. Which fields are used depends on its action.
+//
+// Users, groups, roles, projects and permissions are referred to by name or by ID. Principals are written as
+// "user:Alice" or "group:Sales", and entitlements as "group:Sales:member", the resource type, resource and entitlement
+// slug of the connector.
+type Step struct {
+	Action Action `yaml:"action"`
+	// Name is the name of the user created, or of the c1z written by a sync.
+	Name     string `yaml:"name"`
+	Email    string `yaml:"email"`
+	Password string `yaml:"password"`
+
+	User      string `yaml:"user"`
+	Group     string `yaml:"group"`
+	Role      string `yaml:"role"`
+	Project   string `yaml:"project"`
+	Principal string `yaml:"principal"`
+
+	Entitlement string `yaml:"entitlement"`
+
+	// ExpiresIn makes a group membership, role assignment or project access expire after the given duration.
+	ExpiresIn time.Duration `yaml:"expires_in"`
+	Reason    string        `yaml:"reason"`
+
+	// Duration is how far an advance step moves the clock.
+	Duration time.Duration `yaml:"duration"`
+}
+
+// Load reads a scenario from YAML and checks that every step has the fields its action needs.
+func Load(r io.Reader) (*Scenario, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	s := &Scenario{}
+	err := dec.Decode(s)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("baton-demo: parsing scenario: %w", err)
+	}
+
+	if len(s.Steps) == 0 {
+		return nil, errors.New("baton-demo: scenario has no steps")
+	}
+
+	for i, step := range s.Steps {
+		err := step.validate()
+		if err != nil {
+			return nil, fmt.Errorf("baton-demo: step %d: %w", i+1, err)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Step) validate() error {
+	var required [][2]string
+	assignment := false
+	switch s.Action {
+	case ActionCreateUser:
+		required = [][2]string{{"name", s.Name}}
+	case ActionDeleteUser:
+		required = [][2]string{{"user", s.User}}
+	case ActionAddToGroup:
+		required = [][2]string{{"group", s.Group}, {"principal", s.Principal}}
+		assignment = true
+	case ActionRemoveFromGroup:
+		required = [][2]string{{"group", s.Group}, {"principal", s.Principal}}
+	case ActionAssignRole:
+		required = [][2]string{{"role", s.Role}, {"principal", s.Principal}}
+		assignment = true
+	case ActionUnassignRole:
+		required = [][2]string{{"role", s.Role}, {"principal", s.Principal}}
+	case ActionGrantProjectAccess:
+		required = [][2]string{{"project", s.Project}, {"principal", s.Principal}}
+		assignment = true
+	case ActionRevokeProjectAccess:
+		required = [][2]string{{"project", s.Project}, {"principal", s.Principal}}
+	case ActionTransferOwner:
+		required = [][2]string{{"project", s.Project}, {"user", s.User}}
+	case ActionGrant, ActionRevoke, ActionAssertGrant, ActionAssertNoGrant:
+		required = [][2]string{{"entitlement", s.Entitlement}, {"principal", s.Principal}}
+	case ActionAdvance:
+		if s.Duration <= 0 {
+			return errors.New("advance needs a positive duration")
+		}
+	case ActionSync, ActionReapExpired:
+	case "":
+		return errors.New("action is required")
+	default:
+		return fmt.Errorf("unknown action %q", s.Action)
+	}
+
+	for _, field := range required {
+		if field[1] == "" {
+			return fmt.Errorf("%s needs %s", s.Action, field[0])
+		}
+	}
+
+	if !assignment && (s.ExpiresIn != 0 || s.Reason != "") {
+		return fmt.Errorf("%s does not take expires_in or reason", s.Action)
+	}
+	if s.ExpiresIn < 0 {
+		return errors.New("expires_in must be positive")
+	}
+
+	if s.Principal != "" {
+		_, _, err := parsePrincipal(s.Principal)
+		if err != nil {
+			return err
+		}
+	}
+	if s.Entitlement != "" {
+		_, _, _, err := parseEntitlement(s.Entitlement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parsePrincipal splits a principal such as "user:Alice" into its resource type and the name or ID of the user or
+// group.
+func parsePrincipal(principal string) (string, string, error) {
+	resourceType, ref, ok := strings.Cut(principal, ":")
+	if !ok || ref == "" || (resourceType != "user" && resourceType != "group") {
+		return "", "", fmt.Errorf("principal %q must be user:<user> or group:<group>", principal)
+	}
+	return resourceType, ref, nil
+}
+
+// parseEntitlement splits an entitlement such as "group:Sales:member" into its resource type, the name or ID of the
+// resource, and the entitlement slug. Names may themselves contain colons, like the permission "documents:read".
+func parseEntitlement(entitlement string) (string, string, string, error) {
+	first := strings.Index(entitlement, ":")
+	last := strings.LastIndex(entitlement, ":")
+	if first <= 0 || last == first || last == len(entitlement)-1 {
+		return "", "", "", fmt.Errorf("entitlement %q must be <resource type>:<resource>:<slug>", entitlement)
+	}
+	return entitlement[:first], entitlement[first+1 : last], entitlement[last+1:], nil
+}
@@ -0,0 +1,59 @@
+package scenario_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-demo/pkg/scenario"
+)
+
+func TestLoad(t *testing.T) {
+	s, err := scenario.Load(strings.NewReader(`
+name: expiring membership
+steps:
+  - action: add_to_group
+    group: Sales
+    principal: user:Bob
+    expires_in: 24h
+  - action: sync
+  - action: assert_grant
+    entitlement: group:Sales:member
+    principal: user:Bob
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "expiring membership" || len(s.Steps) != 3 {
+		t.Fatalf("loaded %q with %d steps", s.Name, len(s.Steps))
+	}
+	if step := s.Steps[0]; step.Action != scenario.ActionAddToGroup || step.Group != "Sales" || step.ExpiresIn != 24*time.Hour {
+		t.Errorf("first step loaded as %+v", step)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name, yaml, err string
+	}{
+		{"no steps", "name: empty\n", "scenario has no steps"},
+		{"unknown field", "steps:\n  - action: sync\n    colour: red\n", "field colour not found"},
+		{"no action", "steps:\n  - group: Sales\n", "step 1: action is required"},
+		{"unknown action", "steps:\n  - action: dance\n", `step 1: unknown action "dance"`},
+		{"missing field", "steps:\n  - action: sync\n  - action: add_to_group\n    group: Sales\n", "step 2: add_to_group needs principal"},
+		{"expiry of a removal", "steps:\n  - action: remove_from_group\n    group: Sales\n    principal: user:Bob\n    expires_in: 1h\n", "remove_from_group does not take expires_in or reason"},
+		{"advance without duration", "steps:\n  - action: advance\n", "advance needs a positive duration"},
+		{"bad principal", "steps:\n  - action: add_to_group\n    group: Sales\n    principal: Bob\n", "principal"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := scenario.Load(strings.NewReader(tc.yaml))
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got %q, want it to contain %q", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package scenario
+
+import (
+	"context"
+	"net"
+
+	connectorV2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	sdkSync "github.com/conductorone/baton-sdk/pkg/sync"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// connectorClient implements every connector service by calling a server over a gRPC connection.
+type connectorClient struct {
+	connectorV2.ResourceTypesServiceClient
+	connectorV2.ResourcesServiceClient
+	connectorV2.EntitlementsServiceClient
+	connectorV2.GrantsServiceClient
+	connectorV2.ConnectorServiceClient
+	connectorV2.AssetServiceClient
+	connectorV2.GrantManagerServiceClient
+	connectorV2.ResourceManagerServiceClient
+	connectorV2.AccountManagerServiceClient
+	connectorV2.CredentialManagerServiceClient
+	connectorV2.EventServiceClient
+	connectorV2.TicketsServiceClient
+}
+
+// inProcess serves a connector over an in-memory connection, so that it can be synced without running it as a
+// separate process the way the SDK does.
+type inProcess struct {
+	server *grpc.Server
+	conn   *grpc.ClientConn
+	client types.ConnectorClient
+}
+
+// serve starts serving the connector. Every call it handles has its context passed through withContext first.
+func serve(srv types.ConnectorServer, withContext func(context.Context) context.Context) (*inProcess, error) {
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(
+		func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(withContext(ctx), req)
+		},
+	))
+	connectorV2.RegisterResourceTypesServiceServer(server, srv)
+	connectorV2.RegisterResourcesServiceServer(server, srv)
+	connectorV2.RegisterEntitlementsServiceServer(server, srv)
+	connectorV2.RegisterGrantsServiceServer(server, srv)
+	connectorV2.RegisterConnectorServiceServer(server, srv)
+	connectorV2.RegisterAssetServiceServer(server, srv)
+	connectorV2.RegisterGrantManagerServiceServer(server, srv)
+	connectorV2.RegisterResourceManagerServiceServer(server, srv)
+	connectorV2.RegisterAccountManagerServiceServer(server, srv)
+	connectorV2.RegisterCredentialManagerServiceServer(server, srv)
+	connectorV2.RegisterEventServiceServer(server, srv)
+	connectorV2.RegisterTicketsServiceServer(server, srv)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///in-process",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		server.Stop()
+		return nil, err
+	}
+
+	return &inProcess{
+		server: server,
+		conn:   conn,
+		client: &connectorClient{
+			ResourceTypesServiceClient:     connectorV2.NewResourceTypesServiceClient(conn),
+			ResourcesServiceClient:         connectorV2.NewResourcesServiceClient(conn),
+			EntitlementsServiceClient:      connectorV2.NewEntitlementsServiceClient(conn),
+			GrantsServiceClient:            connectorV2.NewGrantsServiceClient(conn),
+			ConnectorServiceClient:         connectorV2.NewConnectorServiceClient(conn),
+			AssetServiceClient:             connectorV2.NewAssetServiceClient(conn),
+			GrantManagerServiceClient:      connectorV2.NewGrantManagerServiceClient(conn),
+			ResourceManagerServiceClient:   connectorV2.NewResourceManagerServiceClient(conn),
+			AccountManagerServiceClient:    connectorV2.NewAccountManagerServiceClient(conn),
+			CredentialManagerServiceClient: connectorV2.NewCredentialManagerServiceClient(conn),
+			EventServiceClient:             connectorV2.NewEventServiceClient(conn),
+			TicketsServiceClient:           connectorV2.NewTicketsServiceClient(conn),
+		},
+	}, nil
+}
+
+// sync runs a full sync of the connector, writing it to a new c1z at path.
+func (p *inProcess) sync(ctx context.Context, path, tmpDir string) error {
+	syncer, err := sdkSync.NewSyncer(ctx, p.client, sdkSync.WithC1ZPath(path), sdkSync.WithTmpDir(tmpDir))
+	if err != nil {
+		return err
+	}
+
+	err = syncer.Sync(ctx)
+	if err != nil {
+		_ = syncer.Close(ctx)
+		return err
+	}
+
+	return syncer.Close(ctx)
+}
+
+// Sync runs a full sync of a connector, in this process, writing it to a new c1z at path. Temporary files are written
+// to tmpDir.
+func Sync(ctx context.Context, srv types.ConnectorServer, path, tmpDir string) error {
+	p, err := serve(srv, func(ctx context.Context) context.Context { return ctx })
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	return p.sync(ctx, path, tmpDir)
+}
+
+func (p *inProcess) Close() error {
+	err := p.conn.Close()
+	p.server.Stop()
+	return err
+}